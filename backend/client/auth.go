@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// User is the profile of an account.
+type User struct {
+	ID          int64       `json:"id"`
+	Email       string      `json:"email"`
+	Role        string      `json:"role"`
+	DisplayName string      `json:"display_name"`
+	Locale      string      `json:"locale"`
+	Timezone    string      `json:"timezone"`
+	Preferences Preferences `json:"preferences"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	DeleteAfter *time.Time  `json:"delete_after,omitempty"`
+	DisabledAt  *time.Time  `json:"disabled_at,omitempty"`
+}
+
+// Preferences are the settings of a user.
+type Preferences struct {
+	DefaultNoteColor string `json:"default_note_color"`
+	NoteView         string `json:"note_view"`
+}
+
+type authResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	User         User   `json:"user"`
+}
+
+type credentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Register creates an account, starts a session and returns the user.
+func (c *Client) Register(ctx context.Context, email, password string) (*User, error) {
+	return c.startSession(ctx, "/api/register", credentialsRequest{email, password})
+}
+
+// Login starts a session and returns the user.
+func (c *Client) Login(ctx context.Context, email, password string) (*User, error) {
+	return c.startSession(ctx, "/api/login", credentialsRequest{email, password})
+}
+
+func (c *Client) startSession(ctx context.Context, path string, req credentialsRequest) (*User, error) {
+	var resp authResponse
+	if err := c.call(ctx, http.MethodPost, path, req, &resp, false); err != nil {
+		return nil, err
+	}
+	c.setTokens(Tokens{AccessToken: resp.Token, RefreshToken: resp.RefreshToken})
+	return &resp.User, nil
+}
+
+// Refresh replaces the tokens with new ones. Calls refresh expired access
+// tokens by themselves; this is for callers that want a fresh one early.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.refresh(ctx, "")
+}
+
+// Logout ends the session on the server and then forgets the tokens. When
+// the server doesn't confirm that the refresh token was revoked, the
+// tokens are kept and the error is returned, so the logout can be tried
+// again. A session the server no longer knows is forgotten without error.
+func (c *Client) Logout(ctx context.Context) error {
+	tokens := c.Tokens()
+	if tokens.RefreshToken != "" {
+		err := c.call(ctx, http.MethodPost, "/api/logout", refreshRequest{tokens.RefreshToken}, nil, false)
+		if err != nil {
+			return err
+		}
+	}
+	c.setTokens(Tokens{})
+	return nil
+}
+
+// Me returns the user of the session.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/api/me", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
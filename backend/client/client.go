@@ -0,0 +1,293 @@
+// Package client is a Go client of the notes API. A Client logs in, keeps
+// the access and refresh tokens, refreshes the access token when it
+// expires and retries idempotent calls that failed for transient reasons.
+// Error responses are returned as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens are the credentials of a session.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	userAgent  string
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	onTokens   func(Tokens)
+
+	mu     sync.Mutex
+	tokens Tokens
+
+	// refreshMu makes concurrent calls that find the access token expired
+	// share one refresh, as refresh tokens are single use.
+	refreshMu sync.Mutex
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client, http.DefaultClient by default.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithTokens starts the client with the tokens of an earlier session. The
+// access token may be empty: it is refreshed on the first call.
+func WithTokens(t Tokens) Option {
+	return func(c *Client) { c.tokens = t }
+}
+
+// WithTokenHook calls fn whenever the tokens change: after a login, a
+// refresh and a logout, and when the session turns out to be over (with
+// empty tokens). Use it to store the session.
+func WithTokenHook(fn func(Tokens)) Option {
+	return func(c *Client) { c.onTokens = fn }
+}
+
+// WithRetries sets how many times an idempotent call is retried, 3 by
+// default, and the delay before the first retry, which doubles after each
+// one. 0 retries turns retrying off.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = retries, backoff }
+}
+
+// WithUserAgent sets the User-Agent header of the requests.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client of the API at baseURL, such as
+// "https://keep.example.com".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base URL %q", baseURL)
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       http.DefaultClient,
+		userAgent:  "keep-client-go",
+		retries:    3,
+		backoff:    200 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Tokens returns the tokens of the current session.
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
+func (c *Client) setTokens(t Tokens) {
+	c.mu.Lock()
+	c.tokens = t
+	hook := c.onTokens
+	c.mu.Unlock()
+	if hook != nil {
+		hook(t)
+	}
+}
+
+// do calls an endpoint that needs a session. An expired access token is
+// refreshed once and the call repeated.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	return c.call(ctx, method, path, in, out, true)
+}
+
+// call sends a request with in as the JSON body, if not nil, and decodes
+// the response into out, if not nil. Idempotent methods are retried after
+// network errors and responses asking to try again later.
+func (c *Client) call(ctx context.Context, method, path string, in, out interface{}, auth bool) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		token := ""
+		if auth {
+			token = c.Tokens().AccessToken
+			if token == "" && !refreshed {
+				refreshed = true
+				if err := c.refresh(ctx, ""); err != nil {
+					return err
+				}
+				token = c.Tokens().AccessToken
+			}
+		}
+
+		resp, respBody, err := c.send(ctx, method, path, body, token)
+		if err == nil && resp.StatusCode < 400 {
+			if out == nil || resp.StatusCode == http.StatusNoContent {
+				return nil
+			}
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("client: decode response of %s %s: %w", method, path, err)
+			}
+			return nil
+		}
+
+		var apiErr *Error
+		if err == nil {
+			apiErr = parseError(resp, respBody)
+			if auth && resp.StatusCode == http.StatusUnauthorized && !refreshed {
+				refreshed = true
+				if err := c.refresh(ctx, token); err != nil {
+					return err
+				}
+				attempt-- // the refresh isn't a retry
+				continue
+			}
+			err = apiErr
+		}
+
+		wait, ok := c.retryDelay(ctx, method, attempt, apiErr)
+		if !ok {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one request and reads the whole response.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, token string) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("client: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("client: read response of %s %s: %w", method, path, err)
+	}
+	return resp, data, nil
+}
+
+// retryDelay decides whether a failed attempt is retried and after how
+// long. Only idempotent methods are retried, as a request that got no
+// response may have been applied; apiErr is nil for network errors.
+func (c *Client) retryDelay(ctx context.Context, method string, attempt int, apiErr *Error) (time.Duration, bool) {
+	if attempt >= c.retries || ctx.Err() != nil || !idempotent(method) {
+		return 0, false
+	}
+	if apiErr != nil {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return 0, false
+		}
+	}
+
+	// Exponential backoff with jitter, so clients that failed together
+	// don't retry together
+	wait := c.backoff << attempt
+	if wait > c.maxBackoff || wait < c.backoff { // or overflowed
+		wait = c.maxBackoff
+	}
+	wait = wait/2 + rand.N(wait/2+1)
+	if apiErr != nil && apiErr.RetryAfter > 0 {
+		if apiErr.RetryAfter > c.maxBackoff {
+			return 0, false // not worth blocking the caller for
+		}
+		wait = max(wait, apiErr.RetryAfter)
+	}
+	return wait, true
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// refresh exchanges the refresh token for new tokens. expired is the
+// access token that was refused: when another call already replaced it,
+// there is nothing to do. An empty expired always refreshes. When the
+// server rejects the refresh token the session is over: the tokens are
+// cleared and the error wraps ErrNotLoggedIn.
+func (c *Client) refresh(ctx context.Context, expired string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	current := c.Tokens()
+	if expired != "" && current.AccessToken != "" && current.AccessToken != expired {
+		return nil
+	}
+	if current.RefreshToken == "" {
+		return ErrNotLoggedIn
+	}
+
+	var pair struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	err := c.call(ctx, http.MethodPost, "/api/refresh", refreshRequest{current.RefreshToken}, &pair, false)
+	if err != nil {
+		switch ErrorCode(err) {
+		case CodeInvalidToken, CodeAccountDisabled:
+			c.setTokens(Tokens{})
+			return fmt.Errorf("%w: %w", ErrNotLoggedIn, err)
+		}
+		return err
+	}
+	c.setTokens(Tokens{AccessToken: pair.Token, RefreshToken: pair.RefreshToken})
+	return nil
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,202 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/client"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/handlers"
+	"github.com/tgogbera/google_keep_clone-backend/internal/health"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/ratelimit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/server"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	if err := config.Load(config.Options{Flags: map[string]string{"auth.jwt_secret": "test"}}); err != nil {
+		panic(err)
+	}
+	if err := handlers.RegisterValidators(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newServer runs the API on an empty SQLite database.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	router := server.NewRouter(config.Get(), testdb.SQLite(t), ratelimit.New(ratelimit.NewMemoryStore()), health.NewChecker())
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(t *testing.T, url string, opts ...client.Option) *client.Client {
+	t.Helper()
+	c, err := client.New(url, append([]client.Option{client.WithRetries(0, 0)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	srv := newServer(t)
+
+	var stored []client.Tokens
+	c := newClient(t, srv.URL, client.WithTokenHook(func(tokens client.Tokens) {
+		stored = append(stored, tokens)
+	}))
+	user, err := c.Register(ctx, "alice@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "alice@example.com" || len(stored) != 1 || stored[0].RefreshToken == "" {
+		t.Fatalf("register: user %+v, stored tokens %v", user, stored)
+	}
+	if _, err := c.Register(ctx, "alice@example.com", "password"); client.ErrorCode(err) != client.CodeEmailTaken {
+		t.Errorf("register twice: err = %v, want email_taken", err)
+	}
+
+	other := newClient(t, srv.URL)
+	if _, err := other.Login(ctx, "alice@example.com", "wrong"); client.ErrorCode(err) != client.CodeInvalidCredentials {
+		t.Errorf("wrong password: err = %v, want invalid_credentials", err)
+	}
+	if _, err := other.Me(ctx); !errors.Is(err, client.ErrNotLoggedIn) {
+		t.Errorf("call without a session: err = %v, want ErrNotLoggedIn", err)
+	}
+	if _, err := other.Login(ctx, "alice@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A client resumed from the refresh token alone refreshes on the first call
+	resumed := newClient(t, srv.URL, client.WithTokens(client.Tokens{RefreshToken: other.Tokens().RefreshToken}))
+	me, err := resumed.Me(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.ID != user.ID || resumed.Tokens().AccessToken == "" {
+		t.Errorf("resumed session: user %d, tokens %+v", me.ID, resumed.Tokens())
+	}
+
+	// An access token the server refuses is refreshed and the call repeated
+	refresh := resumed.Tokens().RefreshToken
+	broken := newClient(t, srv.URL, client.WithTokens(client.Tokens{AccessToken: "expired", RefreshToken: refresh}))
+	if _, err := broken.Me(ctx); err != nil {
+		t.Fatalf("call with a refused access token: %v", err)
+	}
+	if broken.Tokens().RefreshToken == refresh {
+		t.Error("refresh token not rotated")
+	}
+
+	revoked := c.Tokens()
+	if err := c.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Tokens() != (client.Tokens{}) || stored[len(stored)-1] != (client.Tokens{}) {
+		t.Errorf("tokens after logout: %+v, last stored %+v", c.Tokens(), stored[len(stored)-1])
+	}
+	after := newClient(t, srv.URL, client.WithTokens(client.Tokens{RefreshToken: revoked.RefreshToken}))
+	if err := after.Refresh(ctx); !errors.Is(err, client.ErrNotLoggedIn) || client.ErrorCode(err) != client.CodeInvalidToken {
+		t.Errorf("refresh after logout: err = %v, want ErrNotLoggedIn with invalid_token", err)
+	}
+	if err := after.Logout(ctx); err != nil {
+		t.Errorf("logout without a session: %v", err)
+	}
+}
+
+func TestLogoutKeepsTokensWhenNotConfirmed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"status":500,"code":"internal_error","detail":"Failed to revoke refresh token"}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	tokens := client.Tokens{AccessToken: "access", RefreshToken: "refresh"}
+	c := newClient(t, srv.URL, client.WithTokens(tokens))
+	if err := c.Logout(context.Background()); client.ErrorCode(err) != client.CodeInternal {
+		t.Fatalf("err = %v, want internal_error", err)
+	}
+	if c.Tokens() != tokens {
+		t.Errorf("tokens = %+v after a failed logout, want them kept", c.Tokens())
+	}
+}
+
+func TestNotes(t *testing.T) {
+	ctx := context.Background()
+	srv := newServer(t)
+	alice := newClient(t, srv.URL)
+	if _, err := alice.Register(ctx, "alice@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+	bob := newClient(t, srv.URL)
+	if _, err := bob.Register(ctx, "bob@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	groceries, err := alice.CreateNote(ctx, client.NewNote{Title: "Groceries", Content: "milk", Labels: []string{"home"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.CreateNote(ctx, client.NewNote{Title: "Report", Labels: []string{"work"}, Pinned: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.CreateNote(ctx, client.NewNote{Title: ""}); client.ErrorCode(err) != client.CodeValidationFailed {
+		t.Errorf("note without a title: err = %v, want validation_failed", err)
+	}
+
+	notes, err := alice.ListNotes(ctx, &client.ListOptions{Label: "home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].ID != groceries.ID {
+		t.Errorf("notes labeled home = %+v", notes)
+	}
+	if notes, err = alice.ListNotes(ctx, &client.ListOptions{Sort: client.SortTitle}); err != nil || len(notes) != 2 || notes[0].Title != "Groceries" {
+		t.Errorf("notes by title = %+v, %v", notes, err)
+	}
+
+	title, archived := "Shopping", true
+	updated, err := alice.UpdateNote(ctx, groceries.ID, client.NoteUpdate{Title: &title, Archived: &archived})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Shopping" || !updated.Archived {
+		t.Errorf("updated note = %+v", updated)
+	}
+	note, err := alice.GetNote(ctx, groceries.ID, "labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(note.Labels) != 1 || note.Labels[0].Name != "home" {
+		t.Errorf("expanded labels = %+v", note.Labels)
+	}
+	if notes, _ := alice.ListNotes(ctx, nil); len(notes) != 1 {
+		t.Errorf("notes without the archived = %+v", notes)
+	}
+
+	if _, err := bob.GetNote(ctx, groceries.ID); !client.IsNotFound(err) {
+		t.Errorf("note of another user: err = %v, want not_found", err)
+	}
+	if err := bob.DeleteNote(ctx, groceries.ID); !client.IsNotFound(err) {
+		t.Errorf("delete by another user: err = %v, want not_found", err)
+	}
+	if err := alice.DeleteNote(ctx, groceries.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.GetNote(ctx, groceries.ID); !client.IsNotFound(err) {
+		t.Errorf("deleted note: err = %v, want not_found", err)
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Code identifies the kind of an API error. The values are the codes of
+// the server's problem documents; see README_AUTH.md for their meaning.
+type Code string
+
+const (
+	CodeInvalidRequest     Code = "invalid_request"
+	CodeValidationFailed   Code = "validation_failed"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeAccountDisabled    Code = "account_disabled"
+	CodeForbidden          Code = "forbidden"
+	CodeNotFound           Code = "not_found"
+	CodeEmailTaken         Code = "email_taken"
+	CodeImportInProgress   Code = "import_in_progress"
+	CodePayloadTooLarge    Code = "payload_too_large"
+	CodeRateLimited        Code = "rate_limited"
+	CodeAccountLocked      Code = "account_locked"
+	CodeEmailFailed        Code = "email_failed"
+	CodeUnavailable        Code = "unavailable"
+	CodeInternal           Code = "internal_error"
+)
+
+// ErrNotLoggedIn is returned by calls that need a session when the client
+// has no tokens, and by any call once the refresh token stopped working.
+var ErrNotLoggedIn = errors.New("client: not logged in")
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	Code       Code
+	// Detail is the human-readable explanation, meant for people.
+	Detail string
+	// Fields lists the invalid fields of a validation_failed error.
+	Fields    []FieldError
+	RequestID string
+	// RetryAfter is the wait asked for by a 429 or 503, 0 when not given.
+	RetryAfter time.Duration
+}
+
+// FieldError is a problem with one field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("api: %s (%d)", e.Detail, e.StatusCode)
+	}
+	return fmt.Sprintf("api: %s (%d %s)", e.Detail, e.StatusCode, e.Code)
+}
+
+// ErrorCode returns the code of the API error in err's chain, or "" when
+// err didn't come from an error response.
+func ErrorCode(err error) Code {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// IsNotFound reports whether err is a not_found error of the API.
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == CodeNotFound
+}
+
+// problem is the RFC 7807 document of an error response.
+type problem struct {
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Code      Code         `json:"code"`
+	Errors    []FieldError `json:"errors"`
+	RequestID string       `json:"request_id"`
+}
+
+// parseError builds the error of a response with a status of 400 or more.
+// Bodies that aren't problem documents, such as those of a proxy, keep the
+// status with an empty code.
+func parseError(resp *http.Response, body []byte) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	var p problem
+	if json.Unmarshal(body, &p) == nil && p.Code != "" {
+		apiErr.Code = p.Code
+		apiErr.Detail = p.Detail
+		apiErr.Fields = p.Errors
+		if p.RequestID != "" {
+			apiErr.RequestID = p.RequestID
+		}
+	} else {
+		apiErr.Detail = http.StatusText(resp.StatusCode)
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return apiErr
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Note is a note of the user.
+type Note struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Color    string `json:"color"`
+	Pinned   bool   `json:"pinned"`
+	Archived bool   `json:"archived"`
+	// TrashedAt is set while the note is in the trash.
+	TrashedAt *time.Time `json:"trashed_at,omitempty"`
+	// Position orders the notes when listed with SortManual.
+	Position    string       `json:"position"`
+	Labels      []Label      `json:"labels,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// ChecklistItems and RevisionCount are only set when expanded.
+	ChecklistItems []ChecklistItem `json:"checklist_items,omitempty"`
+	RevisionCount  int             `json:"revision_count,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// ChecklistItem is a Markdown task list item of a note's content.
+type ChecklistItem struct {
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+}
+
+// Label is a label of notes.
+type Label struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Attachment describes a file attached to a note.
+type Attachment struct {
+	ID        int64     `json:"id"`
+	NoteID    int64     `json:"note_id"`
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewNote is a note to create. Missing labels are created.
+type NewNote struct {
+	Title    string   `json:"title"`
+	Content  string   `json:"content,omitempty"`
+	Color    string   `json:"color,omitempty"`
+	Pinned   bool     `json:"pinned,omitempty"`
+	Archived bool     `json:"archived,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// NoteUpdate changes the fields of a note that are not nil. Labels
+// replaces the labels; a pointer to an empty slice removes them all.
+type NoteUpdate struct {
+	Title    *string   `json:"title,omitempty"`
+	Content  *string   `json:"content,omitempty"`
+	Color    *string   `json:"color,omitempty"`
+	Pinned   *bool     `json:"pinned,omitempty"`
+	Archived *bool     `json:"archived,omitempty"`
+	Trashed  *bool     `json:"trashed,omitempty"`
+	Labels   *[]string `json:"labels,omitempty"`
+}
+
+// Flag filters notes on a boolean property.
+type Flag string
+
+const (
+	// FlagDefault leaves the filter to the server: any for pinned, false
+	// for archived and trashed.
+	FlagDefault Flag = ""
+	FlagTrue    Flag = "true"
+	FlagFalse   Flag = "false"
+	FlagAny     Flag = "any"
+)
+
+// Orders of ListOptions.Sort. Created and updated are newest first.
+const (
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortTitle   = "title"
+	SortManual  = "manual"
+)
+
+// ListOptions filters and orders ListNotes. The zero value lists the notes
+// that are neither archived nor trashed, newest first.
+type ListOptions struct {
+	Query    string // text in the title or content
+	Label    string
+	Color    string
+	Pinned   Flag
+	Archived Flag
+	Trashed  Flag
+	Sort     string
+}
+
+func (o *ListOptions) values() url.Values {
+	v := url.Values{}
+	if o == nil {
+		return v
+	}
+	for key, value := range map[string]string{
+		"q":        o.Query,
+		"label":    o.Label,
+		"color":    o.Color,
+		"pinned":   string(o.Pinned),
+		"archived": string(o.Archived),
+		"trashed":  string(o.Trashed),
+		"sort":     o.Sort,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v
+}
+
+// ListNotes returns the notes matching opts, which may be nil, with their
+// labels.
+func (c *Client) ListNotes(ctx context.Context, opts *ListOptions) ([]Note, error) {
+	path := "/api/notes"
+	if q := opts.values().Encode(); q != "" {
+		path += "?" + q
+	}
+	var notes []Note
+	if err := c.do(ctx, http.MethodGet, path, nil, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// GetNote returns a note. expand names related data to include: "labels",
+// "attachments", "checklist_items" and "revision_count".
+func (c *Client) GetNote(ctx context.Context, id int64, expand ...string) (*Note, error) {
+	path := notePath(id)
+	if len(expand) > 0 {
+		path += "?" + url.Values{"expand": {strings.Join(expand, ",")}}.Encode()
+	}
+	var note Note
+	if err := c.do(ctx, http.MethodGet, path, nil, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// CreateNote creates a note. It is not retried, so a failed call never
+// creates the note twice.
+func (c *Client) CreateNote(ctx context.Context, note NewNote) (*Note, error) {
+	var created Note
+	if err := c.do(ctx, http.MethodPost, "/api/notes", note, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateNote changes a note and returns it with its labels.
+func (c *Client) UpdateNote(ctx context.Context, id int64, update NoteUpdate) (*Note, error) {
+	var note Note
+	if err := c.do(ctx, http.MethodPut, notePath(id), update, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// DeleteNote deletes a note permanently. To move it to the trash, update
+// it with Trashed set instead. When a retry follows an attempt that went
+// through without an answer, the note is gone and the error is not_found.
+func (c *Client) DeleteNote(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, notePath(id), nil, nil)
+}
+
+func notePath(id int64) string {
+	return "/api/notes/" + strconv.FormatInt(id, 10)
+}
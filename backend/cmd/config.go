@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+)
+
+// runConfig runs "config print", which prints every setting with its
+// effective value and where it came from. Secrets are redacted.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: server config print [flags]")
+		return 2
+	}
+
+	fs, format := newFlagSet("config print", "config print [flags]")
+	if _, ok := parseFlags(fs, format, args[1:], 0); !ok {
+		return 2
+	}
+
+	entries := config.Get().Entries()
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		value := fmt.Sprint(e.Value)
+		if list, ok := e.Value.([]string); ok {
+			value = strings.Join(list, ",")
+		}
+		rows[i] = []string{e.Key, value, string(e.Source), e.Env}
+	}
+	return done(render(*format, entries, []string{"KEY", "VALUE", "SOURCE", "ENV"}, rows))
+}
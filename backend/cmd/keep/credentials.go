@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/tgogbera/google_keep_clone-backend/client"
+)
+
+// credentials is the stored session. The file is only readable by its
+// owner, as the refresh token gives access to the account.
+type credentials struct {
+	Server       string `json:"server"`
+	Email        string `json:"email"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// credentialsPath returns the credentials file in the user's config
+// directory, or in KEEP_CONFIG_DIR when set.
+func credentialsPath() (string, error) {
+	dir := os.Getenv("KEEP_CONFIG_DIR")
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(configDir, "keep")
+	}
+	return filepath.Join(dir, "credentials.json"), nil
+}
+
+// loadCredentials reads the stored session; it returns
+// client.ErrNotLoggedIn when there is none.
+func loadCredentials() (*credentials, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, client.ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+	var creds credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if creds.Server == "" || creds.RefreshToken == "" {
+		return nil, client.ErrNotLoggedIn
+	}
+	return &creds, nil
+}
+
+// save writes the credentials, replacing the file at once so an
+// interrupted write never loses the session.
+func (creds *credentials) save() error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".credentials-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp already makes the file 0600
+	return os.Rename(tmp.Name(), path)
+}
+
+// removeCredentials forgets the stored session.
+func removeCredentials() error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
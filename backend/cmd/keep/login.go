@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/client"
+)
+
+// defaultServer is the API of a local development server.
+const defaultServer = "http://localhost:8080"
+
+// runLogin runs "login", which logs in and stores the session.
+func runLogin(ctx context.Context, args []string) int {
+	fs, _ := newFlagSet("login", "login [-server <url>] [-password-stdin] [email]")
+	server := fs.String("server", envOr("KEEP_SERVER", defaultServer), "URL of the API (default from KEEP_SERVER)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin")
+	args, ok := parseFlags(fs, nil, args, 0, 1)
+	if !ok {
+		return 2
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	email := ""
+	if len(args) == 1 {
+		email = args[0]
+	} else if *passwordStdin {
+		fmt.Fprintln(os.Stderr, "keep: the email is required with -password-stdin")
+		return 2
+	} else {
+		fmt.Fprint(os.Stderr, "Email: ")
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return fail(fmt.Errorf("read email: %w", err))
+		}
+		email = strings.TrimSpace(line)
+	}
+
+	if !*passwordStdin {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	password, err := readPassword(in, !*passwordStdin)
+	if err != nil {
+		return fail(fmt.Errorf("read password: %w", err))
+	}
+
+	creds := &credentials{Server: strings.TrimRight(*server, "/"), Email: email}
+	c, err := client.New(creds.Server, client.WithUserAgent("keep-cli"))
+	if err != nil {
+		return fail(err)
+	}
+	user, err := c.Login(ctx, email, password)
+	if err != nil {
+		return fail(err)
+	}
+	tokens := c.Tokens()
+	creds.AccessToken, creds.RefreshToken = tokens.AccessToken, tokens.RefreshToken
+	if err := creds.save(); err != nil {
+		return fail(fmt.Errorf("store the session: %w", err))
+	}
+	fmt.Fprintf(os.Stderr, "Logged in to %s as %s\n", creds.Server, user.Email)
+	return 0
+}
+
+// runLogout runs "logout", which ends the session on the server and
+// forgets it. When the server doesn't confirm the logout the session is
+// kept, so it can be tried again; -local forgets it without the server.
+func runLogout(ctx context.Context, args []string) int {
+	fs, _ := newFlagSet("logout", "logout [-local]")
+	local := fs.Bool("local", false, "forget the session without ending it on the server")
+	if _, ok := parseFlags(fs, nil, args, 0, 0); !ok {
+		return 2
+	}
+	if *local {
+		if err := removeCredentials(); err != nil {
+			return fail(fmt.Errorf("forget the session: %w", err))
+		}
+		fmt.Fprintln(os.Stderr, "The session was forgotten; it stays valid on the server until it expires")
+		return 0
+	}
+
+	c, _, err := session()
+	if errors.Is(err, client.ErrNotLoggedIn) {
+		return 0
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if err := c.Logout(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "keep: the server did not end the session, which is kept: %s\n", message(err))
+		fmt.Fprintln(os.Stderr, `Run "keep logout" again, or "keep logout -local" to forget it anyway`)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "Logged out")
+	return 0
+}
+
+// readPassword reads a line. With hide, terminal echo is turned off while
+// typing where stty is available.
+func readPassword(in *bufio.Reader, hide bool) (string, error) {
+	if hide && stty("-echo") {
+		defer func() {
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("the password is empty")
+	}
+	return password, nil
+}
+
+// stty changes the settings of the terminal on stdin; it reports false
+// when stdin isn't a terminal or stty is missing.
+func stty(setting string) bool {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	cmd := exec.Command("stty", setting)
+	cmd.Stdin = os.Stdin
+	return cmd.Run() == nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,120 @@
+// Command keep manages notes from the terminal through the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/tgogbera/google_keep_clone-backend/client"
+)
+
+const usage = `usage: keep <command> [flags]
+
+commands:
+  login [email]        log in and store the session
+  logout               end the session
+  add <title>          create a note
+  ls                   list notes
+  show <id>            print a note
+  edit <id>            edit a note in $EDITOR
+  rm <id>...           move notes to the trash
+
+The session is stored in the user's config directory (KEEP_CONFIG_DIR
+overrides it). Commands printing notes accept -o table|json. Run
+"keep <command> -h" for the flags of a command.`
+
+// commands maps the first argument to its implementation, which returns
+// the exit code.
+var commands = map[string]func(ctx context.Context, args []string) int{
+	"login":  runLogin,
+	"logout": runLogout,
+	"add":    runAdd,
+	"ls":     runList,
+	"show":   runShow,
+	"edit":   runEdit,
+	"rm":     runRemove,
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		fmt.Println(usage)
+		return
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[2:])
+	stop()
+	os.Exit(code)
+}
+
+// session returns a client with the stored session. Tokens the client
+// refreshes are stored again, and the session is forgotten when the
+// server ends it.
+func session() (*client.Client, *credentials, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := client.New(creds.Server,
+		client.WithUserAgent("keep-cli"),
+		client.WithTokens(client.Tokens{AccessToken: creds.AccessToken, RefreshToken: creds.RefreshToken}),
+		client.WithTokenHook(func(t client.Tokens) {
+			var err error
+			if t.RefreshToken == "" {
+				err = removeCredentials()
+			} else {
+				creds.AccessToken, creds.RefreshToken = t.AccessToken, t.RefreshToken
+				err = creds.save()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "keep: failed to store the session: %v\n", err)
+			}
+		}),
+	)
+	return c, creds, err
+}
+
+// fail prints err and returns the exit code of a failed command.
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "keep: %s\n", message(err))
+	return 1
+}
+
+// message describes err for people: API errors by their detail and
+// invalid fields, without the status and code.
+func message(err error) string {
+	var apiErr *client.Error
+	switch {
+	case errors.Is(err, client.ErrNotLoggedIn):
+		return `not logged in: run "keep login"`
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.As(err, &apiErr):
+		if len(apiErr.Fields) < 2 {
+			return apiErr.Detail
+		}
+		msg := apiErr.Detail
+		for _, f := range apiErr.Fields {
+			msg += "\n  " + f.Field + " " + f.Message
+		}
+		return msg
+	default:
+		return err.Error()
+	}
+}
+
+// done returns the exit code after writing the output.
+func done(err error) int {
+	if err != nil {
+		return fail(err)
+	}
+	return 0
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/client"
+)
+
+// runAdd runs "add", which creates a note.
+func runAdd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("add", "add [flags] <title>")
+	body := fs.String("m", "", `content of the note; "-" reads it from stdin`)
+	color := fs.String("color", "", "color of the note, e.g. yellow")
+	pin := fs.Bool("pin", false, "pin the note")
+	var labels listFlag
+	fs.Var(&labels, "l", "label of the note; repeat for several")
+	args, ok := parseFlags(fs, format, args, 1, 1)
+	if !ok {
+		return 2
+	}
+
+	content := *body
+	if content == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fail(fmt.Errorf("read content: %w", err))
+		}
+		content = string(data)
+	}
+
+	c, _, err := session()
+	if err != nil {
+		return fail(err)
+	}
+	note, err := c.CreateNote(ctx, client.NewNote{
+		Title:   args[0],
+		Content: content,
+		Color:   *color,
+		Pinned:  *pin,
+		Labels:  labels,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	return done(renderNotes(*format, note, []client.Note{*note}))
+}
+
+// runList runs "ls", which lists notes.
+func runList(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("ls", "ls [flags]")
+	opts := client.ListOptions{}
+	fs.StringVar(&opts.Label, "label", "", "only notes with this label")
+	fs.StringVar(&opts.Query, "q", "", "only notes containing this text")
+	fs.StringVar(&opts.Color, "color", "", "only notes of this color")
+	fs.StringVar(&opts.Sort, "sort", "", "order: created, updated, title or manual (default created)")
+	pinned := fs.Bool("pinned", false, "only pinned notes")
+	archived := fs.Bool("archived", false, "archived notes instead of the others")
+	trashed := fs.Bool("trashed", false, "notes in the trash instead of the others")
+	if _, ok := parseFlags(fs, format, args, 0, 0); !ok {
+		return 2
+	}
+	if *pinned {
+		opts.Pinned = client.FlagTrue
+	}
+	if *archived {
+		opts.Archived = client.FlagTrue
+	}
+	if *trashed {
+		opts.Trashed = client.FlagTrue
+	}
+
+	c, _, err := session()
+	if err != nil {
+		return fail(err)
+	}
+	notes, err := c.ListNotes(ctx, &opts)
+	if err != nil {
+		return fail(err)
+	}
+	if notes == nil {
+		notes = []client.Note{} // [] rather than null in JSON
+	}
+	return done(renderNotes(*format, notes, notes))
+}
+
+// runShow runs "show", which prints a note with its content.
+func runShow(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("show", "show [-o table|json] <id>")
+	args, ok := parseFlags(fs, format, args, 1, 1)
+	if !ok {
+		return 2
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	c, _, err := session()
+	if err != nil {
+		return fail(err)
+	}
+	note, err := c.GetNote(ctx, id, "labels")
+	if err != nil {
+		return fail(err)
+	}
+	return done(printNote(*format, note))
+}
+
+// runEdit runs "edit", which opens a note in $VISUAL or $EDITOR. The first
+// line of the file is the title, the text after the blank line below it
+// the content.
+func runEdit(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("edit", "edit [-o table|json] <id>")
+	args, ok := parseFlags(fs, format, args, 1, 1)
+	if !ok {
+		return 2
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	c, _, err := session()
+	if err != nil {
+		return fail(err)
+	}
+	note, err := c.GetNote(ctx, id, "labels")
+	if err != nil {
+		return fail(err)
+	}
+
+	title, content, err := editInEditor(note)
+	if err != nil {
+		return fail(err)
+	}
+	var update client.NoteUpdate
+	if title != note.Title {
+		update.Title = &title
+	}
+	if content != note.Content {
+		update.Content = &content
+	}
+	if update.Title == nil && update.Content == nil {
+		fmt.Fprintln(os.Stderr, "No changes")
+		return 0
+	}
+	if title == "" {
+		return fail(errors.New("the title on the first line is empty; the note was not changed"))
+	}
+
+	updated, err := c.UpdateNote(ctx, id, update)
+	if err != nil {
+		return fail(err)
+	}
+	return done(printNote(*format, updated))
+}
+
+// runRemove runs "rm", which moves notes to the trash or deletes them.
+func runRemove(ctx context.Context, args []string) int {
+	fs, _ := newFlagSet("rm", "rm [-permanent] <id>...")
+	permanent := fs.Bool("permanent", false, "delete the notes instead of moving them to the trash")
+	args, ok := parseFlags(fs, nil, args, 1, -1)
+	if !ok {
+		return 2
+	}
+	ids := make([]int64, len(args))
+	for i, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return fail(err)
+		}
+		ids[i] = id
+	}
+
+	c, _, err := session()
+	if err != nil {
+		return fail(err)
+	}
+	code := 0
+	trashed := true
+	for _, id := range ids {
+		if *permanent {
+			err = c.DeleteNote(ctx, id)
+		} else {
+			_, err = c.UpdateNote(ctx, id, client.NoteUpdate{Trashed: &trashed})
+		}
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "keep: note %d: %s\n", id, message(err))
+			code = 1
+		case *permanent:
+			fmt.Fprintf(os.Stderr, "Deleted note %d\n", id)
+		default:
+			fmt.Fprintf(os.Stderr, "Moved note %d to the trash\n", id)
+		}
+		if errors.Is(err, client.ErrNotLoggedIn) || errors.Is(err, context.Canceled) {
+			break
+		}
+	}
+	return code
+}
+
+// editInEditor writes the note to a temporary file, runs the editor on it
+// and returns the title and content read back.
+func editInEditor(note *client.Note) (title, content string, err error) {
+	editor := strings.Fields(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+
+	f, err := os.CreateTemp("", fmt.Sprintf("keep-%d-*.md", note.ID))
+	if err != nil {
+		return "", "", err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	_, err = f.WriteString(note.Title + "\n\n" + note.Content + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("editor %s: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	first, rest, _ := strings.Cut(text, "\n")
+	rest = strings.TrimPrefix(rest, "\n")
+	return strings.TrimSpace(first), strings.TrimSuffix(rest, "\n"), nil
+}
+
+// renderNotes writes v as JSON, or notes as a table.
+func renderNotes(format string, v interface{}, notes []client.Note) error {
+	header := []string{"ID", "PINNED", "TITLE", "LABELS", "UPDATED"}
+	rows := make([][]string, len(notes))
+	for i, n := range notes {
+		pinned := ""
+		if n.Pinned {
+			pinned = "yes"
+		}
+		rows[i] = []string{
+			strconv.FormatInt(n.ID, 10),
+			pinned,
+			truncate(n.Title, 50),
+			strings.Join(labelNames(n), ", "),
+			formatTime(n.UpdatedAt),
+		}
+	}
+	return render(format, v, header, rows)
+}
+
+// printNote writes a note as JSON, or as its title, properties and
+// content.
+func printNote(format string, note *client.Note) error {
+	if format == formatJSON {
+		return render(format, note, nil, nil)
+	}
+
+	var props []string
+	if labels := labelNames(*note); len(labels) > 0 {
+		props = append(props, "labels: "+strings.Join(labels, ", "))
+	}
+	if note.Color != "" && note.Color != "default" {
+		props = append(props, "color: "+note.Color)
+	}
+	for _, p := range []struct {
+		set  bool
+		name string
+	}{{note.Pinned, "pinned"}, {note.Archived, "archived"}, {note.TrashedAt != nil, "in the trash"}} {
+		if p.set {
+			props = append(props, p.name)
+		}
+	}
+	props = append(props, "updated "+formatTime(note.UpdatedAt))
+
+	_, err := fmt.Printf("%s\n%s\n\n%s\n", note.Title, strings.Join(props, " · "), note.Content)
+	return err
+}
+
+func labelNames(n client.Note) []string {
+	names := make([]string, len(n.Labels))
+	for i, l := range n.Labels {
+		names[i] = l.Name
+	}
+	return names
+}
+
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid note ID %q", s)
+	}
+	return id, nil
+}
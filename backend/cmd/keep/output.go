@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats accepted by the -o flag.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// newFlagSet returns a flag set for a command with the -o flag.
+func newFlagSet(name, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: keep %s\n\nflags:\n", usage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("o", formatTable, "output format: table or json")
+	return fs, format
+}
+
+// parseFlags parses args, which may mix flags and positional arguments,
+// and checks the -o flag and that there are between min and max
+// positional arguments (max -1 for no limit). It returns the positional
+// arguments, or false after printing the problem.
+func parseFlags(fs *flag.FlagSet, format *string, args []string, min, max int) ([]string, bool) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, false
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if format != nil && *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(os.Stderr, "invalid output format %q: expected table or json\n", *format)
+		return nil, false
+	}
+	if len(rest) < min || (max >= 0 && len(rest) > max) {
+		fs.Usage()
+		return nil, false
+	}
+	return rest, true
+}
+
+// listFlag is a flag that can be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// render writes v as indented JSON, or the header and rows as an aligned
+// table.
+func render(format string, v interface{}, header []string, rows [][]string) error {
+	if format == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// formatTime renders t in the local time zone, as notes are personal.
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// truncate shortens s to n runes for a table cell, on one line.
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -1,133 +1,153 @@
 package main
 
 import (
-	"net/http"
-
-	"github.com/gin-gonic/gin"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	_ "time/tzdata" // timezone database for profile validation in minimal images
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
 	"github.com/tgogbera/google_keep_clone-backend/internal/config"
 	"github.com/tgogbera/google_keep_clone-backend/internal/database"
-	"github.com/tgogbera/google_keep_clone-backend/internal/handlers"
 	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/migrations"
 	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
 )
 
+const usage = `usage: server [flags] [command]
+
+commands:
+  serve              start the API server (default)
+  migrate            apply, revert or list schema migrations
+  user               create, list, disable and enable users, set roles, reset passwords
+  tokens revoke      end the sessions of a user or of everybody
+  notes purge-trash  permanently delete trashed notes
+  stats              show instance-wide counts
+  openapi [check]    print the OpenAPI document, or check it covers every route
+  config print       show the effective configuration
+
+Settings come from the config file given by -config or CONFIG_FILE, the
+environment and the flags, each overriding the one before. Run "server -h"
+for the flags and "server <command> -h" for the options of a command.`
+
+// commands maps the first argument to its implementation, which returns
+// the exit code.
+var commands = map[string]func(args []string) int{
+	"serve":   runServe,
+	"migrate": runMigrate,
+	"user":    runUser,
+	"tokens":  runTokens,
+	"notes":   runNotes,
+	"stats":   runStats,
+	"openapi": runOpenAPI,
+	"config":  runConfig,
+}
+
 func main() {
-	// Load configuration (based on environment variables)
-	config.Load()
+	// Flags before the command override the config file and environment
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "%s\n\nflags:\n", usage)
+		fs.PrintDefaults()
+	}
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file (env CONFIG_FILE)")
+	flags := config.Flags(fs)
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	name, args := "serve", fs.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		fmt.Println(usage)
+		return
+	}
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", name, usage)
+		os.Exit(2)
+	}
+
+	if err := config.Load(config.Options{File: *file, Flags: flags}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	cfg := config.Get()
 
-	// Initialize logger
-	logger.Init(logger.Config{
+	// Initialize logger. Other commands log to stderr so their output can
+	// be piped.
+	logConfig := logger.Config{
 		Level:       logger.LogLevel(cfg.LogLevel),
 		Environment: string(cfg.Environment),
-	})
+	}
+	if name != "serve" {
+		logConfig.Output = os.Stderr
+		if cfg.Source("log.level") == config.SourceDefault {
+			logConfig.Level = logger.LevelWarn
+		}
+	}
+	logger.Init(logConfig)
 
 	// Log any config warnings that were collected before logger was available
 	for _, warning := range cfg.Warnings {
 		logger.Warn(warning)
 	}
 
-	// Set Gin mode based on environment
-	if cfg.Environment == config.EnvProduction {
-		gin.SetMode(gin.ReleaseMode)
-	}
+	os.Exit(run(args))
+}
 
-	// Initialize database
-	if err := database.InitDB(); err != nil {
-		logger.WithError(err).Fatal("Failed to initialize database")
+// connect opens the database for a command other than migrate and checks
+// the schema is current.
+func connect() bool {
+	if err := database.Connect(); err != nil {
+		logger.WithError(err).Error("Failed to connect to database")
+		return false
 	}
 
-	// Create router without default middleware (we'll add our own)
-	router := gin.New()
-
-	// Add recovery middleware (handles panics)
-	router.Use(logger.RecoveryLogger())
-
-	// Add request logging middleware
-	router.Use(logger.RequestLogger())
-
-	// CORS middleware
-	router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	// Health check
-	router.GET("/ping", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"message": "pong",
-		})
-	})
-
-	// Auth routes
-	api := router.Group("/api")
-	{
-		api.POST("/register", handlers.Register)
-		api.POST("/login", handlers.Login)
-		api.POST("/refresh", handlers.Refresh)
-		api.POST("/logout", handlers.Logout)
+	pending, err := migrations.Pending(context.Background(), database.DB)
+	if err != nil {
+		logger.WithError(err).Error("Failed to check migrations")
+		return false
 	}
+	if len(pending) > 0 {
+		logger.Errorf("Database schema is behind by %d migration(s): run \"migrate up\"", len(pending))
+		return false
+	}
+	return true
+}
 
-	// Protected routes example
-	protected := api.Group("/")
-	protected.Use(handlers.AuthMiddleware())
-	{
-
-		protected.GET("/me", func(c *gin.Context) {
-			// Safely extract user_id from context
-			userID, exists := c.Get("user_id")
-			if !exists {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id not found in context"})
-				return
-			}
-
-			// Type assert to int64 (claims.UserID is int64)
-			userIDInt64, ok := userID.(int64)
-			if !ok {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user_id type"})
-				return
-			}
-
-			// Validate user_id is positive
-			if userIDInt64 <= 0 {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
-				return
-			}
-
-			// Load full user from database
-			var user models.User
-			if err := database.DB.First(&user, userIDInt64).Error; err != nil {
-				if err.Error() == "record not found" {
-					c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-					return
-				}
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user"})
-				return
-			}
-
-			c.JSON(http.StatusOK, user.ToDTO())
-		})
-
-		// Note routes
-		protected.POST("/notes", handlers.CreateNote)
-		protected.GET("/notes", handlers.GetAllNotes)
-		protected.PUT("/notes/:id", handlers.UpdateNote)
-		protected.DELETE("/notes/:id", handlers.DeleteNote)
+// record adds an action of the operator to the audit log. Events from the
+// command line have no actor; their user agent is "cli". userID is the
+// account acted on, 0 for none.
+func record(action string, userID int64, before, after map[string]interface{}) {
+	event := models.AuditEvent{Action: action, Before: before, After: after}
+	if userID != 0 {
+		event.UserID = audit.ID(userID)
+		event.TargetType = models.AuditTargetUser
+		event.TargetID = audit.ID(userID)
 	}
+	ctx := audit.WithSource(context.Background(), audit.Source{UserAgent: "cli"})
+	audit.New(repository.NewAuditRepository(database.DB)).Record(ctx, event)
+}
+
+// fail logs err and returns the exit code for a failed command.
+func fail(err error, msg string) int {
+	logger.WithError(err).Error(msg)
+	return 1
+}
 
-	// Start server with configured port
-	addr := ":" + cfg.Port
-	logger.WithField("address", addr).Info("Starting server")
-	if err := router.Run(addr); err != nil {
-		logger.WithError(err).Fatal("Failed to start server")
+// done returns the exit code after writing the output.
+func done(err error) int {
+	if err != nil {
+		return fail(err, "Failed to write output")
 	}
+	return 0
 }
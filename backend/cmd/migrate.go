@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/migrations"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    revert the last n applied migrations (default 1)
+  status      list migrations and when they were applied
+
+Every command accepts -o table|json.`
+
+// migrationResult is one migration applied or reverted by up or down.
+type migrationResult struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+}
+
+// runMigrate runs "migrate up|down|status".
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	usage, positional := "migrate "+command+" [flags]", 0
+	if command == "down" {
+		usage, positional = usage+" [n]", -1
+	}
+	fs, format := newFlagSet("migrate "+command, usage)
+	args, ok := parseFlags(fs, format, args[1:], positional)
+	if !ok {
+		return 2
+	}
+
+	steps := 1
+	if command == "down" {
+		switch {
+		case len(args) > 1:
+			fs.Usage()
+			return 2
+		case len(args) == 1:
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid number of migrations %q\n", args[0])
+				return 2
+			}
+			steps = n
+		}
+	}
+
+	if err := database.Connect(); err != nil {
+		logger.WithError(err).Error("Failed to connect to database")
+		return 1
+	}
+
+	ctx := context.Background()
+	if command == "status" {
+		statuses, err := migrations.List(ctx, database.DB)
+		if err != nil {
+			return fail(err, "Failed to read migration status")
+		}
+
+		type statusResult struct {
+			Version   int     `json:"version"`
+			Name      string  `json:"name"`
+			AppliedAt *string `json:"applied_at"`
+		}
+		results := []statusResult{}
+		var rows [][]string
+		for _, s := range statuses {
+			applied := "pending"
+			result := statusResult{Version: s.Version, Name: s.Name}
+			if s.AppliedAt != nil {
+				applied = formatTime(s.AppliedAt)
+				result.AppliedAt = &applied
+			}
+			results = append(results, result)
+			rows = append(rows, []string{fmt.Sprintf("%04d", s.Version), s.Name, applied})
+		}
+		return done(render(*format, results, []string{"VERSION", "NAME", "APPLIED"}, rows))
+	}
+
+	var ran []migrations.Migration
+	var err error
+	action := "applied"
+	if command == "up" {
+		ran, err = migrations.Up(ctx, database.DB)
+	} else {
+		action = "reverted"
+		ran, err = migrations.Down(ctx, database.DB, steps)
+	}
+
+	// Report what ran even if a later migration failed
+	results := []migrationResult{}
+	var rows [][]string
+	for _, m := range ran {
+		results = append(results, migrationResult{m.Version, m.Name, action})
+		rows = append(rows, []string{fmt.Sprintf("%04d", m.Version), m.Name, action})
+	}
+	if len(ran) > 0 || *format == formatJSON {
+		if code := done(render(*format, results, []string{"VERSION", "NAME", "ACTION"}, rows)); code != 0 {
+			return code
+		}
+	}
+	if err != nil {
+		return fail(err, "Migration failed")
+	}
+	if len(ran) == 0 && *format == formatTable {
+		fmt.Println("nothing to do")
+	}
+	return 0
+}
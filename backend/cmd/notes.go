@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/admin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+)
+
+// runNotes runs "notes purge-trash", which permanently deletes notes from
+// the trash.
+func runNotes(args []string) int {
+	if len(args) == 0 || args[0] != "purge-trash" {
+		fmt.Fprintln(os.Stderr, "usage: server notes purge-trash [flags]")
+		return 2
+	}
+
+	fs, format := newFlagSet("notes purge-trash", "notes purge-trash [flags]")
+	olderThan := fs.String("older-than", "0", "only notes trashed longer ago than this, e.g. 30d or 12h; 0 empties the trash")
+	userRef := fs.String("user", "", "only the notes of this user (ID or email)")
+	if _, ok := parseFlags(fs, format, args[1:], 0); !ok {
+		return 2
+	}
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -older-than %q: %v\n", *olderThan, err)
+		return 2
+	}
+	if !connect() {
+		return 1
+	}
+
+	ctx := context.Background()
+	var userID int64
+	if *userRef != "" {
+		user, err := admin.FindUser(ctx, database.DB, *userRef)
+		if err != nil {
+			return fail(err, "Failed to find user")
+		}
+		userID = user.ID
+	}
+
+	cutoff := time.Now().Add(-age)
+	deleted, err := admin.PurgeTrash(ctx, database.DB, cutoff, userID)
+	if err != nil {
+		return fail(err, "Failed to purge trash")
+	}
+	record(audit.ActionAdminPurgeTrash, userID, nil, map[string]interface{}{"trashed_before": cutoff, "deleted": deleted})
+
+	result := struct {
+		TrashedBefore time.Time `json:"trashed_before"`
+		Deleted       int64     `json:"deleted"`
+	}{cutoff.UTC(), deleted}
+	header, rows := fields("trashed_before", formatTime(&cutoff), "deleted", strconv.FormatInt(deleted, 10))
+	return done(render(*format, result, header, rows))
+}
+
+// parseAge parses a duration that may also be given in days, e.g. "30d".
+func parseAge(s string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if age < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return age, nil
+}
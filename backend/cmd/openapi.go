@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/health"
+	"github.com/tgogbera/google_keep_clone-backend/internal/openapi"
+	"github.com/tgogbera/google_keep_clone-backend/internal/ratelimit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/server"
+)
+
+// runOpenAPI runs "openapi", which prints the OpenAPI document, and
+// "openapi check", which fails when the document and the routes of the
+// server disagree. The check needs no database.
+func runOpenAPI(args []string) int {
+	switch {
+	case len(args) == 0:
+		_, err := os.Stdout.Write(append(openapi.JSON(), '\n'))
+		return done(err)
+	case len(args) == 1 && args[0] == "check":
+		return checkOpenAPI()
+	default:
+		fmt.Fprintln(os.Stderr, "usage: server openapi [check]")
+		return 2
+	}
+}
+
+// checkOpenAPI builds the router the way serve does, with every optional
+// route enabled, and compares its routes with the document.
+func checkOpenAPI() int {
+	gin.SetMode(gin.ReleaseMode)
+	cfg := *config.Get()
+	cfg.MetricsAddr, cfg.MetricsToken = "", "check"
+	router := server.NewRouter(&cfg, nil, ratelimit.New(ratelimit.NewMemoryStore()), health.NewChecker())
+
+	missing, stale, err := openapi.Compare(router.Routes())
+	if err != nil {
+		return fail(err, "Failed to read the OpenAPI document")
+	}
+	for _, op := range missing {
+		fmt.Fprintf(os.Stderr, "not documented: %s\n", op)
+	}
+	for _, op := range stale {
+		fmt.Fprintf(os.Stderr, "documented but not served: %s\n", op)
+	}
+	if len(missing) > 0 || len(stale) > 0 {
+		fmt.Fprintln(os.Stderr, "update internal/openapi/openapi.yaml")
+		return 1
+	}
+	fmt.Printf("%d routes documented\n", len(router.Routes()))
+	return 0
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats accepted by the -o flag.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// newFlagSet returns a flag set for a subcommand with the -o flag. Parse
+// errors are reported by the caller through parseFlags.
+func newFlagSet(name, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server %s\n\nflags:\n", usage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("o", formatTable, "output format: table or json")
+	return fs, format
+}
+
+// parseFlags parses args, which may mix flags and positional arguments,
+// and checks the -o flag and the number of positional arguments; -1 leaves
+// the latter to the caller. It returns the positional arguments, or false
+// after printing the problem.
+func parseFlags(fs *flag.FlagSet, format *string, args []string, positional int) ([]string, bool) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, false
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(os.Stderr, "invalid output format %q: expected table or json\n", *format)
+		return nil, false
+	}
+	if positional >= 0 && len(rest) != positional {
+		fs.Usage()
+		return nil, false
+	}
+	return rest, true
+}
+
+// render writes v as indented JSON, or the header and rows as an aligned
+// table.
+func render(format string, v interface{}, header []string, rows [][]string) error {
+	if format == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// fields renders a single record as FIELD/VALUE rows.
+func fields(pairs ...string) ([]string, [][]string) {
+	var rows [][]string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		rows = append(rows, []string{pairs[i], pairs[i+1]})
+	}
+	return []string{"FIELD", "VALUE"}, rows
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatBytes renders a size with binary units, e.g. 1.5 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/handlers"
+	"github.com/tgogbera/google_keep_clone-backend/internal/health"
+	"github.com/tgogbera/google_keep_clone-backend/internal/jobs"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/mailer"
+	"github.com/tgogbera/google_keep_clone-backend/internal/metrics"
+	"github.com/tgogbera/google_keep_clone-backend/internal/migrations"
+	"github.com/tgogbera/google_keep_clone-backend/internal/ratelimit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/server"
+	"github.com/tgogbera/google_keep_clone-backend/internal/tracing"
+)
+
+// runServe starts the API server and runs it until SIGINT or SIGTERM, then
+// shuts down gracefully.
+func runServe(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: server serve")
+		return 2
+	}
+
+	cfg := config.Get()
+
+	// Set Gin mode based on environment
+	if cfg.Environment == config.EnvProduction {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// Initialize mailer (logs emails when SMTP is not configured)
+	mailer.Init(mailer.Config{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize tracing")
+	}
+
+	if err := handlers.RegisterValidators(); err != nil {
+		logger.WithError(err).Fatal("Failed to register request validators")
+	}
+
+	// Initialize database
+	if err := database.InitDB(context.Background()); err != nil {
+		logger.WithError(err).Fatal("Failed to initialize database")
+	}
+	if sqlDB, err := database.DB.DB(); err != nil {
+		logger.WithError(err).Fatal("Failed to access database pool")
+	} else if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		logger.WithError(err).Fatal("Failed to register database metrics")
+	}
+
+	// Rate limiting (Postgres store shares limits across instances)
+	var limitStore ratelimit.Store
+	if cfg.RateLimitStore == "postgres" {
+		pgStore := ratelimit.NewPostgresStore(database.DB)
+		runPeriodic("rate_limit_cleanup", func(ctx context.Context) error {
+			return pgStore.Cleanup(ctx, 24*time.Hour)
+		})
+		limitStore = pgStore
+	} else {
+		limitStore = ratelimit.NewMemoryStore()
+	}
+	limiter := ratelimit.New(limitStore)
+	logger.WithField("store", cfg.RateLimitStore).Info("Rate limiting enabled")
+
+	// Background jobs
+	runPeriodic("purge_deleted_accounts", func(ctx context.Context) error {
+		return jobs.PurgeDeletedAccounts(ctx, database.DB)
+	})
+	runPeriodic("rebalance_note_positions", func(ctx context.Context) error {
+		return jobs.RebalanceNotePositions(ctx, database.DB)
+	})
+
+	// Dependencies checked by /readyz
+	readiness := health.NewChecker(
+		health.Check{Name: "database", Run: func(ctx context.Context) error {
+			sqlDB, err := database.DB.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		}},
+		health.Check{Name: "migrations", Run: func(ctx context.Context) error {
+			pending, err := migrations.Check(ctx, database.DB)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("%d pending, next is %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+			}
+			return nil
+		}},
+	)
+	if cfg.SMTPHost != "" {
+		// Only emails fail without SMTP, so the API keeps serving
+		readiness.Add(health.Check{Name: "smtp", Optional: true, Run: mailer.Check})
+	}
+
+	// Metrics on their own listener, or token protected on the API port
+	var metricsServer *http.Server
+	switch {
+	case cfg.MetricsAddr != "":
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(cfg.MetricsToken))
+		metricsServer = &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			logger.WithField("address", cfg.MetricsAddr).Info("Serving metrics")
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.WithError(err).Fatal("Failed to serve metrics")
+			}
+		}()
+	case cfg.MetricsToken != "":
+		// Served by the router
+	default:
+		logger.Info("Metrics disabled: set METRICS_ADDR or METRICS_TOKEN to serve /metrics")
+	}
+
+	router := server.NewRouter(cfg, database.DB, limiter, readiness)
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+	}
+
+	// A second signal during the shutdown kills the process at once
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		logger.WithField("address", server.Addr).Info("Starting server")
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		logger.WithError(err).Error("Failed to start server")
+		return 1
+	case <-ctx.Done():
+		stop()
+	}
+
+	readiness.SetShuttingDown()
+	if cfg.ShutdownDelay > 0 {
+		logger.WithField("delay", cfg.ShutdownDelay.String()).Info("Reporting not ready before shutting down")
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	logger.WithField("timeout", cfg.ShutdownTimeout.String()).Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	return shutdown(shutdownCtx, server, metricsServer, shutdownTracing)
+}
+
+// shutdown stops accepting connections and waits for the requests in
+// progress, then stops the background jobs, flushes traces and closes the
+// database. Whatever is still running when ctx ends is cut off. It returns
+// the exit code.
+func shutdown(ctx context.Context, server, metricsServer *http.Server, shutdownTracing func(context.Context) error) int {
+	code := 0
+	if err := server.Shutdown(ctx); err != nil {
+		logger.WithError(err).Warn("Requests still running at the shutdown deadline were cut off")
+		server.Close()
+		code = 1
+	}
+	logger.Info("HTTP server stopped")
+
+	if err := jobs.Shutdown(ctx); err != nil {
+		logger.WithError(err).Warn("Background jobs still running at the shutdown deadline")
+		code = 1
+	} else {
+		logger.Info("Background jobs stopped")
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			metricsServer.Close()
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.WithError(err).Warn("Failed to flush traces")
+	}
+
+	if err := database.Close(); err != nil {
+		logger.WithError(err).Warn("Failed to close database")
+		code = 1
+	}
+	logger.Info("Shutdown complete")
+	return code
+}
+
+// runPeriodic starts a background job running fn every hour until shutdown.
+func runPeriodic(name string, fn func(ctx context.Context) error) {
+	jobs.Go(func(ctx context.Context) {
+		jobs.RunPeriodic(ctx, name, time.Hour, fn)
+	})
+}
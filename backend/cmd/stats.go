@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/admin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+)
+
+// runStats runs "stats", which prints instance-wide counts.
+func runStats(args []string) int {
+	fs, format := newFlagSet("stats", "stats [flags]")
+	if _, ok := parseFlags(fs, format, args, 0); !ok {
+		return 2
+	}
+	if !connect() {
+		return 1
+	}
+
+	stats, err := admin.GetStats(context.Background(), database.DB)
+	if err != nil {
+		return fail(err, "Failed to compute stats")
+	}
+
+	n := func(v int64) string { return strconv.FormatInt(v, 10) }
+	header, rows := fields(
+		"users", n(stats.Users),
+		"disabled_users", n(stats.DisabledUsers),
+		"pending_deletion", n(stats.PendingDeletion),
+		"notes", n(stats.Notes),
+		"archived_notes", n(stats.ArchivedNotes),
+		"trashed_notes", n(stats.TrashedNotes),
+		"labels", n(stats.Labels),
+		"attachments", n(stats.Attachments),
+		"storage", formatBytes(stats.StorageBytes),
+		"active_sessions", n(stats.ActiveSessions),
+		"active_import_jobs", n(stats.ActiveImportJobs),
+	)
+	return done(render(*format, stats, header, rows))
+}
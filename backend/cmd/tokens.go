@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/admin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+)
+
+// runTokens runs "tokens revoke", which ends sessions by revoking refresh
+// tokens. Access tokens already issued stay valid until they expire.
+func runTokens(args []string) int {
+	if len(args) == 0 || args[0] != "revoke" {
+		fmt.Fprintln(os.Stderr, "usage: server tokens revoke [-all] [flags] [<user>]")
+		return 2
+	}
+
+	fs, format := newFlagSet("tokens revoke", "tokens revoke [-all] [flags] [<user>]")
+	all := fs.Bool("all", false, "revoke the sessions of every user")
+	args, ok := parseFlags(fs, format, args[1:], -1)
+	if !ok {
+		return 2
+	}
+	if *all == (len(args) == 1) || len(args) > 1 {
+		fs.Usage()
+		return 2
+	}
+	if !connect() {
+		return 1
+	}
+
+	ctx := context.Background()
+	var userID int64
+	if !*all {
+		user, err := admin.FindUser(ctx, database.DB, args[0])
+		if err != nil {
+			return fail(err, "Failed to find user")
+		}
+		userID = user.ID
+	}
+
+	revoked, err := admin.RevokeTokens(ctx, database.DB, userID)
+	if err != nil {
+		return fail(err, "Failed to revoke tokens")
+	}
+	record(audit.ActionAdminLogout, userID, nil, map[string]interface{}{"revoked": revoked})
+
+	result := struct {
+		UserID  int64 `json:"user_id,omitempty"`
+		Revoked int64 `json:"revoked"`
+	}{userID, revoked}
+	pairs := []string{"revoked", strconv.FormatInt(revoked, 10)}
+	if userID != 0 {
+		pairs = append([]string{"user_id", strconv.FormatInt(userID, 10)}, pairs...)
+	}
+	header, rows := fields(pairs...)
+	return done(render(*format, result, header, rows))
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/admin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/ratelimit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/server"
+)
+
+const userUsage = `usage: server user <command>
+
+commands:
+  create -email <email> [-display-name <name>] [-role user|admin] [-password-stdin]
+  list [-search <text>] [-limit <n>] [-offset <n>]
+  disable <user>
+  enable <user>
+  set-role <user> user|admin
+  reset-password [-password-stdin] <user>
+
+<user> is a user ID or email address. Without -password-stdin a random
+password is generated and printed. Every command accepts -o table|json.`
+
+// runUser runs "user create|list|disable|enable|set-role|reset-password".
+func runUser(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "create":
+		return userCreate(args[1:])
+	case "list":
+		return userList(args[1:])
+	case "disable", "enable":
+		return userSetDisabled(args[0], args[1:])
+	case "set-role":
+		return userSetRole(args[1:])
+	case "reset-password":
+		return userResetPassword(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+}
+
+func userCreate(args []string) int {
+	fs, format := newFlagSet("user create", "user create -email <email> [flags]")
+	email := fs.String("email", "", "email address (required)")
+	displayName := fs.String("display-name", "", "display name")
+	role := fs.String("role", "user", "role: user or admin")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin")
+	if _, ok := parseFlags(fs, format, args, 0); !ok {
+		return 2
+	}
+	if *email == "" {
+		fmt.Fprintln(os.Stderr, "-email is required")
+		return 2
+	}
+
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return fail(err, "Failed to read password")
+	}
+	if !connect() {
+		return 1
+	}
+
+	user, err := admin.CreateUser(context.Background(), database.DB, admin.NewUser{
+		Email:       *email,
+		Password:    password,
+		DisplayName: *displayName,
+		Role:        *role,
+	})
+	if err != nil {
+		return fail(err, "Failed to create user")
+	}
+	record(audit.ActionAdminCreateUser, user.ID, nil, map[string]interface{}{"email": user.Email, "role": user.Role})
+
+	result := credentials{ID: user.ID, Email: user.Email}
+	if generated {
+		result.Password = password
+	}
+	return result.render(*format)
+}
+
+func userList(args []string) int {
+	fs, format := newFlagSet("user list", "user list [flags]")
+	search := fs.String("search", "", "only users whose email or display name contains this text")
+	limit := fs.Int("limit", 100, "maximum number of users, 0 for all")
+	offset := fs.Int("offset", 0, "number of users to skip")
+	if _, ok := parseFlags(fs, format, args, 0); !ok {
+		return 2
+	}
+	if !connect() {
+		return 1
+	}
+
+	users, err := admin.ListUsers(context.Background(), database.DB, admin.UserQuery{
+		Search: *search,
+		Limit:  *limit,
+		Offset: *offset,
+	})
+	if err != nil {
+		return fail(err, "Failed to list users")
+	}
+
+	var rows [][]string
+	for _, u := range users {
+		status := "active"
+		switch {
+		case u.DisabledAt != nil:
+			status = "disabled"
+		case u.DeleteAfter != nil:
+			status = "deleting"
+		}
+		rows = append(rows, []string{
+			strconv.FormatInt(u.ID, 10),
+			u.Email,
+			u.DisplayName,
+			u.Role,
+			status,
+			strconv.FormatInt(u.NoteCount, 10),
+			strconv.FormatInt(u.TrashedCount, 10),
+			strconv.FormatInt(u.AttachmentCount, 10),
+			formatBytes(u.StorageBytes),
+			formatTime(&u.CreatedAt),
+		})
+	}
+	header := []string{"ID", "EMAIL", "NAME", "ROLE", "STATUS", "NOTES", "TRASHED", "ATTACHMENTS", "STORAGE", "CREATED"}
+	return done(render(*format, users, header, rows))
+}
+
+// userSetDisabled disables or enables an account. Enabling also lifts a
+// login lockout kept in the shared rate limit store.
+func userSetDisabled(command string, args []string) int {
+	fs, format := newFlagSet("user "+command, "user "+command+" [flags] <user>")
+	args, ok := parseFlags(fs, format, args, 1)
+	if !ok {
+		return 2
+	}
+	if !connect() {
+		return 1
+	}
+
+	ctx := context.Background()
+	user, err := admin.FindUser(ctx, database.DB, args[0])
+	if err != nil {
+		return fail(err, "Failed to find user")
+	}
+
+	if command == "disable" {
+		err = admin.DisableUser(ctx, database.DB, user.ID)
+	} else {
+		err = admin.EnableUser(ctx, database.DB, user.ID)
+		if err == nil && config.Get().RateLimitStore == "postgres" {
+			key := ratelimit.AccountKey(server.LoginRule, user.Email)
+			err = ratelimit.NewPostgresStore(database.DB).ResetFailures(ctx, key)
+		}
+	}
+	if err != nil {
+		return fail(err, "Failed to "+command+" user")
+	}
+	if command == "disable" {
+		record(audit.ActionAdminDisable, user.ID, nil, nil)
+	} else {
+		record(audit.ActionAdminEnable, user.ID, nil, nil)
+	}
+
+	result := struct {
+		ID     int64  `json:"id"`
+		Email  string `json:"email"`
+		Status string `json:"status"`
+	}{user.ID, user.Email, command + "d"}
+	header, rows := fields("id", strconv.FormatInt(user.ID, 10), "email", user.Email, "status", result.Status)
+	return done(render(*format, result, header, rows))
+}
+
+func userSetRole(args []string) int {
+	fs, format := newFlagSet("user set-role", "user set-role [flags] <user> user|admin")
+	args, ok := parseFlags(fs, format, args, 2)
+	if !ok {
+		return 2
+	}
+	if !connect() {
+		return 1
+	}
+
+	ctx := context.Background()
+	user, err := admin.FindUser(ctx, database.DB, args[0])
+	if err != nil {
+		return fail(err, "Failed to find user")
+	}
+	if err := admin.SetRole(ctx, database.DB, user.ID, args[1]); err != nil {
+		return fail(err, "Failed to change role")
+	}
+	record(audit.ActionAdminSetRole, user.ID, map[string]interface{}{"role": user.Role}, map[string]interface{}{"role": args[1]})
+
+	result := struct {
+		ID    int64  `json:"id"`
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}{user.ID, user.Email, args[1]}
+	header, rows := fields("id", strconv.FormatInt(user.ID, 10), "email", user.Email, "role", result.Role)
+	return done(render(*format, result, header, rows))
+}
+
+func userResetPassword(args []string) int {
+	fs, format := newFlagSet("user reset-password", "user reset-password [flags] <user>")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin")
+	args, ok := parseFlags(fs, format, args, 1)
+	if !ok {
+		return 2
+	}
+
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return fail(err, "Failed to read password")
+	}
+	if !connect() {
+		return 1
+	}
+
+	ctx := context.Background()
+	user, err := admin.FindUser(ctx, database.DB, args[0])
+	if err != nil {
+		return fail(err, "Failed to find user")
+	}
+	if err := admin.ResetPassword(ctx, database.DB, user.ID, password); err != nil {
+		return fail(err, "Failed to reset password")
+	}
+	record(audit.ActionAdminResetPassword, user.ID, nil, nil)
+
+	result := credentials{ID: user.ID, Email: user.Email}
+	if generated {
+		result.Password = password
+	}
+	return result.render(*format)
+}
+
+// credentials is the output of create and reset-password. Password is only
+// set when it was generated.
+type credentials struct {
+	ID       int64  `json:"id"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+}
+
+func (c credentials) render(format string) int {
+	pairs := []string{"id", strconv.FormatInt(c.ID, 10), "email", c.Email}
+	if c.Password != "" {
+		pairs = append(pairs, "password", c.Password)
+	}
+	header, rows := fields(pairs...)
+	return done(render(format, c, header, rows))
+}
+
+// readPassword reads the first line of stdin, or generates a password when
+// fromStdin is false; generated reports the latter.
+func readPassword(fromStdin bool) (password string, generated bool, err error) {
+	if !fromStdin {
+		password, err = admin.GeneratePassword()
+		return password, true, err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false, errors.New("no password on stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), false, nil
+}
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// PurgeTrash permanently deletes notes trashed before cutoff, with their
+// label links and attachments, for one user or for everybody when userID
+// is 0. It returns the number of notes deleted.
+func PurgeTrash(ctx context.Context, db *gorm.DB, cutoff time.Time, userID int64) (int64, error) {
+	var deleted int64
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		trashed := func() *gorm.DB {
+			query := tx.Model(&models.Note{}).Where("trashed_at IS NOT NULL AND trashed_at < ?", cutoff)
+			if userID != 0 {
+				query = query.Where("user_id = ?", userID)
+			}
+			return query
+		}
+
+		if err := tx.Exec("DELETE FROM note_labels WHERE note_id IN (?)", trashed().Select("id")).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("note_id IN (?)", trashed().Select("id")).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		res := trashed().Delete(&models.Note{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	return deleted, err
+}
+
+// Stats summarizes the data stored by the whole instance.
+type Stats struct {
+	Users            int64 `json:"users"`
+	DisabledUsers    int64 `json:"disabled_users"`
+	PendingDeletion  int64 `json:"pending_deletion"` // accounts in the deletion grace period
+	Notes            int64 `json:"notes"`
+	ArchivedNotes    int64 `json:"archived_notes"`
+	TrashedNotes     int64 `json:"trashed_notes"`
+	Labels           int64 `json:"labels"`
+	Attachments      int64 `json:"attachments"`
+	StorageBytes     int64 `json:"storage_bytes"` // total size of the attachments
+	ActiveSessions   int64 `json:"active_sessions"`
+	ActiveImportJobs int64 `json:"active_import_jobs"` // imports pending or running
+}
+
+// GetStats counts users, notes, attachments and sessions.
+func GetStats(ctx context.Context, db *gorm.DB) (Stats, error) {
+	db = db.WithContext(ctx)
+	now := time.Now()
+
+	var s Stats
+	counts := []struct {
+		dest  *int64
+		query *gorm.DB
+	}{
+		{&s.Users, db.Model(&models.User{})},
+		{&s.DisabledUsers, db.Model(&models.User{}).Where("disabled_at IS NOT NULL")},
+		{&s.PendingDeletion, db.Model(&models.User{}).Where("delete_after IS NOT NULL")},
+		{&s.Notes, db.Model(&models.Note{})},
+		{&s.ArchivedNotes, db.Model(&models.Note{}).Where("archived = ?", true)},
+		{&s.TrashedNotes, db.Model(&models.Note{}).Where("trashed_at IS NOT NULL")},
+		{&s.Labels, db.Model(&models.Label{})},
+		{&s.Attachments, db.Model(&models.Attachment{})},
+		{&s.ActiveSessions, db.Model(&models.RefreshToken{}).Where("revoked = ? AND expires_at > ?", false, now)},
+		{&s.ActiveImportJobs, db.Model(&models.ImportJob{}).
+			Where("status IN ?", []string{models.ImportStatusPending, models.ImportStatusRunning})},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return Stats{}, err
+		}
+	}
+
+	if err := db.Model(&models.Attachment{}).Select("COALESCE(SUM(size), 0)").Scan(&s.StorageBytes).Error; err != nil {
+		return Stats{}, err
+	}
+	return s, nil
+}
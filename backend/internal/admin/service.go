@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Service runs the user management of the admin API on a database. The
+// command line calls the functions of this package directly.
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService returns a Service working on db.
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// ListUsers returns the users matching q with their usage, oldest first.
+func (s *Service) ListUsers(ctx context.Context, q UserQuery) ([]UserSummary, error) {
+	return ListUsers(ctx, s.db, q)
+}
+
+// GetUser returns one user with their usage.
+func (s *Service) GetUser(ctx context.Context, userID int64) (*UserSummary, error) {
+	return GetUser(ctx, s.db, userID)
+}
+
+// DisableUser blocks the user and ends all of their sessions.
+func (s *Service) DisableUser(ctx context.Context, userID int64) error {
+	return DisableUser(ctx, s.db, userID)
+}
+
+// EnableUser allows a disabled user to log in again.
+func (s *Service) EnableUser(ctx context.Context, userID int64) error {
+	return EnableUser(ctx, s.db, userID)
+}
+
+// Logout revokes the active refresh tokens of an existing user and returns
+// how many were revoked.
+func (s *Service) Logout(ctx context.Context, userID int64) (int64, error) {
+	if _, err := GetUser(ctx, s.db, userID); err != nil {
+		return 0, err
+	}
+	return RevokeTokens(ctx, s.db, userID)
+}
+
+// SetRole changes the role of a user and returns the role they had.
+func (s *Service) SetRole(ctx context.Context, userID int64, role string) (string, error) {
+	var before string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("role").Where("id = ?", userID).Take(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		before = user.Role
+		return SetRole(ctx, tx, userID, role)
+	})
+	return before, err
+}
@@ -0,0 +1,217 @@
+// Package admin implements operator tasks on accounts and data: creating
+// and disabling users, resetting passwords, revoking sessions, purging the
+// trash and usage statistics. It works directly on the database, like the
+// background jobs.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// MinPasswordLength matches the validation of the registration endpoint.
+const MinPasswordLength = 6
+
+var (
+	// ErrUserNotFound is returned when no user matches.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrEmailTaken is returned by CreateUser for an address already in use.
+	ErrEmailTaken = errors.New("a user with this email already exists")
+)
+
+// UserSummary is a user together with how much data they store.
+type UserSummary struct {
+	models.User     `gorm:"embedded"`
+	NoteCount       int64 `json:"note_count"`
+	TrashedCount    int64 `json:"trashed_count"`
+	AttachmentCount int64 `json:"attachment_count"`
+	StorageBytes    int64 `json:"storage_bytes"` // total size of the attachments
+}
+
+// UserQuery selects users for ListUsers.
+type UserQuery struct {
+	Search string // case-insensitive substring of the email or display name
+	Limit  int    // 0 means no limit
+	Offset int
+}
+
+// summaryColumns adds the usage counters of UserSummary to a query on users.
+const summaryColumns = `users.*,
+	(SELECT COUNT(*) FROM notes WHERE notes.user_id = users.id) AS note_count,
+	(SELECT COUNT(*) FROM notes WHERE notes.user_id = users.id AND notes.trashed_at IS NOT NULL) AS trashed_count,
+	(SELECT COUNT(*) FROM attachments WHERE attachments.user_id = users.id) AS attachment_count,
+	(SELECT COALESCE(SUM(size), 0) FROM attachments WHERE attachments.user_id = users.id) AS storage_bytes`
+
+// ListUsers returns the users matching q with their usage, oldest first.
+func ListUsers(ctx context.Context, db *gorm.DB, q UserQuery) ([]UserSummary, error) {
+	query := db.WithContext(ctx).Model(&models.User{}).Select(summaryColumns).Order("users.id")
+	if search := strings.TrimSpace(q.Search); search != "" {
+		pattern := "%" + database.EscapeLike(strings.ToLower(search)) + "%"
+		query = query.Where(`LOWER(users.email) LIKE ? ESCAPE '\' OR LOWER(users.display_name) LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+	if q.Offset > 0 {
+		query = query.Offset(q.Offset)
+	}
+
+	users := []UserSummary{}
+	if err := query.Scan(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetUser returns one user with their usage.
+func GetUser(ctx context.Context, db *gorm.DB, userID int64) (*UserSummary, error) {
+	var users []UserSummary
+	if err := db.WithContext(ctx).Model(&models.User{}).Select(summaryColumns).
+		Where("users.id = ?", userID).Scan(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return &users[0], nil
+}
+
+// FindUser looks a user up by ID or, if ref isn't a number, by email.
+func FindUser(ctx context.Context, db *gorm.DB, ref string) (*models.User, error) {
+	query := db.WithContext(ctx)
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("email = ?", ref)
+	}
+
+	var user models.User
+	err := query.First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// NewUser describes an account created by an operator.
+type NewUser struct {
+	Email       string
+	Password    string
+	DisplayName string
+	Role        string // empty means models.RoleUser
+}
+
+// CreateUser creates an account.
+func CreateUser(ctx context.Context, db *gorm.DB, u NewUser) (*models.User, error) {
+	if _, err := mail.ParseAddress(u.Email); err != nil || strings.ContainsAny(u.Email, "<> ") {
+		return nil, fmt.Errorf("invalid email address %q", u.Email)
+	}
+	if u.Role == "" {
+		u.Role = models.RoleUser
+	}
+	if !models.IsRole(u.Role) {
+		return nil, fmt.Errorf("invalid role %q: expected %s or %s", u.Role, models.RoleUser, models.RoleAdmin)
+	}
+	if len(u.DisplayName) > 100 {
+		return nil, errors.New("display name must be at most 100 characters")
+	}
+	hash, err := hashPassword(u.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{Email: u.Email, PasswordHash: hash, DisplayName: u.DisplayName, Role: u.Role}
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("email = ?", u.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ResetPassword sets a new password and ends all of the user's sessions.
+func ResetPassword(ctx context.Context, db *gorm.DB, userID int64, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateUser(tx, userID, map[string]interface{}{"password_hash": hash}); err != nil {
+			return err
+		}
+		_, err := revokeTokens(tx, userID)
+		return err
+	})
+}
+
+// DisableUser blocks logins and ends all of the user's sessions. The API
+// rejects the access tokens already issued as well.
+func DisableUser(ctx context.Context, db *gorm.DB, userID int64) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateUser(tx, userID, map[string]interface{}{"disabled_at": time.Now()}); err != nil {
+			return err
+		}
+		_, err := revokeTokens(tx, userID)
+		return err
+	})
+}
+
+// SetRole changes the role of a user. The new role takes effect in access
+// tokens issued from now on; admin endpoints check it at once.
+func SetRole(ctx context.Context, db *gorm.DB, userID int64, role string) error {
+	if !models.IsRole(role) {
+		return fmt.Errorf("invalid role %q: expected %s or %s", role, models.RoleUser, models.RoleAdmin)
+	}
+	return updateUser(db.WithContext(ctx), userID, map[string]interface{}{"role": role})
+}
+
+// EnableUser allows a disabled user to log in again.
+func EnableUser(ctx context.Context, db *gorm.DB, userID int64) error {
+	return updateUser(db.WithContext(ctx), userID, map[string]interface{}{"disabled_at": nil})
+}
+
+// RevokeTokens revokes the active refresh tokens of a user, or of every
+// user when userID is 0, and returns how many were revoked.
+func RevokeTokens(ctx context.Context, db *gorm.DB, userID int64) (int64, error) {
+	return revokeTokens(db.WithContext(ctx), userID)
+}
+
+func revokeTokens(db *gorm.DB, userID int64) (int64, error) {
+	query := db.Model(&models.RefreshToken{}).Where("revoked = ?", false)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	res := query.Update("revoked", true)
+	return res.RowsAffected, res.Error
+}
+
+func updateUser(db *gorm.DB, userID int64, fields map[string]interface{}) error {
+	res := db.Model(&models.User{}).Where("id = ?", userID).Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
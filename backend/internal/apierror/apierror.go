@@ -0,0 +1,137 @@
+// Package apierror defines the errors of the HTTP API. Handlers return an
+// *Error (or any error, which becomes a 500) and Middleware writes it as an
+// RFC 7807 problem document with a stable machine-readable code.
+package apierror
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Code identifies the kind of error for clients. Codes are part of the API:
+// they don't change when the human-readable detail does.
+type Code string
+
+const (
+	// CodeInvalidRequest: malformed body, URL parameter or query parameter.
+	CodeInvalidRequest Code = "invalid_request"
+	// CodeValidationFailed: fields break the rules; see the errors member.
+	CodeValidationFailed Code = "validation_failed"
+	// CodeUnauthenticated: no or an unusable access token.
+	CodeUnauthenticated Code = "unauthenticated"
+	// CodeInvalidCredentials: wrong email or password.
+	CodeInvalidCredentials Code = "invalid_credentials"
+	// CodeInvalidToken: a refresh or confirmation token that is unknown,
+	// expired or revoked.
+	CodeInvalidToken Code = "invalid_token"
+	// CodeAccountDisabled: the account was disabled by an administrator.
+	CodeAccountDisabled Code = "account_disabled"
+	// CodeForbidden: the user may not do this.
+	CodeForbidden Code = "forbidden"
+	// CodeNotFound: the resource doesn't exist or belongs to another user.
+	CodeNotFound Code = "not_found"
+	// CodeEmailTaken: another account uses the email address.
+	CodeEmailTaken Code = "email_taken"
+	// CodeImportInProgress: the user already has an import running.
+	CodeImportInProgress Code = "import_in_progress"
+	// CodePayloadTooLarge: the upload exceeds the size limit.
+	CodePayloadTooLarge Code = "payload_too_large"
+	// CodeRateLimited: too many requests; see Retry-After.
+	CodeRateLimited Code = "rate_limited"
+	// CodeAccountLocked: too many failed logins; see Retry-After.
+	CodeAccountLocked Code = "account_locked"
+	// CodeEmailFailed: the email server refused the message.
+	CodeEmailFailed Code = "email_failed"
+	// CodeUnavailable: the server is shutting down or overloaded.
+	CodeUnavailable Code = "unavailable"
+	// CodeInternal: an unexpected error; details are only logged.
+	CodeInternal Code = "internal_error"
+)
+
+// Error is an API error: the HTTP status, the code and a detail meant for
+// the client. Err is the underlying cause, which is logged but never sent.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError is a problem with one field of the request.
+type FieldError struct {
+	// Field is the JSON path of the field, such as "preferences.note_view",
+	// or the name of the query parameter.
+	Field string `json:"field"`
+	// Code is the rule the value breaks, such as "required" or "max".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Detail, e.Err)
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with the given status, code and detail.
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// Wrap returns an error like New with cause err.
+func Wrap(err error, status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail, Err: err}
+}
+
+// BadRequest returns a 400 invalid_request error.
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+// Unauthenticated returns a 401 unauthenticated error.
+func Unauthenticated(detail string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, detail)
+}
+
+// Forbidden returns a 403 forbidden error.
+func Forbidden(detail string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, detail)
+}
+
+// NotFound returns a 404 not_found error.
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Internal returns a 500 error with cause err. detail says what failed,
+// such as "Failed to create note".
+func Internal(err error, detail string) *Error {
+	return Wrap(err, http.StatusInternalServerError, CodeInternal, detail)
+}
+
+// Invalid returns a 400 validation_failed error for one field.
+func Invalid(field, code, message string) *Error {
+	return Validation(FieldError{Field: field, Code: code, Message: message})
+}
+
+// Validation returns a 400 validation_failed error listing fields.
+func Validation(fields ...FieldError) *Error {
+	detail := "The request has an invalid field"
+	if len(fields) == 1 {
+		detail = fields[0].Field + " " + fields[0].Message
+	} else if len(fields) > 1 {
+		detail = fmt.Sprintf("The request has %d invalid fields", len(fields))
+	}
+	return &Error{
+		Status: http.StatusBadRequest,
+		Code:   CodeValidationFailed,
+		Detail: detail,
+		Fields: fields,
+	}
+}
@@ -0,0 +1,116 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Bind converts an error of gin's ShouldBind* methods: invalid fields
+// become a validation_failed error with one entry per field, and bodies
+// that aren't JSON an invalid_request error. Field names are the JSON
+// names when the validator was set up with JSONFieldName.
+func Bind(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fields[i] = FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: ruleMessage(fe),
+			}
+		}
+		return Validation(fields...)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return Invalid(typeErr.Field, "type", "must be "+jsonType(typeErr.Type))
+	case errors.Is(err, io.EOF):
+		return Wrap(err, http.StatusBadRequest, CodeInvalidRequest, "The request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &typeErr):
+		return Wrap(err, http.StatusBadRequest, CodeInvalidRequest, "The request body is not valid JSON")
+	default:
+		return Wrap(err, http.StatusBadRequest, CodeInvalidRequest, "The request body is invalid")
+	}
+}
+
+// JSONFieldName is a validator tag name function reporting fields by
+// their JSON name, for validator.Validate.RegisterTagNameFunc.
+func JSONFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fieldPath returns the path of the field without the name of the request
+// type, such as "preferences.note_view" or "ids[2]".
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+// ruleMessage describes the rule of a validation tag.
+func ruleMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "note_color":
+		return "must be a note color"
+	default:
+		return "is invalid"
+	}
+}
+
+// jsonType names the JSON type of a Go type.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	default:
+		return "an object"
+	}
+}
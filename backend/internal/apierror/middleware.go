@@ -0,0 +1,103 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+)
+
+// ContentType is the media type of problem documents.
+const ContentType = "application/problem+json"
+
+// Problem is the RFC 7807 problem document written for an Error. Type is
+// always about:blank, so Title is the HTTP status text; clients tell
+// errors apart by Code.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// Handler adapts a handler returning an error to Gin. A non-nil error
+// aborts the request and is written by Middleware.
+func Handler(fn func(c *gin.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := fn(c); err != nil {
+			Abort(c, err)
+		}
+	}
+}
+
+// Abort stops the request with err, for middleware. The status is set at
+// once, so middleware further out sees it, and Middleware writes the body.
+func Abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Status(From(err).Status)
+	c.Abort()
+}
+
+// From returns err as an *Error. Other errors become a 500 internal_error
+// with err as the cause.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal(err, "Internal server error")
+}
+
+// Middleware writes the last error of the request as a problem document,
+// unless a response was written already. The errors, causes included, are
+// logged by the request logger.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		Write(c, From(c.Errors.Last().Err))
+	}
+}
+
+// Write writes err as a problem document.
+func Write(c *gin.Context, err *Error) {
+	c.Header("Cache-Control", "no-store")
+	c.Render(err.Status, problemRender{Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(err.Status),
+		Status:    err.Status,
+		Detail:    err.Detail,
+		Instance:  c.Request.URL.Path,
+		Code:      err.Code,
+		Errors:    err.Fields,
+		RequestID: logger.RequestID(c.Request.Context()),
+	}})
+}
+
+// problemRender renders a Problem as JSON with the problem+json media type.
+type problemRender struct {
+	problem Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+}
+
+// NoRoute answers requests that matched no route.
+func NoRoute(c *gin.Context) {
+	Write(c, NotFound("No route for "+c.Request.Method+" "+c.Request.URL.Path))
+}
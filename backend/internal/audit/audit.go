@@ -0,0 +1,133 @@
+// Package audit records security-relevant and data-changing events in the
+// audit_events table. The actor, IP and user agent of the request are
+// carried in the context, so code below the HTTP layer can record events
+// without knowing about requests.
+package audit
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+)
+
+// Actions recorded in the audit log. Actions are grouped by prefix, which
+// AuditFilter.Action can select, e.g. "auth.".
+const (
+	ActionLogin       = "auth.login"
+	ActionLoginFailed = "auth.login_failed"
+	ActionRefresh     = "auth.refresh"
+	ActionLogout      = "auth.logout"
+
+	ActionRegister       = "account.register"
+	ActionPasswordChange = "account.password_change"
+	ActionDeleteRequest  = "account.delete_request"
+
+	ActionNoteCreate = "note.create"
+	ActionNoteUpdate = "note.update"
+	ActionNoteDelete = "note.delete"
+	ActionNoteMove   = "note.move"
+	ActionNoteBatch  = "note.batch"
+
+	ActionAdminListUsers     = "admin.users.list"
+	ActionAdminViewUser      = "admin.users.view"
+	ActionAdminCreateUser    = "admin.users.create"
+	ActionAdminDisable       = "admin.users.disable"
+	ActionAdminEnable        = "admin.users.enable"
+	ActionAdminLogout        = "admin.users.logout"
+	ActionAdminSetRole       = "admin.users.set_role"
+	ActionAdminResetPassword = "admin.users.reset_password"
+	ActionAdminPurgeTrash    = "admin.notes.purge_trash"
+	ActionAdminQueryEvents   = "admin.audit.query"
+)
+
+// Source describes who caused the events recorded with a context.
+type Source struct {
+	ActorID   int64 // 0 when nobody is logged in
+	IP        string
+	UserAgent string
+}
+
+type sourceKey struct{}
+
+// WithSource returns a context whose events are attributed to src.
+func WithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// SourceFrom returns the source stored by WithSource.
+func SourceFrom(ctx context.Context) Source {
+	src, _ := ctx.Value(sourceKey{}).(Source)
+	return src
+}
+
+// Log records audit events.
+type Log struct {
+	events repository.AuditRepository
+}
+
+// New returns a Log writing to events.
+func New(events repository.AuditRepository) *Log {
+	return &Log{events: events}
+}
+
+// Record stores event, filling in the source from ctx where the event
+// doesn't set it. A failure is logged but not returned: the action being
+// audited has already happened.
+func (l *Log) Record(ctx context.Context, event models.AuditEvent) {
+	src := SourceFrom(ctx)
+	if event.ActorID == nil && src.ActorID != 0 {
+		event.ActorID = ID(src.ActorID)
+	}
+	if event.IP == "" {
+		event.IP = src.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = truncate(src.UserAgent, 255)
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	// Events are recorded after the change they describe, so they must not
+	// be lost when the request is cancelled right after it
+	if err := l.events.Create(context.WithoutCancel(ctx), &event); err != nil {
+		logger.WithContext(ctx).WithError(err).WithField("action", event.Action).Error("Failed to record audit event")
+	}
+}
+
+// NoteSummary describes a note for the audit log. The content itself is
+// left out, only its length is kept.
+func NoteSummary(note *models.Note) map[string]interface{} {
+	labels := make([]string, 0, len(note.Labels))
+	for _, l := range note.Labels {
+		labels = append(labels, l.Name)
+	}
+	sort.Strings(labels)
+	return map[string]interface{}{
+		"title":          note.Title,
+		"content_length": len(note.Content),
+		"color":          note.Color,
+		"pinned":         note.Pinned,
+		"archived":       note.Archived,
+		"trashed":        note.TrashedAt != nil,
+		"labels":         labels,
+	}
+}
+
+// ID returns a pointer to id, for the optional ID fields of an event.
+func ID(id int64) *int64 {
+	return &id
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return strings.ToValidUTF8(s[:n], "")
+	}
+	return s
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"flag"
+	"fmt"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,6 +28,12 @@ const (
 	LogLevelError LogLevel = "error"
 )
 
+// Supported values of DBDriver.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // Config holds all runtime configuration for the app.
 type Config struct {
 	// Environment indicates whether the app is running in development or production.
@@ -31,6 +42,22 @@ type Config struct {
 	// Server
 	Port string
 
+	// HTTP server timeouts. Read and write cover the whole request and
+	// response, including import uploads and exports; 0 means no limit.
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+	HTTPIdleTimeout  time.Duration
+
+	// ShutdownTimeout bounds the graceful shutdown: draining requests and
+	// waiting for background jobs
+	ShutdownTimeout time.Duration
+	// ShutdownDelay is how long the server keeps serving after a signal
+	// while reporting not ready, so load balancers can take it out first
+	ShutdownDelay time.Duration
+
+	// PublicURL is the base URL of the web app, used to build links in emails
+	PublicURL string
+
 	// Auth / Security
 	JWTSecret string
 
@@ -41,101 +68,369 @@ type Config struct {
 	// Refresh token cookie
 	RefreshTokenCookieName string
 
+	// Account deletion grace period before data is purged
+	AccountDeletionGracePeriod time.Duration
+
+	// Database. DBDriver selects Postgres, configured by the other DB*
+	// fields, or the SQLite file at DBPath for single-user deployments.
+	DBDriver   string
+	DBHost     string
+	DBPort     int
+	DBUser     string
+	DBPassword string
+	DBName     string
+	DBSSLMode  string
+	DBPath     string
+
 	// Logging
 	LogLevel LogLevel
 
+	// Metrics. /metrics is served on MetricsAddr when set, otherwise on the
+	// API port when MetricsToken is set, otherwise not at all. A set
+	// MetricsToken must be sent as a bearer token in both cases.
+	MetricsAddr  string
+	MetricsToken string
+
+	// TracesExporter sends OpenTelemetry spans to "otlp", "stdout" or
+	// "none". The exporter itself is configured by the standard OTEL_*
+	// variables.
+	TracesExporter string
+
+	// MigrateOnStart applies pending schema migrations at startup. When false
+	// the server refuses to start while migrations are pending.
+	MigrateOnStart bool
+
+	// Rate limiting
+	RateLimitStore         string        // "memory" or "postgres"
+	LoginRateLimitPerIP    int           // login attempts per minute per client IP
+	LoginRateLimitPerEmail int           // login attempts per minute per account
+	RegisterRateLimitPerIP int           // registrations per minute per client IP
+	LockoutThreshold       int           // failed logins before the account is locked
+	LockoutBaseDuration    time.Duration // first lockout duration, doubled on each further failure
+	LockoutMaxDuration     time.Duration // upper bound for a single lockout
+
+	// Email (SMTP). When SMTPHost is empty, emails are written to the log instead.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Maximum size of uploaded import archives
+	ImportMaxBytes int64
+
+	// Email change confirmation link lifetime
+	EmailChangeTTL time.Duration
+
+	// CORS. Allowed origins are exact, like https://notes.example.com, or
+	// match subdomains, like https://*.example.com; "*" allows any origin
+	// but without cookies.
+	CORSAllowedOrigins []string
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+	CORSMaxAge         time.Duration // how long browsers may cache preflight responses
+
 	// Warnings collected during config load (before logger is available)
 	Warnings []string
+
+	// File is the config file the configuration was read from, if any.
+	File string
+
+	// sources records where each setting came from, by key.
+	sources map[string]Source
+}
+
+// Source is where the value of a setting came from. Later sources
+// override earlier ones: a file overrides the defaults, the environment
+// the file and flags everything.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Options are the sources of the configuration besides the environment.
+type Options struct {
+	// File is a YAML or TOML config file; none when empty.
+	File string
+	// Flags are flag values by setting key, as filled in by Flags.
+	Flags map[string]string
+}
+
+// Error lists every problem of an invalid configuration.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
 }
 
 var cfg *Config
 
-// Load initializes the global configuration from environment variables.
-// It should be called once on application startup.
-func Load() {
+// Flags defines a flag for every setting on fs, named by its key such as
+// -db.host. The returned map is filled in as fs is parsed, for
+// Options.Flags; values are checked by Load.
+func Flags(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	for _, s := range settings {
+		key := s.key
+		usage := s.usage
+		if s.def != "" {
+			usage += " (default " + s.def + ")"
+		}
+		if s.env != "" {
+			usage += " (env " + s.env + ")"
+		}
+		fs.Func(key, usage, func(v string) error {
+			values[key] = v
+			return nil
+		})
+	}
+	return values
+}
+
+// Load initializes the global configuration from the defaults, the config
+// file, the environment and the flags, in increasing precedence. It
+// should be called once on application startup. It returns an *Error
+// listing every problem when the configuration is invalid.
+func Load(opts Options) error {
 	if cfg != nil {
 		// Already loaded
-		return
+		return nil
 	}
 
-	env := getEnv("APP_ENV", "development")
-	var environment Environment
-	switch Environment(env) {
-	case EnvProduction:
-		environment = EnvProduction
-	default:
-		environment = EnvDevelopment
+	c := &Config{File: opts.File, sources: make(map[string]Source, len(settings))}
+	var problems []string
+
+	for _, s := range settings {
+		if err := s.set(c, s.def, false); err != nil {
+			panic(fmt.Sprintf("config: invalid default of %s: %v", s.key, err))
+		}
+		c.sources[s.key] = SourceDefault
 	}
 
-	port := getEnv("PORT", "8080")
+	if opts.File != "" {
+		values, fileProblems, err := readFile(opts.File)
+		if err != nil {
+			return &Error{Problems: []string{err.Error()}}
+		}
+		for _, p := range fileProblems {
+			problems = append(problems, opts.File+": "+p)
+		}
+		for key, raw := range values {
+			s, ok := settingByKey(key)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown setting %q", opts.File, key))
+				continue
+			}
+			if err := s.set(c, raw, false); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s: %v", opts.File, key, err))
+				continue
+			}
+			c.sources[key] = SourceFile
+		}
+	}
 
-	var warnings []string
+	for _, s := range settings {
+		// Empty variables count as unset, as they always have
+		raw := os.Getenv(s.env)
+		if s.env == "" || raw == "" {
+			continue
+		}
+		if err := s.set(c, raw, true); err != nil {
+			problems = append(problems, fmt.Sprintf("%s=%s: %v", s.env, raw, err))
+			continue
+		}
+		c.sources[s.key] = SourceEnv
+	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		warnings = append(warnings, "JWT_SECRET is not set - using empty secret is insecure")
+	for _, s := range settings {
+		raw, ok := opts.Flags[s.key]
+		if !ok {
+			continue
+		}
+		if err := s.set(c, raw, false); err != nil {
+			problems = append(problems, fmt.Sprintf("-%s=%s: %v", s.key, raw, err))
+			continue
+		}
+		c.sources[s.key] = SourceFlag
 	}
 
-	// TTLs: read minutes/days from env with sensible defaults
-	atMin := getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15)
-	rtDays := getEnvInt("REFRESH_TOKEN_TTL_DAYS", 7)
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
 
 	// Log level: default to debug in development, info in production
-	logLevelStr := getEnv("LOG_LEVEL", "")
-	var logLevel LogLevel
-	switch LogLevel(logLevelStr) {
-	case LogLevelDebug:
-		logLevel = LogLevelDebug
-	case LogLevelInfo:
-		logLevel = LogLevelInfo
-	case LogLevelWarn:
-		logLevel = LogLevelWarn
-	case LogLevelError:
-		logLevel = LogLevelError
-	default:
-		if environment == EnvProduction {
-			logLevel = LogLevelInfo
-		} else {
-			logLevel = LogLevelDebug
-		}
-	}
-
-	cfg = &Config{
-		Environment:            environment,
-		Port:                   port,
-		JWTSecret:              jwtSecret,
-		AccessTokenTTL:         time.Duration(atMin) * time.Minute,
-		RefreshTokenTTL:        time.Duration(rtDays) * 24 * time.Hour,
-		RefreshTokenCookieName: getEnv("REFRESH_TOKEN_COOKIE", "refresh_token"),
-		LogLevel:               logLevel,
-		Warnings:               warnings,
+	if c.LogLevel == "" {
+		c.LogLevel = LogLevelDebug
+		if c.Environment == EnvProduction {
+			c.LogLevel = LogLevelInfo
+		}
+	}
+
+	if c.JWTSecret == "" {
+		c.Warnings = append(c.Warnings, "JWT_SECRET is not set - using empty secret is insecure")
+	}
+	if c.Environment == EnvProduction {
+		for _, o := range c.CORSAllowedOrigins {
+			if o == "*" {
+				c.Warnings = append(c.Warnings, "CORS_ALLOWED_ORIGINS allows any origin - browsers on other origins can't send the refresh token cookie")
+			}
+		}
+	}
+	if c.SMTPHost == "" && c.Environment == EnvProduction {
+		c.Warnings = append(c.Warnings, "SMTP_HOST is not set - emails will only be logged")
 	}
+
+	cfg = c
+	return nil
 }
 
-// Get returns the loaded configuration. It panics if Load has not been called.
-func Get() *Config {
-	if cfg == nil {
-		panic("config not loaded: call config.Load() at startup")
+// validate returns every problem of the values, naming each setting by
+// its key and environment variable.
+func (c *Config) validate() []string {
+	var problems []string
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if ok {
+			return
+		}
+		name := key
+		if s, found := settingByKey(key); found && s.env != "" {
+			name += " (" + s.env + ")"
+		}
+		problems = append(problems, name+": "+fmt.Sprintf(format, args...))
 	}
-	return cfg
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		check(false, key, "must be one of %s, not %q", strings.Join(allowed, ", "), value)
+	}
+
+	oneOf("environment", string(c.Environment), string(EnvDevelopment), string(EnvProduction))
+
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port", "must be a port number, not %q", c.Port)
+	u, err := url.Parse(c.PublicURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "server.public_url", "must be an http or https URL, not %q", c.PublicURL)
+	for key, d := range map[string]time.Duration{
+		"server.read_timeout":                c.HTTPReadTimeout,
+		"server.write_timeout":               c.HTTPWriteTimeout,
+		"server.idle_timeout":                c.HTTPIdleTimeout,
+		"server.shutdown_timeout":            c.ShutdownTimeout,
+		"server.shutdown_delay":              c.ShutdownDelay,
+		"auth.account_deletion_grace_period": c.AccountDeletionGracePeriod,
+	} {
+		check(d >= 0, key, "must not be negative")
+	}
+
+	check(c.JWTSecret != "" || c.Environment != EnvProduction, "auth.jwt_secret", "is required in production")
+	for key, d := range map[string]time.Duration{
+		"auth.access_token_ttl":   c.AccessTokenTTL,
+		"auth.refresh_token_ttl":  c.RefreshTokenTTL,
+		"auth.email_change_ttl":   c.EmailChangeTTL,
+		"rate_limit.lockout_base": c.LockoutBaseDuration,
+		"rate_limit.lockout_max":  c.LockoutMaxDuration,
+	} {
+		check(d > 0, key, "must be positive")
+	}
+	check(c.RefreshTokenCookieName != "", "auth.refresh_token_cookie", "must not be empty")
+
+	oneOf("db.driver", c.DBDriver, DriverPostgres, DriverSQLite)
+	switch c.DBDriver {
+	case DriverPostgres:
+		check(c.DBHost != "", "db.host", "must not be empty")
+		check(c.DBPort > 0 && c.DBPort < 65536, "db.port", "must be a port number")
+		check(c.DBName != "", "db.name", "must not be empty")
+	case DriverSQLite:
+		check(c.DBPath != "", "db.path", "must not be empty")
+	}
+
+	if c.LogLevel != "" {
+		oneOf("log.level", string(c.LogLevel), string(LogLevelDebug), string(LogLevelInfo), string(LogLevelWarn), string(LogLevelError))
+	}
+	oneOf("tracing.exporter", c.TracesExporter, "otlp", "stdout", "none")
+
+	oneOf("rate_limit.store", c.RateLimitStore, "memory", "postgres")
+	check(c.RateLimitStore != "postgres" || c.DBDriver == DriverPostgres, "rate_limit.store", "postgres needs the postgres database driver")
+	for key, n := range map[string]int{
+		"rate_limit.login_per_ip":      c.LoginRateLimitPerIP,
+		"rate_limit.login_per_email":   c.LoginRateLimitPerEmail,
+		"rate_limit.register_per_ip":   c.RegisterRateLimitPerIP,
+		"rate_limit.lockout_threshold": c.LockoutThreshold,
+	} {
+		check(n >= 0, key, "must not be negative")
+	}
+	check(c.LockoutBaseDuration <= c.LockoutMaxDuration, "rate_limit.lockout_base", "must not be longer than rate_limit.lockout_max")
+
+	check(c.SMTPPort > 0 && c.SMTPPort < 65536, "smtp.port", "must be a port number")
+	check(c.SMTPHost == "" || c.SMTPFrom != "", "smtp.from", "is required when smtp.host is set")
+
+	check(c.ImportMaxBytes > 0, "import.max_size", "must be positive")
+
+	for _, o := range c.CORSAllowedOrigins {
+		check(o == "*" || validOrigin(o), "cors.allowed_origins", "%q is not an origin such as https://notes.example.com or https://*.example.com", o)
+	}
+	check(len(c.CORSAllowedMethods) > 0, "cors.allowed_methods", "must not be empty")
+	check(c.CORSMaxAge >= 0, "cors.max_age", "must not be negative")
+
+	// Maps make the order random; keep the output stable
+	sort.Strings(problems)
+	return problems
 }
 
-func getEnv(key, fallback string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+// validOrigin reports whether o is a scheme and host with an optional port
+// and nothing else, where the first label of the host may be a wildcard.
+func validOrigin(o string) bool {
+	scheme, host, ok := strings.Cut(o, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return false
 	}
-	return fallback
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "*."), "/")
+	u, err := url.Parse(scheme + "://" + host)
+	return err == nil && u.Host == host && u.Hostname() != "" && !strings.Contains(host, "*")
+}
+
+// Entry is a setting of the configuration, for display.
+type Entry struct {
+	Key    string      `json:"key"`
+	Env    string      `json:"env,omitempty"`
+	Value  interface{} `json:"value"` // a string, int, bool or []string
+	Source Source      `json:"source"`
 }
 
-func getEnvInt(key string, fallback int) int {
-	valStr := os.Getenv(key)
-	if valStr == "" {
-		return fallback
+// redacted replaces the value of secrets that are set.
+const redacted = "REDACTED"
+
+// Entries returns every setting with its value, with secrets redacted.
+func (c *Config) Entries() []Entry {
+	entries := make([]Entry, len(settings))
+	for i, s := range settings {
+		value := s.value(c)
+		if s.secret && value != "" {
+			value = redacted
+		}
+		entries[i] = Entry{Key: s.key, Env: s.env, Value: value, Source: c.sources[s.key]}
 	}
-	v, err := strconv.Atoi(valStr)
-	if err != nil {
-		// Invalid int values silently use fallback - this is acceptable for config
-		return fallback
+	return entries
+}
+
+// Source returns where the setting with the given key came from.
+func (c *Config) Source(key string) Source {
+	return c.sources[key]
+}
+
+// Get returns the loaded configuration. It panics if Load has not been called.
+func Get() *Config {
+	if cfg == nil {
+		panic("config not loaded: call config.Load() at startup")
 	}
-	return v
+	return cfg
 }
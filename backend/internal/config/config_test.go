@@ -0,0 +1,218 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load runs Load with a clean environment, in which only env is set, and
+// returns the configuration or its error.
+func load(t *testing.T, env map[string]string, opts Options) (*Config, error) {
+	t.Helper()
+	for _, s := range settings {
+		if s.env != "" {
+			// Empty variables count as unset
+			t.Setenv(s.env, "")
+		}
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	cfg = nil
+	t.Cleanup(func() { cfg = nil })
+	if err := Load(opts); err != nil {
+		return nil, err
+	}
+	return Get(), nil
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := load(t, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != "8080" || c.AccessTokenTTL != 15*time.Minute || c.RefreshTokenTTL != 7*24*time.Hour ||
+		c.ImportMaxBytes != 512<<20 || c.LogLevel != LogLevelDebug || c.Source("server.port") != SourceDefault {
+		t.Errorf("defaults = %+v", c)
+	}
+}
+
+func TestLoadCombinesProblems(t *testing.T) {
+	_, err := load(t, map[string]string{
+		"APP_ENV":                  "production",
+		"PORT":                     "http",
+		"ACCESS_TOKEN_TTL_MINUTES": "15m",
+		"DB_DRIVER":                "mysql",
+	}, Options{Flags: map[string]string{"smtp.port": "0"}})
+
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	for _, want := range []string{
+		"ACCESS_TOKEN_TTL_MINUTES=15m: must be a whole number",
+		"auth.jwt_secret (JWT_SECRET): is required in production",
+		`db.driver (DB_DRIVER): must be one of postgres, sqlite, not "mysql"`,
+		`server.port (PORT): must be a port number, not "http"`,
+		"smtp.port (SMTP_PORT): must be a port number",
+	} {
+		found := false
+		for _, p := range cfgErr.Problems {
+			found = found || p == want
+		}
+		if !found {
+			t.Errorf("problems %q miss %q", cfgErr.Problems, want)
+		}
+	}
+	if len(cfgErr.Problems) != 5 {
+		t.Errorf("%d problems, want 5: %q", len(cfgErr.Problems), cfgErr.Problems)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+server:
+  port: 9000
+  public_url: https://notes.example.com
+db:
+  host: file-host
+auth:
+  access_token_ttl: 30m
+cors:
+  allowed_origins:
+    - https://notes.example.com
+    - https://*.example.com
+`,
+		"config.toml": `
+[server]
+port = 9000
+public_url = "https://notes.example.com"
+
+[db]
+host = "file-host"
+
+[auth]
+access_token_ttl = "30m"
+
+[cors]
+allowed_origins = ["https://notes.example.com", "https://*.example.com"]
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			c, err := load(t, map[string]string{
+				"PORT":    "9100",
+				"DB_HOST": "env-host",
+				"DB_NAME": "env-name",
+			}, Options{
+				File:  writeFile(t, name, content),
+				Flags: map[string]string{"server.port": "9200"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tt := range []struct {
+				key    string
+				got    interface{}
+				want   interface{}
+				source Source
+			}{
+				{"server.port", c.Port, "9200", SourceFlag},
+				{"db.host", c.DBHost, "env-host", SourceEnv},
+				{"db.name", c.DBName, "env-name", SourceEnv},
+				{"server.public_url", c.PublicURL, "https://notes.example.com", SourceFile},
+				{"auth.access_token_ttl", c.AccessTokenTTL, 30 * time.Minute, SourceFile},
+				{"cors.allowed_origins", c.CORSAllowedOrigins, []string{"https://notes.example.com", "https://*.example.com"}, SourceFile},
+				{"db.user", c.DBUser, "postgres", SourceDefault},
+			} {
+				if !reflect.DeepEqual(tt.got, tt.want) || c.Source(tt.key) != tt.source {
+					t.Errorf("%s = %v from %s, want %v from %s", tt.key, tt.got, c.Source(tt.key), tt.want, tt.source)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileProblems(t *testing.T) {
+	_, err := load(t, nil, Options{File: writeFile(t, "config.yaml", "server:\n  prot: 9000\ndb:\n  port: many\n")})
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) || len(cfgErr.Problems) != 2 ||
+		!strings.Contains(err.Error(), `unknown setting "server.prot"`) ||
+		!strings.Contains(err.Error(), "db.port: must be a whole number") {
+		t.Errorf("err = %v, want an unknown setting and an invalid port", err)
+	}
+}
+
+func TestLoadEnvUnits(t *testing.T) {
+	c, err := load(t, map[string]string{
+		"ACCESS_TOKEN_TTL_MINUTES":   "30",
+		"REFRESH_TOKEN_TTL_DAYS":     "14",
+		"EMAIL_CHANGE_TTL_HOURS":     "2",
+		"HTTP_READ_TIMEOUT_SECONDS":  "0",
+		"LOGIN_LOCKOUT_BASE_SECONDS": "90",
+		"IMPORT_MAX_MB":              "64",
+	}, Options{Flags: map[string]string{"cors.max_age": "1h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tt := range map[string]struct{ got, want int64 }{
+		"access token TTL":  {int64(c.AccessTokenTTL), int64(30 * time.Minute)},
+		"refresh token TTL": {int64(c.RefreshTokenTTL), int64(14 * 24 * time.Hour)},
+		"email change TTL":  {int64(c.EmailChangeTTL), int64(2 * time.Hour)},
+		"read timeout":      {int64(c.HTTPReadTimeout), 0},
+		"lockout base":      {int64(c.LockoutBaseDuration), int64(90 * time.Second)},
+		"import max size":   {c.ImportMaxBytes, 64 << 20},
+		"CORS max age":      {int64(c.CORSMaxAge), int64(time.Hour)},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestEntriesRedactSecrets(t *testing.T) {
+	c, err := load(t, map[string]string{
+		"JWT_SECRET":  "jwt-secret-value",
+		"DB_PASSWORD": "db-password-value",
+	}, Options{Flags: map[string]string{"metrics.token": "metrics-token-value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]interface{})
+	for _, e := range c.Entries() {
+		values[e.Key] = e.Value
+		if s, ok := e.Value.(string); ok && strings.HasSuffix(s, "-value") {
+			t.Errorf("%s shows the secret %q", e.Key, s)
+		}
+	}
+	for key, want := range map[string]interface{}{
+		"auth.jwt_secret": redacted,
+		"db.password":     redacted,
+		"metrics.token":   redacted,
+		"smtp.password":   "", // unset secrets show as unset
+		"db.user":         "postgres",
+		"server.port":     "8080",
+	} {
+		if values[key] != want {
+			t.Errorf("%s = %v, want %v", key, values[key], want)
+		}
+	}
+}
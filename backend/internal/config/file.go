@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// readFile reads a YAML (.yaml, .yml) or TOML (.toml) config file into its
+// settings, keyed like "db.host". Sections are nested tables or mappings.
+func readFile(path string) (map[string]string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, nil, fmt.Errorf("unsupported config file type %q: use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	var problems []string
+	flatten("", doc, values, &problems)
+	return values, problems, nil
+}
+
+// flatten adds the scalar values of section to values under prefix.
+func flatten(prefix string, section map[string]interface{}, values map[string]string, problems *[]string) {
+	keys := make([]string, 0, len(section))
+	for k := range section {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := section[k].(type) {
+		case map[string]interface{}:
+			flatten(key, v, values, problems)
+		case map[interface{}]interface{}:
+			nested := make(map[string]interface{}, len(v))
+			for nk, nv := range v {
+				nested[fmt.Sprint(nk)] = nv
+			}
+			flatten(key, nested, values, problems)
+		case []interface{}:
+			// Lists are written like in the environment: comma-separated
+			items := make([]string, len(v))
+			for i, item := range v {
+				switch item.(type) {
+				case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+					*problems = append(*problems, fmt.Sprintf("%s: lists may only hold single values", key))
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
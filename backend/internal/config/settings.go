@@ -0,0 +1,276 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a configuration value that can come from the config file,
+// the environment and a flag. Its key names it in files, where dots
+// separate sections, and is the name of its flag.
+type setting struct {
+	key   string
+	env   string
+	def   string // default, in the syntax of files
+	usage string
+	// unit is the unit of the environment variable of a duration or size,
+	// such as time.Minute for ACCESS_TOKEN_TTL_MINUTES. Files and flags
+	// take durations such as "15m" and sizes such as "512MiB".
+	unit   int64
+	secret bool
+	field  func(c *Config) interface{} // pointer to the field
+}
+
+const mib = 1 << 20
+
+// settings lists every setting, in the order of config print.
+var settings = []setting{
+	{key: "environment", env: "APP_ENV", def: string(EnvDevelopment), usage: "development or production",
+		field: func(c *Config) interface{} { return &c.Environment }},
+
+	{key: "server.port", env: "PORT", def: "8080", usage: "port of the API",
+		field: func(c *Config) interface{} { return &c.Port }},
+	{key: "server.public_url", env: "PUBLIC_URL", def: "http://localhost:8080", usage: "base URL of the web app, for links in emails",
+		field: func(c *Config) interface{} { return &c.PublicURL }},
+	{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT_SECONDS", def: "5m", unit: int64(time.Second), usage: "limit for reading a request; 0 for none",
+		field: func(c *Config) interface{} { return &c.HTTPReadTimeout }},
+	{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT_SECONDS", def: "5m", unit: int64(time.Second), usage: "limit for writing a response; 0 for none",
+		field: func(c *Config) interface{} { return &c.HTTPWriteTimeout }},
+	{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT_SECONDS", def: "2m", unit: int64(time.Second), usage: "how long idle keep-alive connections stay open",
+		field: func(c *Config) interface{} { return &c.HTTPIdleTimeout }},
+	{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT_SECONDS", def: "30s", unit: int64(time.Second), usage: "limit for a graceful shutdown",
+		field: func(c *Config) interface{} { return &c.ShutdownTimeout }},
+	{key: "server.shutdown_delay", env: "SHUTDOWN_DELAY_SECONDS", def: "0s", unit: int64(time.Second), usage: "how long to keep serving as not ready after a signal",
+		field: func(c *Config) interface{} { return &c.ShutdownDelay }},
+
+	{key: "auth.jwt_secret", env: "JWT_SECRET", secret: true, usage: "key signing access tokens; required in production",
+		field: func(c *Config) interface{} { return &c.JWTSecret }},
+	{key: "auth.access_token_ttl", env: "ACCESS_TOKEN_TTL_MINUTES", def: "15m", unit: int64(time.Minute), usage: "lifetime of access tokens",
+		field: func(c *Config) interface{} { return &c.AccessTokenTTL }},
+	{key: "auth.refresh_token_ttl", env: "REFRESH_TOKEN_TTL_DAYS", def: "7d", unit: int64(24 * time.Hour), usage: "lifetime of refresh tokens",
+		field: func(c *Config) interface{} { return &c.RefreshTokenTTL }},
+	{key: "auth.refresh_token_cookie", env: "REFRESH_TOKEN_COOKIE", def: "refresh_token", usage: "name of the refresh token cookie",
+		field: func(c *Config) interface{} { return &c.RefreshTokenCookieName }},
+	{key: "auth.email_change_ttl", env: "EMAIL_CHANGE_TTL_HOURS", def: "24h", unit: int64(time.Hour), usage: "lifetime of email change confirmation links",
+		field: func(c *Config) interface{} { return &c.EmailChangeTTL }},
+	{key: "auth.account_deletion_grace_period", env: "ACCOUNT_DELETION_GRACE_DAYS", def: "30d", unit: int64(24 * time.Hour), usage: "delay before a deleted account is purged",
+		field: func(c *Config) interface{} { return &c.AccountDeletionGracePeriod }},
+
+	{key: "db.driver", env: "DB_DRIVER", def: "postgres", usage: "postgres or sqlite",
+		field: func(c *Config) interface{} { return &c.DBDriver }},
+	{key: "db.host", env: "DB_HOST", def: "localhost", usage: "Postgres host",
+		field: func(c *Config) interface{} { return &c.DBHost }},
+	{key: "db.port", env: "DB_PORT", def: "5432", usage: "Postgres port",
+		field: func(c *Config) interface{} { return &c.DBPort }},
+	{key: "db.user", env: "DB_USER", def: "postgres", usage: "Postgres user",
+		field: func(c *Config) interface{} { return &c.DBUser }},
+	{key: "db.password", env: "DB_PASSWORD", def: "postgres", secret: true, usage: "Postgres password",
+		field: func(c *Config) interface{} { return &c.DBPassword }},
+	{key: "db.name", env: "DB_NAME", def: "google_keep_clone", usage: "Postgres database",
+		field: func(c *Config) interface{} { return &c.DBName }},
+	{key: "db.sslmode", env: "DB_SSLMODE", def: "disable", usage: "Postgres sslmode",
+		field: func(c *Config) interface{} { return &c.DBSSLMode }},
+	{key: "db.path", env: "DB_PATH", def: "data/notes.db", usage: "SQLite file",
+		field: func(c *Config) interface{} { return &c.DBPath }},
+	{key: "db.migrate_on_start", env: "MIGRATE_ON_START", def: "true", usage: "apply pending migrations at startup",
+		field: func(c *Config) interface{} { return &c.MigrateOnStart }},
+
+	{key: "log.level", env: "LOG_LEVEL", usage: "debug, info, warn or error (default debug in development, info in production)",
+		field: func(c *Config) interface{} { return &c.LogLevel }},
+	{key: "metrics.addr", env: "METRICS_ADDR", usage: "separate listener for /metrics, e.g. :9090",
+		field: func(c *Config) interface{} { return &c.MetricsAddr }},
+	{key: "metrics.token", env: "METRICS_TOKEN", secret: true, usage: "bearer token required by /metrics",
+		field: func(c *Config) interface{} { return &c.MetricsToken }},
+	{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", def: "none", usage: "otlp, stdout or none",
+		field: func(c *Config) interface{} { return &c.TracesExporter }},
+
+	{key: "rate_limit.store", env: "RATE_LIMIT_STORE", def: "memory", usage: "memory, or postgres to share limits across instances",
+		field: func(c *Config) interface{} { return &c.RateLimitStore }},
+	{key: "rate_limit.login_per_ip", env: "LOGIN_RATE_LIMIT_PER_IP", def: "20", usage: "login attempts per minute per client IP",
+		field: func(c *Config) interface{} { return &c.LoginRateLimitPerIP }},
+	{key: "rate_limit.login_per_email", env: "LOGIN_RATE_LIMIT_PER_EMAIL", def: "5", usage: "login attempts per minute per account",
+		field: func(c *Config) interface{} { return &c.LoginRateLimitPerEmail }},
+	{key: "rate_limit.register_per_ip", env: "REGISTER_RATE_LIMIT_PER_IP", def: "5", usage: "registrations per minute per client IP",
+		field: func(c *Config) interface{} { return &c.RegisterRateLimitPerIP }},
+	{key: "rate_limit.lockout_threshold", env: "LOGIN_LOCKOUT_THRESHOLD", def: "5", usage: "failed logins before an account is locked; 0 disables lockouts",
+		field: func(c *Config) interface{} { return &c.LockoutThreshold }},
+	{key: "rate_limit.lockout_base", env: "LOGIN_LOCKOUT_BASE_SECONDS", def: "1m", unit: int64(time.Second), usage: "first lockout, doubled on each further failure",
+		field: func(c *Config) interface{} { return &c.LockoutBaseDuration }},
+	{key: "rate_limit.lockout_max", env: "LOGIN_LOCKOUT_MAX_MINUTES", def: "1h", unit: int64(time.Minute), usage: "longest lockout",
+		field: func(c *Config) interface{} { return &c.LockoutMaxDuration }},
+
+	{key: "smtp.host", env: "SMTP_HOST", usage: "SMTP server; emails are logged when empty",
+		field: func(c *Config) interface{} { return &c.SMTPHost }},
+	{key: "smtp.port", env: "SMTP_PORT", def: "587", usage: "SMTP port",
+		field: func(c *Config) interface{} { return &c.SMTPPort }},
+	{key: "smtp.username", env: "SMTP_USERNAME", usage: "SMTP user",
+		field: func(c *Config) interface{} { return &c.SMTPUsername }},
+	{key: "smtp.password", env: "SMTP_PASSWORD", secret: true, usage: "SMTP password",
+		field: func(c *Config) interface{} { return &c.SMTPPassword }},
+	{key: "smtp.from", env: "SMTP_FROM", def: "no-reply@localhost", usage: "sender of emails",
+		field: func(c *Config) interface{} { return &c.SMTPFrom }},
+
+	{key: "import.max_size", env: "IMPORT_MAX_MB", def: "512MiB", unit: mib, usage: "largest import archive",
+		field: func(c *Config) interface{} { return &c.ImportMaxBytes }},
+
+	{key: "cors.allowed_origins", env: "CORS_ALLOWED_ORIGINS", def: "*", usage: "comma-separated origins that may call the API, exact or like https://*.example.com; * allows any, without cookies",
+		field: func(c *Config) interface{} { return &c.CORSAllowedOrigins }},
+	{key: "cors.allowed_methods", env: "CORS_ALLOWED_METHODS", def: "GET,POST,PUT,PATCH,DELETE", usage: "comma-separated methods allowed cross-origin",
+		field: func(c *Config) interface{} { return &c.CORSAllowedMethods }},
+	{key: "cors.allowed_headers", env: "CORS_ALLOWED_HEADERS", def: "Content-Type,Content-Length,Accept-Encoding,X-CSRF-Token,Authorization,Accept,Origin,Cache-Control,X-Requested-With,X-Request-ID",
+		usage: "comma-separated request headers allowed cross-origin",
+		field: func(c *Config) interface{} { return &c.CORSAllowedHeaders }},
+	{key: "cors.max_age", env: "CORS_MAX_AGE_SECONDS", def: "10m", unit: int64(time.Second), usage: "how long browsers may cache preflight responses; 0 leaves it to them",
+		field: func(c *Config) interface{} { return &c.CORSMaxAge }},
+}
+
+// settingByKey finds a setting.
+func settingByKey(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// set parses raw into the field of s. fromEnv selects the syntax of the
+// environment variable for durations and sizes.
+func (s setting) set(c *Config, raw string, fromEnv bool) error {
+	raw = strings.TrimSpace(raw)
+	switch p := s.field(c).(type) {
+	case *time.Duration:
+		if fromEnv && s.unit != 0 {
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return errors.New("must be a whole number")
+			}
+			*p = time.Duration(n * s.unit)
+			return nil
+		}
+		d, err := parseDuration(raw)
+		if err != nil {
+			return errors.New("must be a duration such as 30s, 15m, 2h or 7d")
+		}
+		*p = d
+	case *int64: // sizes
+		if fromEnv && s.unit != 0 {
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return errors.New("must be a whole number")
+			}
+			*p = n * s.unit
+			return nil
+		}
+		n, err := parseSize(raw)
+		if err != nil {
+			return errors.New("must be a size such as 512MiB or 1GiB")
+		}
+		*p = n
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("must be a whole number")
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		*p = b
+	case *[]string: // comma-separated, or a list in files
+		*p = []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	default:
+		// Strings and string types such as Environment
+		reflect.ValueOf(p).Elem().SetString(raw)
+	}
+	return nil
+}
+
+// value returns the value of s in the syntax of files: a string, an int,
+// a bool or a list of strings.
+func (s setting) value(c *Config) interface{} {
+	switch p := s.field(c).(type) {
+	case *time.Duration:
+		return formatDuration(*p)
+	case *int64:
+		return formatSize(*p)
+	case *int:
+		return *p
+	case *bool:
+		return *p
+	case *[]string:
+		return append([]string{}, *p...)
+	default:
+		return reflect.ValueOf(p).Elem().String()
+	}
+}
+
+// parseDuration is time.ParseDuration with a "d" unit for whole days.
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// formatDuration writes d in the largest unit that divides it, such as 7d
+// rather than 168h0m0s.
+func formatDuration(d time.Duration) string {
+	for _, u := range []struct {
+		unit   time.Duration
+		suffix string
+	}{{24 * time.Hour, "d"}, {time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"}} {
+		if d != 0 && d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+		}
+	}
+	if d == 0 {
+		return "0s"
+	}
+	return d.String()
+}
+
+// sizeUnits are the suffixes of sizes, longest first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3}, {"B", 1},
+}
+
+// parseSize reads a number of bytes with an optional unit, such as 512MiB.
+func parseSize(s string) (int64, error) {
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			v, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+			return v * u.bytes, err
+		}
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// formatSize writes n in the largest binary unit that divides it.
+func formatSize(n int64) string {
+	for _, u := range sizeUnits[:3] {
+		if n != 0 && n%u.bytes == 0 {
+			return strconv.FormatInt(n/u.bytes, 10) + u.suffix
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
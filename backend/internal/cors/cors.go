@@ -0,0 +1,117 @@
+// Package cors answers cross-origin requests from browsers. Allowed
+// origins are echoed back with credentials, so the web app can send the
+// refresh token cookie; "*" allows any origin, but only without
+// credentials, as browsers reject the two together.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Policy configures the middleware.
+type Policy struct {
+	// AllowedOrigins are origins such as "https://notes.example.com",
+	// patterns with a wildcard first label such as "https://*.example.com",
+	// which match any subdomain, or "*" for any origin.
+	AllowedOrigins []string
+	// AllowedMethods and AllowedHeaders are answered to preflight requests.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read.
+	ExposedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response; 0 leaves
+	// it to the browser.
+	MaxAge time.Duration
+}
+
+// pattern is an allowed origin split around its wildcard.
+type pattern struct {
+	prefix, suffix string // "https://" and ".example.com"
+}
+
+func (p pattern) match(origin string) bool {
+	if len(origin) <= len(p.prefix)+len(p.suffix) ||
+		!strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+		return false
+	}
+	sub := origin[len(p.prefix) : len(origin)-len(p.suffix)]
+	return !strings.ContainsAny(sub, "/:@")
+}
+
+// Middleware returns a middleware applying p. It answers every OPTIONS
+// request itself, with the CORS headers when the origin is allowed.
+func Middleware(p Policy) gin.HandlerFunc {
+	exact := make(map[string]bool)
+	var patterns []pattern
+	anyOrigin := false
+	for _, o := range p.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		scheme, host, _ := strings.Cut(o, "://")
+		switch {
+		case o == "*":
+			anyOrigin = true
+		case strings.HasPrefix(host, "*."):
+			patterns = append(patterns, pattern{prefix: scheme + "://", suffix: host[1:]})
+		default:
+			exact[o] = true
+		}
+	}
+
+	methods := strings.ToUpper(strings.Join(p.AllowedMethods, ", "))
+	headers := strings.Join(p.AllowedHeaders, ", ")
+	exposed := strings.Join(p.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(p.MaxAge / time.Second))
+
+	// allowed reports whether origin may call the API and whether it
+	// may send credentials.
+	allowed := func(origin string) (ok, credentials bool) {
+		origin = strings.ToLower(origin)
+		if exact[origin] {
+			return true, true
+		}
+		for _, pt := range patterns {
+			if pt.match(origin) {
+				return true, true
+			}
+		}
+		return anyOrigin, false
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		// The response depends on the origin, also for caches
+		h.Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions
+		if origin := c.GetHeader("Origin"); origin != "" {
+			if ok, credentials := allowed(origin); ok {
+				if credentials {
+					h.Set("Access-Control-Allow-Origin", origin)
+					h.Set("Access-Control-Allow-Credentials", "true")
+				} else {
+					h.Set("Access-Control-Allow-Origin", "*")
+				}
+				if preflight {
+					h.Set("Access-Control-Allow-Methods", methods)
+					h.Set("Access-Control-Allow-Headers", headers)
+					if p.MaxAge > 0 {
+						h.Set("Access-Control-Max-Age", maxAge)
+					}
+				} else if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+			}
+		}
+
+		if preflight {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,141 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter(origins ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(Policy{
+		AllowedOrigins: origins,
+		AllowedMethods: []string{"get", "post", "patch"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	}))
+	router.GET("/notes", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func request(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/notes", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOriginMatching(t *testing.T) {
+	router := newRouter("https://notes.example.com", "https://*.example.com", "http://localhost:3000/")
+	for origin, allowed := range map[string]bool{
+		"https://notes.example.com":     true,
+		"https://NOTES.example.com":     true,
+		"https://app.example.com":       true,
+		"https://a.b.example.com":       true,
+		"http://localhost:3000":         true,
+		"https://example.com":           false,
+		"https://evilexample.com":       false,
+		"https://example.com.evil.com":  false,
+		"https://.example.com":          false,
+		"https://evil.com/.example.com": false,
+		"https://user@app.example.com":  false,
+		"http://notes.example.com":      false,
+		"http://app.example.com":        false,
+		"https://app.example.com:8443":  false,
+		"http://localhost:3001":         false,
+		"https://localhost:3000":        false,
+		"null":                          false,
+	} {
+		rec := request(router, http.MethodGet, origin)
+		got := rec.Header().Get("Access-Control-Allow-Origin")
+		if allowed && (got != origin || rec.Header().Get("Access-Control-Allow-Credentials") != "true") {
+			t.Errorf("%s: Allow-Origin %q, credentials %q, want the origin with credentials",
+				origin, got, rec.Header().Get("Access-Control-Allow-Credentials"))
+		}
+		if !allowed && got != "" {
+			t.Errorf("%s: Allow-Origin %q, want none", origin, got)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want the request to be served", origin, rec.Code)
+		}
+	}
+}
+
+func TestAnyOriginWithoutCredentials(t *testing.T) {
+	router := newRouter("*", "https://notes.example.com")
+	for origin, credentials := range map[string]bool{
+		"https://other.example.org": false,
+		"https://notes.example.com": true,
+	} {
+		for _, method := range []string{http.MethodGet, http.MethodOptions} {
+			rec := request(router, method, origin)
+			h := rec.Header()
+			want := "*"
+			if credentials {
+				want = origin
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != want {
+				t.Errorf("%s %s: Allow-Origin %q, want %q", method, origin, got, want)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); (got == "true") != credentials {
+				t.Errorf("%s %s: Allow-Credentials %q with Allow-Origin %q", method, origin, got, h.Get("Access-Control-Allow-Origin"))
+			}
+		}
+	}
+}
+
+func TestVaryOrigin(t *testing.T) {
+	router := newRouter("https://notes.example.com")
+	for _, origin := range []string{"https://notes.example.com", "https://other.example.org", ""} {
+		for _, method := range []string{http.MethodGet, http.MethodOptions} {
+			if rec := request(router, method, origin); rec.Header().Get("Vary") != "Origin" {
+				t.Errorf("%s from %q: Vary %q, want Origin", method, origin, rec.Header().Get("Vary"))
+			}
+		}
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	router := newRouter("https://notes.example.com")
+
+	rec := request(router, http.MethodOptions, "https://notes.example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("preflight status %d, want 204", rec.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Methods":  "GET, POST, PATCH",
+		"Access-Control-Allow-Headers":  "Content-Type, Authorization",
+		"Access-Control-Max-Age":        "600",
+		"Access-Control-Expose-Headers": "",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("preflight %s = %q, want %q", header, got, want)
+		}
+	}
+
+	// Only actual responses expose headers
+	rec = request(router, http.MethodGet, "https://notes.example.com")
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+		t.Errorf("Expose-Headers = %q, want X-Request-ID", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Errorf("Allow-Methods on an actual response = %q", got)
+	}
+
+	// Preflights from other origins are answered without CORS headers
+	rec = request(router, http.MethodOptions, "https://other.example.org")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("preflight from another origin: status %d, Allow-Methods %q", rec.Code, rec.Header().Get("Access-Control-Allow-Methods"))
+	}
+}
@@ -1,66 +1,141 @@
 package database
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 
+	"github.com/glebarez/sqlite"
 	"github.com/tgogbera/google_keep_clone-backend/internal/config"
 	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
-	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/migrations"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// InitDB initializes the global GORM DB connection and runs migrations.
-func InitDB() error {
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "postgres")
-	dbname := getEnv("DB_NAME", "google_keep_clone")
-	sslmode := getEnv("DB_SSLMODE", "disable")
+// Supported values of the db.driver setting.
+const (
+	DriverPostgres = config.DriverPostgres
+	DriverSQLite   = config.DriverSQLite
+)
 
-	logger.WithFields(map[string]interface{}{
-		"host":    host,
-		"port":    port,
-		"dbname":  dbname,
-		"sslmode": sslmode,
-	}).Info("Connecting to database")
+// Connect opens the global GORM DB connection without touching the schema.
+func Connect() error {
+	db, err := Open(config.Get())
+	if err != nil {
+		logger.WithError(err).Error("Failed to connect to database")
+		return err
+	}
+	DB = db
 
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode,
-	)
+	logger.Info("Database connection established")
+	return nil
+}
 
-	// Get log level from config
-	cfg := config.Get()
-	gormLogger := logger.NewGormLoggerAdapter(logger.LogLevel(cfg.LogLevel))
+// Open opens a GORM connection to the database of cfg. The db.driver
+// setting selects Postgres (default) or a SQLite file for single-user
+// deployments.
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.DBDriver {
+	case DriverPostgres:
+		dialector = postgresDialector(cfg)
+	case DriverSQLite:
+		var err error
+		if dialector, err = sqliteDialector(cfg.DBPath); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q: expected %s or %s", cfg.DBDriver, DriverPostgres, DriverSQLite)
+	}
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: gormLogger,
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.NewGormLoggerAdapter(logger.LogLevel(cfg.LogLevel)),
 	})
 	if err != nil {
-		logger.WithError(err).Error("Failed to connect to database")
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	return db, nil
+}
 
-	logger.Info("Database connection established")
+// InitDB connects to the database and makes sure the schema is current. With
+// MigrateOnStart pending migrations are applied; without it the server
+// refuses to start until they are applied with "migrate up".
+func InitDB(ctx context.Context) error {
+	if err := Connect(); err != nil {
+		return err
+	}
+
+	if !config.Get().MigrateOnStart {
+		pending, err := migrations.Pending(ctx, DB)
+		if err != nil {
+			return fmt.Errorf("failed to check migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("database schema is behind by %d migration(s), starting with %04d_%s: run \"migrate up\"",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
 
-	// Run migrations (including refresh tokens for session management)
 	logger.Info("Running database migrations")
-	if err := DB.AutoMigrate(&models.User{}, &models.Note{}, &models.RefreshToken{}); err != nil {
+	applied, err := migrations.Up(ctx, DB)
+	if err != nil {
 		logger.WithError(err).Error("Failed to run migrations")
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
+	for _, m := range applied {
+		logger.WithFields(map[string]interface{}{
+			"version": m.Version,
+			"name":    m.Name,
+		}).Info("Applied migration")
+	}
 
 	logger.Info("Database migrations completed successfully")
 	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Close closes the connection pool, waiting for queries in progress.
+func Close() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func postgresDialector(cfg *config.Config) gorm.Dialector {
+	logger.WithFields(map[string]interface{}{
+		"host":    cfg.DBHost,
+		"port":    cfg.DBPort,
+		"dbname":  cfg.DBName,
+		"sslmode": cfg.DBSSLMode,
+	}).Info("Connecting to database")
+
+	dsn := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode,
+	)
+	return postgres.Open(dsn)
+}
+
+// sqliteDialector opens the SQLite file at path in WAL mode, so reads
+// don't block on the single writer. Transactions take the write lock up
+// front, which lets busy_timeout resolve contention instead of failing.
+func sqliteDialector(path string) (gorm.Dialector, error) {
+	logger.WithField("path", path).Info("Opening SQLite database")
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
 	}
-	return defaultValue
+
+	dsn := "file:" + path + "?" + url.Values{
+		"_pragma": {"journal_mode(WAL)", "busy_timeout(5000)", "foreign_keys(1)", "synchronous(NORMAL)"},
+		"_txlock": {"immediate"},
+	}.Encode()
+	return sqlite.Open(dsn), nil
 }
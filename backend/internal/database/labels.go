@@ -0,0 +1,42 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FindOrCreateLabels returns the user's labels with the given names, creating
+// the missing ones. Names are trimmed, blanks dropped and duplicates merged.
+func FindOrCreateLabels(tx *gorm.DB, userID int64, names []string) ([]models.Label, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+
+	labels := make([]models.Label, 0, len(unique))
+	if len(unique) == 0 {
+		return labels, nil
+	}
+
+	toCreate := make([]models.Label, 0, len(unique))
+	for _, name := range unique {
+		toCreate = append(toCreate, models.Label{UserID: userID, Name: name})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&toCreate).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ? AND name IN ?", userID, unique).Order("name").Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/rank"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxPositionLength is the size of the notes.position column.
+const MaxPositionLength = 255
+
+// ErrAnchorNotFound is returned by MoveNote when an anchor note doesn't
+// exist or belongs to another user.
+var ErrAnchorNotFound = errors.New("anchor note not found")
+
+// TopNotePosition returns a position that sorts before all of the user's
+// notes. While the user still has notes without a position it returns an
+// empty position too, since those sort first until they are respaced.
+func TopNotePosition(tx *gorm.DB, userID int64) (string, error) {
+	var first []string
+	if err := tx.Model(&models.Note{}).Where("user_id = ?", userID).
+		Order("position").Limit(1).Pluck("position", &first).Error; err != nil {
+		return "", err
+	}
+	if len(first) == 0 {
+		return rank.Between("", "")
+	}
+	if first[0] == "" {
+		return "", nil
+	}
+	return rank.Between("", first[0])
+}
+
+// MoveNote places the note right after the note with ID after and/or before
+// the note with ID before, and returns its new position. Only the moved note
+// is written, unless the user's positions first have to be respaced because
+// they are missing, duplicated or too long.
+func MoveNote(tx *gorm.DB, userID, noteID int64, after, before *int64) (string, error) {
+	if err := LockUser(tx, userID); err != nil {
+		return "", err
+	}
+
+	position, err := positionBetween(tx, userID, noteID, after, before)
+	if errors.Is(err, rank.ErrInvalidRange) || (err == nil && len(position) > MaxPositionLength) {
+		if err := respaceNotePositions(tx, userID); err != nil {
+			return "", err
+		}
+		position, err = positionBetween(tx, userID, noteID, after, before)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Reordering isn't an edit, so updated_at is left alone
+	if err := tx.Model(&models.Note{}).Where("id = ? AND user_id = ?", noteID, userID).
+		UpdateColumn("position", position).Error; err != nil {
+		return "", err
+	}
+	return position, nil
+}
+
+// positionBetween computes a position between the anchors. With a single
+// anchor the other bound is that anchor's current neighbour.
+func positionBetween(tx *gorm.DB, userID, noteID int64, after, before *int64) (string, error) {
+	var lower, upper string
+	var err error
+
+	if after != nil {
+		if lower, err = anchorPosition(tx, userID, *after); err != nil {
+			return "", err
+		}
+	}
+	if before != nil {
+		if upper, err = anchorPosition(tx, userID, *before); err != nil {
+			return "", err
+		}
+	}
+
+	neighbour := func(cond, order string, bound string) (string, error) {
+		var positions []string
+		err := tx.Model(&models.Note{}).
+			Where("user_id = ? AND id <> ? AND position "+cond+" ?", userID, noteID, bound).
+			Order(order).Limit(1).Pluck("position", &positions).Error
+		if err != nil || len(positions) == 0 {
+			return "", err
+		}
+		return positions[0], nil
+	}
+	if after == nil {
+		if lower, err = neighbour("<", "position DESC", upper); err != nil {
+			return "", err
+		}
+	}
+	if before == nil {
+		if upper, err = neighbour(">", "position", lower); err != nil {
+			return "", err
+		}
+	}
+
+	return rank.Between(lower, upper)
+}
+
+// anchorPosition returns the position of one of the user's notes. Notes that
+// were never placed have no usable position and report rank.ErrInvalidRange.
+func anchorPosition(tx *gorm.DB, userID, noteID int64) (string, error) {
+	var positions []string
+	if err := tx.Model(&models.Note{}).Where("id = ? AND user_id = ?", noteID, userID).
+		Pluck("position", &positions).Error; err != nil {
+		return "", err
+	}
+	if len(positions) == 0 {
+		return "", ErrAnchorNotFound
+	}
+	if positions[0] == "" {
+		return "", rank.ErrInvalidRange
+	}
+	return positions[0], nil
+}
+
+// RespaceNotePositions gives all of the user's notes fresh, evenly spaced
+// positions, keeping their current order.
+func RespaceNotePositions(tx *gorm.DB, userID int64) error {
+	if err := LockUser(tx, userID); err != nil {
+		return err
+	}
+	return respaceNotePositions(tx, userID)
+}
+
+func respaceNotePositions(tx *gorm.DB, userID int64) error {
+	var ids []int64
+	if err := tx.Model(&models.Note{}).Where("user_id = ?", userID).
+		Order("position, created_at DESC, id DESC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for i, position := range rank.Spread(len(ids)) {
+		if err := tx.Model(&models.Note{}).Where("id = ?", ids[i]).
+			UpdateColumn("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LockUser locks the user's row until the end of the transaction. It
+// serialises changes to the user's note order and the start of imports.
+func LockUser(tx *gorm.DB, userID int64) error {
+	var user models.User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error
+}
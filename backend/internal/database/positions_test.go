@@ -0,0 +1,136 @@
+package database_test
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/rank"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	os.Exit(m.Run())
+}
+
+// createNotes creates a user with one note per position, titled by index
+// and created one minute apart, and returns the user ID and note IDs.
+func createNotes(t *testing.T, db *gorm.DB, email string, positions ...string) (int64, []int64) {
+	t.Helper()
+	user := models.User{Email: email, PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := make([]int64, len(positions))
+	for i, position := range positions {
+		note := models.Note{
+			UserID: user.ID, Title: string(rune('A' + i)), Position: position,
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+		}
+		if err := db.Create(&note).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = note.ID
+	}
+	return user.ID, ids
+}
+
+// manualOrder returns the titles of the user's notes in manual order and
+// fails when a position is not a unique, valid key.
+func manualOrder(t *testing.T, db *gorm.DB, userID int64) string {
+	t.Helper()
+	var notes []models.Note
+	if err := db.Where("user_id = ?", userID).Order("position, id").Find(&notes).Error; err != nil {
+		t.Fatal(err)
+	}
+	var order strings.Builder
+	for i, n := range notes {
+		if n.Position == "" || !rank.Valid(n.Position) || len(n.Position) > database.MaxPositionLength {
+			t.Errorf("note %s has position %q", n.Title, n.Position)
+		}
+		if i > 0 && notes[i-1].Position == n.Position {
+			t.Errorf("notes %s and %s share position %q", notes[i-1].Title, n.Title, n.Position)
+		}
+		order.WriteString(n.Title)
+	}
+	return order.String()
+}
+
+func TestMoveNote(t *testing.T) {
+	long := strings.Repeat("z", database.MaxPositionLength)
+	for _, tt := range []struct {
+		name          string
+		positions     []string
+		move          int
+		after, before *int
+		want          string
+	}{
+		// Respacing orders ties newest first: C, B, A
+		{"duplicated", []string{"i", "i", "i"}, 0, intPtr(2), intPtr(1), "CAB"},
+		{"empty", []string{"", "", "i"}, 2, intPtr(1), nil, "BCA"},
+		{"empty anchor", []string{"", "a", "b"}, 2, nil, intPtr(0), "CAB"},
+		{"too long", []string{"a", long}, 0, intPtr(1), nil, "BA"},
+		{"regular", []string{"a", "b", "c"}, 0, intPtr(1), intPtr(2), "BAC"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+				userID, ids := createNotes(t, db, "alice@example.com", tt.positions...)
+				var after, before *int64
+				if tt.after != nil {
+					after = &ids[*tt.after]
+				}
+				if tt.before != nil {
+					before = &ids[*tt.before]
+				}
+
+				err := db.Transaction(func(tx *gorm.DB) error {
+					_, err := database.MoveNote(tx, userID, ids[tt.move], after, before)
+					return err
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := manualOrder(t, db, userID); got != tt.want {
+					t.Errorf("order after move = %s, want %s", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestRespaceNotePositions(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		userID, _ := createNotes(t, db, "alice@example.com", "k", "", "b", "k", "")
+		otherID, _ := createNotes(t, db, "bob@example.com", "", "")
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return database.RespaceNotePositions(tx, userID)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		// Empty positions first, ties newest first
+		if got := manualOrder(t, db, userID); got != "EBCDA" {
+			t.Errorf("order after respacing = %s, want EBCDA", got)
+		}
+
+		var positions []string
+		if err := db.Model(&models.Note{}).Where("user_id = ?", otherID).Order("id").
+			Pluck("position", &positions).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(positions, []string{"", ""}) {
+			t.Errorf("another user's positions changed to %q", positions)
+		}
+	})
+}
+
+func intPtr(i int) *int { return &i }
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// searchDocument is the text searched in Postgres. The expression has to
+// match idx_notes_search (created by the migrations) exactly for the index
+// to be used. On SQLite the migrations create notes_fts instead.
+const searchDocument = "to_tsvector('simple', title || ' ' || coalesce(content, ''))"
+
+// SearchNotes restricts a query on notes to those whose title or content
+// contains every word of q.
+func SearchNotes(db *gorm.DB, q string) *gorm.DB {
+	if db.Dialector.Name() == DriverSQLite {
+		return db.Where("notes.id IN (SELECT rowid FROM notes_fts WHERE notes_fts MATCH ?)", ftsQuery(q))
+	}
+	return db.Where(searchDocument+" @@ plainto_tsquery('simple', ?)", q)
+}
+
+// ftsQuery quotes every word of q so FTS5 treats it as a plain term rather
+// than query syntax.
+func ftsQuery(q string) string {
+	words := strings.Fields(q)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, for use with
+// ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes s so a LIKE ... ESCAPE '\' pattern matches it
+// literally.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
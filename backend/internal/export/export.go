@@ -0,0 +1,87 @@
+package export
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// Supported export formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+	FormatENEX     = "enex"
+)
+
+// Source provides the notes to export. EachNote must preload Labels and the
+// attachment metadata; attachment contents are fetched one at a time.
+type Source interface {
+	EachNote(fn func(note models.Note) error) error
+	AttachmentData(id int64) ([]byte, error)
+}
+
+// NotePath returns a stable, filesystem-safe path for a note inside an archive.
+func NotePath(note models.Note, ext string) string {
+	slug := slugify(note.Title)
+	if slug == "" {
+		return fmt.Sprintf("notes/%d%s", note.ID, ext)
+	}
+	return fmt.Sprintf("notes/%d-%s%s", note.ID, slug, ext)
+}
+
+// AttachmentPath returns the path of an attachment inside an archive. Only
+// the base of the stored filename is used, so extracting the archive can't
+// write outside the attachments directory.
+func AttachmentPath(a models.Attachment) string {
+	name := CleanFilename(a.Filename)
+	if name == "" {
+		return fmt.Sprintf("attachments/%d", a.ID)
+	}
+	return fmt.Sprintf("attachments/%d-%s", a.ID, name)
+}
+
+// CleanFilename returns the last element of name, with backslashes counted
+// as separators, or "" when that is empty, "." or "..".
+func CleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	switch name {
+	case ".", "..", "/":
+		return ""
+	}
+	return name
+}
+
+func labelNames(labels []models.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// slugify lowercases s and keeps only ASCII letters and digits, joined by dashes.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if b.Len() >= 50 {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,22 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+func TestAttachmentPath(t *testing.T) {
+	for filename, want := range map[string]string{
+		"photo.jpg":        "attachments/7-photo.jpg",
+		"../../etc/passwd": "attachments/7-passwd",
+		`..\..\boot.ini`:   "attachments/7-boot.ini",
+		"/":                "attachments/7",
+		"..":               "attachments/7",
+		"":                 "attachments/7",
+	} {
+		if got := AttachmentPath(models.Attachment{ID: 7, Filename: filename}); got != want {
+			t.Errorf("AttachmentPath(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
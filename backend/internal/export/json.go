@@ -0,0 +1,129 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// DocumentVersion is the version of the JSON export format.
+const DocumentVersion = 1
+
+// DocumentFile is the name of the notes document inside a JSON export archive.
+const DocumentFile = "notes.json"
+
+// Document is the JSON export format. It is read back by the JSON import,
+// so every field needed to recreate a note must be present.
+type Document struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Notes      []NoteRecord `json:"notes"`
+}
+
+// NoteRecord is a note in the JSON export format.
+type NoteRecord struct {
+	Title       string             `json:"title"`
+	Content     string             `json:"content"`
+	Color       string             `json:"color"`
+	Pinned      bool               `json:"pinned"`
+	Archived    bool               `json:"archived"`
+	TrashedAt   *time.Time         `json:"trashed_at,omitempty"`
+	Position    string             `json:"position,omitempty"`
+	Revision    int                `json:"revision,omitempty"`
+	Labels      []string           `json:"labels"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Attachments []AttachmentRecord `json:"attachments,omitempty"`
+}
+
+// AttachmentRecord references an attachment file inside the archive.
+type AttachmentRecord struct {
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Path      string    `json:"path"`
+}
+
+// NewNoteRecord converts a note to its JSON export representation.
+func NewNoteRecord(note models.Note) NoteRecord {
+	rec := NoteRecord{
+		Title:     note.Title,
+		Content:   note.Content,
+		Color:     note.Color,
+		Pinned:    note.Pinned,
+		Archived:  note.Archived,
+		TrashedAt: note.TrashedAt,
+		Position:  note.Position,
+		Revision:  note.Revision,
+		Labels:    labelNames(note.Labels),
+		CreatedAt: note.CreatedAt,
+		UpdatedAt: note.UpdatedAt,
+	}
+	for _, a := range note.Attachments {
+		rec.Attachments = append(rec.Attachments, AttachmentRecord{
+			Filename:  a.Filename,
+			MimeType:  a.MimeType,
+			Size:      a.Size,
+			CreatedAt: a.CreatedAt,
+			Path:      AttachmentPath(a),
+		})
+	}
+	return rec
+}
+
+// JSON writes a ZIP with notes.json (a Document) and the attachment files.
+func JSON(w io.Writer, src Source) error {
+	zw := zip.NewWriter(w)
+
+	if err := WriteDocument(zw, src); err != nil {
+		return err
+	}
+	if err := WriteAttachments(zw, src); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// WriteDocument adds notes.json to zw. The notes array is streamed instead
+// of building the whole Document in memory.
+func WriteDocument(zw *zip.Writer, src Source) error {
+	f, err := zw.Create(DocumentFile)
+	if err != nil {
+		return err
+	}
+
+	exportedAt, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf(`{"version":%d,"exported_at":%s,"notes":[`, DocumentVersion, exportedAt)
+	if _, err := io.WriteString(f, header); err != nil {
+		return err
+	}
+
+	first := true
+	err = src.EachNote(func(note models.Note) error {
+		data, err := json.Marshal(NewNoteRecord(note))
+		if err != nil {
+			return err
+		}
+		sep := ",\n"
+		if first {
+			sep = "\n"
+			first = false
+		}
+		_, err = f.Write(append([]byte(sep), data...))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(f, "\n]}\n")
+	return err
+}
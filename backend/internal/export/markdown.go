@@ -0,0 +1,102 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// RenderMarkdown renders a note as Markdown with a YAML front-matter block
+// carrying its labels, color, state, timestamps and attachment paths.
+func RenderMarkdown(note models.Note) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	writeYAML(&b, "title", note.Title)
+	writeYAML(&b, "labels", labelNames(note.Labels))
+	writeYAML(&b, "color", note.Color)
+	writeYAML(&b, "pinned", note.Pinned)
+	writeYAML(&b, "archived", note.Archived)
+	if note.TrashedAt != nil {
+		writeYAML(&b, "trashed_at", formatTime(*note.TrashedAt))
+	}
+	writeYAML(&b, "created_at", formatTime(note.CreatedAt))
+	writeYAML(&b, "updated_at", formatTime(note.UpdatedAt))
+	if len(note.Attachments) > 0 {
+		paths := make([]string, 0, len(note.Attachments))
+		for _, a := range note.Attachments {
+			paths = append(paths, "../"+AttachmentPath(a))
+		}
+		writeYAML(&b, "attachments", paths)
+	}
+	b.WriteString("---\n\n")
+
+	b.WriteString(note.Content)
+	if !strings.HasSuffix(note.Content, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// writeYAML writes a single key. Values are encoded as JSON, which is valid
+// YAML and takes care of quoting and escaping.
+func writeYAML(b *strings.Builder, key string, value interface{}) {
+	data, _ := json.Marshal(value)
+	b.WriteString(key + ": " + string(data) + "\n")
+}
+
+// Markdown writes a ZIP with one Markdown file per note under notes/ and the
+// attachment files under attachments/.
+func Markdown(w io.Writer, src Source) error {
+	zw := zip.NewWriter(w)
+
+	if err := WriteMarkdownNotes(zw, src); err != nil {
+		return err
+	}
+	if err := WriteAttachments(zw, src); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// WriteMarkdownNotes adds one Markdown file per note to zw.
+func WriteMarkdownNotes(zw *zip.Writer, src Source) error {
+	return src.EachNote(func(note models.Note) error {
+		f, err := zw.Create(NotePath(note, ".md"))
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, RenderMarkdown(note))
+		return err
+	})
+}
+
+// WriteAttachments adds every attachment of the exported notes to zw.
+func WriteAttachments(zw *zip.Writer, src Source) error {
+	// Collect the metadata first: a zip entry can't be written while notes are still streaming in
+	var attachments []models.Attachment
+	err := src.EachNote(func(note models.Note) error {
+		attachments = append(attachments, note.Attachments...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		data, err := src.AttachmentData(a.ID)
+		if err != nil {
+			return err
+		}
+		f, err := zw.Create(AttachmentPath(a))
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/export"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+	"github.com/tgogbera/google_keep_clone-backend/internal/service"
+)
+
+// AccountHandler serves the profile and the account of the authenticated
+// user, and the confirmation of email changes.
+type AccountHandler struct {
+	users  repository.UserRepository
+	tokens repository.TokenRepository
+	emails repository.EmailChangeRepository
+	notes  *service.NoteService
+	audit  *audit.Log
+}
+
+// NewAccountHandler returns an AccountHandler using the given repositories,
+// exporting notes through notes and recording events in auditLog.
+func NewAccountHandler(users repository.UserRepository, tokens repository.TokenRepository, emails repository.EmailChangeRepository,
+	notes *service.NoteService, auditLog *audit.Log) *AccountHandler {
+	return &AccountHandler{users: users, tokens: tokens, emails: emails, notes: notes, audit: auditLog}
+}
+
+// ExportAccount streams a ZIP archive with the user's profile, notes (in the
+// JSON export format and as one Markdown file per note), labels and
+// attachment files.
+func (h *AccountHandler) ExportAccount(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "ExportAccount",
+		"ip":      c.ClientIP(),
+	})
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	ctx := c.Request.Context()
+	user, err := h.users.FindByID(ctx, userID.(int64))
+	if err != nil {
+		log.WithError(err).Warn("User not found")
+		return apierror.NotFound("User not found")
+	}
+
+	filename := fmt.Sprintf("keep-export-%d-%s.zip", user.ID, time.Now().UTC().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// From here on the response is committed; errors can only be logged
+	zw := zip.NewWriter(c.Writer)
+
+	if err := writeZipJSON(zw, "profile.json", user.ToDTO()); err != nil {
+		log.WithError(err).Error("Failed to write profile to export")
+		return nil
+	}
+
+	src := noteSource{ctx: ctx, notes: h.notes, userID: user.ID}
+	if err := export.WriteDocument(zw, src); err != nil {
+		log.WithError(err).Error("Failed to write notes to export")
+		return nil
+	}
+	if err := export.WriteMarkdownNotes(zw, src); err != nil {
+		log.WithError(err).Error("Failed to write note files to export")
+		return nil
+	}
+
+	labels, err := h.notes.Labels(ctx, user.ID)
+	if err != nil {
+		log.WithError(err).Error("Failed to load labels for export")
+		return nil
+	}
+	if err := writeZipJSON(zw, "labels.json", labels); err != nil {
+		log.WithError(err).Error("Failed to write labels to export")
+		return nil
+	}
+
+	if err := export.WriteAttachments(zw, src); err != nil {
+		log.WithError(err).Error("Failed to write attachments to export")
+		return nil
+	}
+
+	if err := zw.Close(); err != nil {
+		log.WithError(err).Error("Failed to finish export archive")
+		return nil
+	}
+
+	log.Info("Account data exported")
+	return nil
+}
+
+// DeleteAccount schedules the account for deletion after the configured grace
+// period. The password must be re-confirmed. All sessions are revoked
+// immediately; logging in again during the grace period cancels the deletion.
+func (h *AccountHandler) DeleteAccount(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "DeleteAccount",
+		"ip":      c.ClientIP(),
+	})
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid delete account request")
+		return apierror.Bind(err)
+	}
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	ctx := c.Request.Context()
+	user, err := h.users.FindByID(ctx, userID.(int64))
+	if err != nil {
+		log.WithError(err).Warn("User not found")
+		return apierror.NotFound("User not found")
+	}
+
+	if err := checkPassword(ctx, user.PasswordHash, req.Password); err != nil {
+		log.Warn("Account deletion refused: invalid password")
+		return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid password")
+	}
+
+	cfg := config.Get()
+	deleteAfter := time.Now().Add(cfg.AccountDeletionGracePeriod)
+
+	// Revoke sessions first: if scheduling then fails, the worst case is
+	// signing in again
+	if err := h.tokens.RevokeAll(ctx, user.ID); err != nil {
+		log.WithError(err).Error("Failed to revoke sessions")
+		return apierror.Internal(err, "Failed to schedule account deletion")
+	}
+	if err := h.users.Update(ctx, user, map[string]interface{}{"delete_after": deleteAfter}); err != nil {
+		log.WithError(err).Error("Failed to schedule account deletion")
+		return apierror.Internal(err, "Failed to schedule account deletion")
+	}
+
+	// Clear the refresh cookie of the current session too
+	c.SetCookie(cfg.RefreshTokenCookieName, "", -1, "/", "", cfg.Environment == config.EnvProduction, true)
+
+	h.audit.Record(auditContext(c), models.AuditEvent{
+		UserID: audit.ID(user.ID),
+		Action: audit.ActionDeleteRequest,
+		After:  map[string]interface{}{"delete_after": deleteAfter},
+	})
+
+	log.WithField("delete_after", deleteAfter).Info("Account deletion scheduled")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "Account scheduled for deletion",
+		"delete_after": deleteAfter,
+	})
+	return nil
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/admin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// Page size limits of ListUsers.
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// AdminHandler serves the /api/admin endpoints. The routes must be guarded
+// by AuthMiddleware and RequireRole(models.RoleAdmin). Every action is
+// recorded in the audit log.
+type AdminHandler struct {
+	admin *admin.Service
+	audit *audit.Log
+}
+
+// NewAdminHandler returns an AdminHandler acting through service and
+// recording its actions in auditLog.
+func NewAdminHandler(service *admin.Service, auditLog *audit.Log) *AdminHandler {
+	return &AdminHandler{admin: service, audit: auditLog}
+}
+
+// ListUsers returns users with their note counts and storage. Query
+// parameters: search (email or display name), limit (default 50, at most
+// 200) and offset.
+func (h *AdminHandler) ListUsers(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler":  "ListUsers",
+		"ip":       c.ClientIP(),
+		"admin_id": c.GetInt64("user_id"),
+	})
+
+	query := admin.UserQuery{Search: c.Query("search"), Limit: defaultAdminPageSize}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxAdminPageSize {
+			log.WithField("limit", s).Warn("Invalid limit")
+			return apierror.BadRequest("limit must be between 1 and 200")
+		}
+		query.Limit = n
+	}
+	if s := c.Query("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			log.WithField("offset", s).Warn("Invalid offset")
+			return apierror.BadRequest("offset must be a non-negative number")
+		}
+		query.Offset = n
+	}
+
+	users, err := h.admin.ListUsers(c.Request.Context(), query)
+	if err != nil {
+		log.WithError(err).Error("Failed to list users")
+		return apierror.Internal(err, "Failed to list users")
+	}
+
+	h.record(c, audit.ActionAdminListUsers, 0, nil, map[string]interface{}{"search": query.Search, "count": len(users)})
+
+	c.JSON(http.StatusOK, users)
+	return nil
+}
+
+// GetUser returns one user with their note counts and storage.
+func (h *AdminHandler) GetUser(c *gin.Context) error {
+	log, targetID, err := adminTarget(c, "GetUser")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.admin.GetUser(c.Request.Context(), targetID)
+	if err != nil {
+		return adminError(log, err, "Failed to load user")
+	}
+
+	h.record(c, audit.ActionAdminViewUser, targetID, nil, nil)
+
+	c.JSON(http.StatusOK, user)
+	return nil
+}
+
+// DisableUser blocks the user's logins and requests and revokes their
+// sessions. Admins can't disable themselves.
+func (h *AdminHandler) DisableUser(c *gin.Context) error {
+	log, targetID, err := adminTarget(c, "DisableUser")
+	if err != nil {
+		return err
+	}
+	if targetID == c.GetInt64("user_id") {
+		log.Warn("Admin tried to disable their own account")
+		return apierror.BadRequest("You cannot disable your own account")
+	}
+
+	if err := h.admin.DisableUser(c.Request.Context(), targetID); err != nil {
+		return adminError(log, err, "Failed to disable user")
+	}
+
+	h.record(c, audit.ActionAdminDisable, targetID, nil, nil)
+
+	return h.respondWithUser(c, log, targetID)
+}
+
+// EnableUser lets a disabled user log in again.
+func (h *AdminHandler) EnableUser(c *gin.Context) error {
+	log, targetID, err := adminTarget(c, "EnableUser")
+	if err != nil {
+		return err
+	}
+
+	if err := h.admin.EnableUser(c.Request.Context(), targetID); err != nil {
+		return adminError(log, err, "Failed to enable user")
+	}
+
+	h.record(c, audit.ActionAdminEnable, targetID, nil, nil)
+
+	return h.respondWithUser(c, log, targetID)
+}
+
+// LogoutUser revokes all of the user's refresh tokens. Access tokens
+// already issued stay valid until they expire.
+func (h *AdminHandler) LogoutUser(c *gin.Context) error {
+	log, targetID, err := adminTarget(c, "LogoutUser")
+	if err != nil {
+		return err
+	}
+
+	revoked, err := h.admin.Logout(c.Request.Context(), targetID)
+	if err != nil {
+		return adminError(log, err, "Failed to revoke sessions")
+	}
+
+	h.record(c, audit.ActionAdminLogout, targetID, nil, map[string]interface{}{"revoked": revoked})
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+	return nil
+}
+
+// SetRole changes the user's role. Admins can't change their own role, so
+// there is always an admin left who can undo a mistake.
+func (h *AdminHandler) SetRole(c *gin.Context) error {
+	log, targetID, err := adminTarget(c, "SetRole")
+	if err != nil {
+		return err
+	}
+
+	var req models.SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid set role request")
+		return apierror.Bind(err)
+	}
+	if targetID == c.GetInt64("user_id") {
+		log.Warn("Admin tried to change their own role")
+		return apierror.BadRequest("You cannot change your own role")
+	}
+
+	before, err := h.admin.SetRole(c.Request.Context(), targetID, req.Role)
+	if err != nil {
+		return adminError(log, err, "Failed to change role")
+	}
+
+	h.record(c, audit.ActionAdminSetRole, targetID, map[string]interface{}{"role": before}, map[string]interface{}{"role": req.Role})
+
+	return h.respondWithUser(c, log, targetID)
+}
+
+// respondWithUser writes the user's current summary after a change.
+func (h *AdminHandler) respondWithUser(c *gin.Context, log *logrus.Entry, userID int64) error {
+	user, err := h.admin.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		return adminError(log, err, "Failed to load user")
+	}
+	c.JSON(http.StatusOK, user)
+	return nil
+}
+
+// adminTarget reads the :id URL parameter naming the user an admin acts on.
+func adminTarget(c *gin.Context, handler string) (*logrus.Entry, int64, error) {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler":  handler,
+		"ip":       c.ClientIP(),
+		"admin_id": c.GetInt64("user_id"),
+	})
+
+	id, err := idParam(c, "id", "user")
+	if err != nil {
+		log.WithField("user_id_str", c.Param("id")).Warn("Invalid user ID format")
+		return log, 0, err
+	}
+	return log.WithField("target_user_id", id), id, nil
+}
+
+// adminError converts an error from the admin package. failure is the
+// detail used for unexpected errors.
+func adminError(log *logrus.Entry, err error, failure string) error {
+	if errors.Is(err, admin.ErrUserNotFound) {
+		log.Warn("User not found")
+		return apierror.NotFound("User not found")
+	}
+	log.WithError(err).Error(failure)
+	return apierror.Internal(err, failure)
+}
+
+// record adds an admin action to the audit log. targetID is the user acted
+// on, 0 for none.
+func (h *AdminHandler) record(c *gin.Context, action string, targetID int64, before, after map[string]interface{}) {
+	event := models.AuditEvent{Action: action, Before: before, After: after}
+	if targetID != 0 {
+		event.UserID = audit.ID(targetID)
+		event.TargetType = models.AuditTargetUser
+		event.TargetID = audit.ID(targetID)
+	}
+	h.audit.Record(auditContext(c), event)
+}
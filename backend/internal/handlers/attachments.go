@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/service"
+)
+
+// GetAttachment serves the content of an attachment owned by the user.
+func (h *NoteHandler) GetAttachment(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "GetAttachment",
+		"ip":      c.ClientIP(),
+	})
+
+	attachmentID, err := idParam(c, "id", "attachment")
+	if err != nil {
+		log.WithField("attachment_id_str", c.Param("id")).Warn("Invalid attachment ID format")
+		return err
+	}
+
+	log = log.WithField("attachment_id", attachmentID)
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	attachment, err := h.notes.Attachment(c.Request.Context(), userID.(int64), attachmentID)
+	if errors.Is(err, service.ErrAttachmentNotFound) {
+		log.Warn("Attachment not found or does not belong to user")
+		return apierror.NotFound("Attachment not found")
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to load attachment")
+		return apierror.Internal(err, "Failed to load attachment")
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", attachment.Filename))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, attachment.MimeType, attachment.Data)
+	return nil
+}
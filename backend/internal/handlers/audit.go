@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+)
+
+// Page size limits of the audit event endpoints.
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// AuditHandler serves the audit log: a user's own activity and the admin
+// query endpoint.
+type AuditHandler struct {
+	events repository.AuditRepository
+	audit  *audit.Log
+}
+
+// NewAuditHandler returns an AuditHandler reading events and recording
+// admin queries in auditLog.
+func NewAuditHandler(events repository.AuditRepository, auditLog *audit.Log) *AuditHandler {
+	return &AuditHandler{events: events, audit: auditLog}
+}
+
+// MyActivity returns the events done by or concerning the current user,
+// newest first. Query parameters: action (exact or a prefix ending in "."),
+// since and until (RFC 3339), before_id for the next page and limit
+// (default 50, at most 200).
+func (h *AuditHandler) MyActivity(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "MyActivity",
+		"ip":      c.ClientIP(),
+		"user_id": c.GetInt64("user_id"),
+	})
+
+	filter, err := auditFilter(c, log)
+	if err != nil {
+		return err
+	}
+	filter.Involving = c.GetInt64("user_id")
+
+	events, err := h.events.List(c.Request.Context(), filter)
+	if err != nil {
+		log.WithError(err).Error("Failed to load activity")
+		return apierror.Internal(err, "Failed to load activity")
+	}
+
+	c.JSON(http.StatusOK, events)
+	return nil
+}
+
+// QueryEvents returns audit events of all users, newest first. On top of
+// the parameters of MyActivity it filters by actor_id, user_id,
+// target_type and target_id. The query itself is recorded.
+func (h *AuditHandler) QueryEvents(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler":  "QueryEvents",
+		"ip":       c.ClientIP(),
+		"admin_id": c.GetInt64("user_id"),
+	})
+
+	filter, err := auditFilter(c, log)
+	if err != nil {
+		return err
+	}
+	for param, dest := range map[string]*int64{
+		"actor_id":  &filter.ActorID,
+		"user_id":   &filter.UserID,
+		"target_id": &filter.TargetID,
+	} {
+		if *dest, err = positiveParam(c, log, param); err != nil {
+			return err
+		}
+	}
+	filter.TargetType = c.Query("target_type")
+
+	ctx := auditContext(c)
+	events, err := h.events.List(ctx, filter)
+	if err != nil {
+		log.WithError(err).Error("Failed to query audit events")
+		return apierror.Internal(err, "Failed to query audit events")
+	}
+
+	h.audit.Record(ctx, models.AuditEvent{
+		Action: audit.ActionAdminQueryEvents,
+		After:  map[string]interface{}{"query": c.Request.URL.RawQuery, "count": len(events)},
+	})
+
+	c.JSON(http.StatusOK, events)
+	return nil
+}
+
+// auditFilter reads the query parameters shared by the audit endpoints.
+func auditFilter(c *gin.Context, log *logrus.Entry) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{Action: c.Query("action"), Limit: defaultAuditPageSize}
+
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxAuditPageSize {
+			log.WithField("limit", s).Warn("Invalid limit")
+			return filter, apierror.Invalid("limit", "range", "must be between 1 and 200")
+		}
+		filter.Limit = n
+	}
+
+	var err error
+	if filter.BeforeID, err = positiveParam(c, log, "before_id"); err != nil {
+		return filter, err
+	}
+
+	for param, dest := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		s := c.Query(param)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			log.WithField(param, s).Warn("Invalid time")
+			return filter, apierror.Invalid(param, "time", "must be an RFC 3339 time")
+		}
+		*dest = t
+	}
+	return filter, nil
+}
+
+// positiveParam reads an optional ID query parameter; 0 means absent.
+func positiveParam(c *gin.Context, log *logrus.Entry, param string) (int64, error) {
+	s := c.Query(param)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 1 {
+		log.WithField(param, s).Warn("Invalid ID parameter")
+		return 0, apierror.Invalid(param, "gt", "must be a positive number")
+	}
+	return n, nil
+}
+
+// auditContext returns the request context carrying the audit source of
+// the request: the authenticated user, if any, the client IP and the user
+// agent.
+func auditContext(c *gin.Context) context.Context {
+	return audit.WithSource(c.Request.Context(), audit.Source{
+		ActorID:   c.GetInt64("user_id"),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+}
@@ -1,32 +1,53 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
 	"github.com/tgogbera/google_keep_clone-backend/internal/config"
-	"github.com/tgogbera/google_keep_clone-backend/internal/database"
 	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/metrics"
 	"github.com/tgogbera/google_keep_clone-backend/internal/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+	"github.com/tgogbera/google_keep_clone-backend/internal/tracing"
 )
 
 // Claims contains the JWT payload for access tokens.
 type Claims struct {
 	UserID int64  `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func Register(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
+// AuthHandler serves registration, login and session endpoints, and records
+// them in the audit log.
+type AuthHandler struct {
+	users  repository.UserRepository
+	tokens repository.TokenRepository
+	audit  *audit.Log
+}
+
+// NewAuthHandler returns an AuthHandler using the given repositories and
+// audit log.
+func NewAuthHandler(users repository.UserRepository, tokens repository.TokenRepository, auditLog *audit.Log) *AuthHandler {
+	return &AuthHandler{users: users, tokens: tokens, audit: auditLog}
+}
+
+func (h *AuthHandler) Register(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"handler": "Register",
 		"ip":      c.ClientIP(),
 	})
@@ -34,74 +55,56 @@ func Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.WithError(err).Warn("Invalid registration request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return apierror.Bind(err)
 	}
 
 	log = log.WithField("email", req.Email)
 	log.Info("Processing registration request")
 
+	ctx := c.Request.Context()
+
 	// Check if user already exists
-	var existing models.User
-	if err := database.DB.Where("email = ?", req.Email).First(&existing).Error; err == nil {
+	if _, err := h.users.FindByEmail(ctx, req.Email); err == nil {
 		log.Warn("Registration failed: email already exists")
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
-		return
+		return apierror.New(http.StatusConflict, apierror.CodeEmailTaken, "User with this email already exists")
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(ctx, req.Password)
 	if err != nil {
 		log.WithError(err).Error("Failed to hash password")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
+		return apierror.Internal(err, "Failed to hash password")
 	}
 
 	// Insert user
 	user := models.User{
 		Email:        req.Email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 	}
-	if err := database.DB.Create(&user).Error; err != nil {
+	if err := h.users.Create(ctx, &user); err != nil {
 		log.WithError(err).Error("Failed to create user in database")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-		return
+		return apierror.Internal(err, "Failed to create user")
 	}
 
 	log = log.WithField("user_id", user.ID)
 
-	// Create tokens
-	accessToken, err := generateAccessToken(user.ID, user.Email)
+	resp, err := h.issueSession(c, user)
 	if err != nil {
-		log.WithError(err).Error("Failed to generate access token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
-		return
+		log.WithError(err).Error("Failed to issue session")
+		return apierror.Internal(err, "Failed to generate tokens")
 	}
 
-	refreshToken, err := generateAndStoreRefreshToken(user.ID)
-	if err != nil {
-		log.WithError(err).Error("Failed to generate refresh token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
-		return
-	}
-
-	// Set refresh token as secure httpOnly cookie
-	cfg := config.Get()
-	maxAge := int(cfg.RefreshTokenTTL.Seconds())
-	secure := cfg.Environment == config.EnvProduction
-	c.SetCookie(cfg.RefreshTokenCookieName, refreshToken, maxAge, "/", "", secure, true)
+	h.record(c, audit.ActionRegister, user.ID, map[string]interface{}{"email": user.Email})
+	metrics.Registrations.Inc()
 
 	log.Info("User registered successfully")
 
-	c.JSON(http.StatusCreated, models.AuthResponse{
-		Token:        accessToken,
-		RefreshToken: refreshToken, // also return in body for convenience (frontend should prefer cookie)
-		User:         user.ToDTO(),
-	})
+	c.JSON(http.StatusCreated, resp)
+	return nil
 }
 
-func Login(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
+func (h *AuthHandler) Login(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"handler": "Login",
 		"ip":      c.ClientIP(),
 	})
@@ -109,64 +112,65 @@ func Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.WithError(err).Warn("Invalid login request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return apierror.Bind(err)
 	}
 
 	log = log.WithField("email", req.Email)
 	log.Info("Processing login request")
 
+	ctx := c.Request.Context()
+
 	// Get user from database
-	var user models.User
-	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	user, err := h.users.FindByEmail(ctx, req.Email)
+	if err != nil {
 		log.Warn("Login failed: user not found")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
-		return
+		h.recordFailedLogin(c, req.Email, 0, "unknown_email")
+		return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or password")
 	}
 
 	// Check password
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
-	if err != nil {
+	if err := checkPassword(ctx, user.PasswordHash, req.Password); err != nil {
 		log.Warn("Login failed: invalid password")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
-		return
+		h.recordFailedLogin(c, req.Email, user.ID, "wrong_password")
+		return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or password")
 	}
 
 	log = log.WithField("user_id", user.ID)
 
-	// Create tokens
-	accessToken, err := generateAccessToken(user.ID, user.Email)
-	if err != nil {
-		log.WithError(err).Error("Failed to generate access token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
-		return
+	if user.DisabledAt != nil {
+		log.Warn("Login failed: account disabled")
+		h.recordFailedLogin(c, req.Email, user.ID, "account_disabled")
+		return apierror.New(http.StatusForbidden, apierror.CodeAccountDisabled, "Account disabled")
 	}
 
-	refreshToken, err := generateAndStoreRefreshToken(user.ID)
+	// Logging in during the deletion grace period cancels the deletion
+	if user.DeleteAfter != nil {
+		if err := h.users.Update(ctx, user, map[string]interface{}{"delete_after": nil}); err != nil {
+			log.WithError(err).Error("Failed to cancel scheduled account deletion")
+			return apierror.Internal(err, "Failed to cancel scheduled account deletion")
+		}
+		user.DeleteAfter = nil
+		log.Info("Scheduled account deletion cancelled by login")
+	}
+
+	resp, err := h.issueSession(c, *user)
 	if err != nil {
-		log.WithError(err).Error("Failed to generate refresh token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
-		return
+		log.WithError(err).Error("Failed to issue session")
+		return apierror.Internal(err, "Failed to generate tokens")
 	}
 
-	// Set refresh token as secure httpOnly cookie
-	cfg := config.Get()
-	maxAge := int(cfg.RefreshTokenTTL.Seconds())
-	secure := cfg.Environment == config.EnvProduction
-	c.SetCookie(cfg.RefreshTokenCookieName, refreshToken, maxAge, "/", "", secure, true)
+	h.record(c, audit.ActionLogin, user.ID, nil)
+	metrics.Logins.WithLabelValues(metrics.ResultSuccess).Inc()
 
 	log.Info("User logged in successfully")
 
-	c.JSON(http.StatusOK, models.AuthResponse{
-		Token:        accessToken,
-		RefreshToken: refreshToken, // included for convenience
-		User:         user.ToDTO(),
-	})
+	c.JSON(http.StatusOK, resp)
+	return nil
 }
 
 // Refresh exchanges a valid refresh token for a new access token and rotates the refresh token.
-func Refresh(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
+func (h *AuthHandler) Refresh(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"handler": "Refresh",
 		"ip":      c.ClientIP(),
 	})
@@ -181,112 +185,217 @@ func Refresh(c *gin.Context) {
 		}
 		if err := c.ShouldBindJSON(&body); err != nil || body.RefreshToken == "" {
 			log.Warn("Refresh failed: no refresh token provided")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token required"})
-			return
+			metrics.TokenRefreshes.WithLabelValues(metrics.ResultFailure).Inc()
+			return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Refresh token required")
 		}
 		rt = body.RefreshToken
 	}
 
-	hash := hashToken(rt)
-	var stored models.RefreshToken
-	if err := database.DB.Where("token_hash = ?", hash).First(&stored).Error; err != nil {
+	ctx := c.Request.Context()
+
+	stored, err := h.tokens.FindByHash(ctx, hashToken(rt))
+	if err != nil {
 		log.Warn("Refresh failed: invalid refresh token")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
-		return
+		metrics.TokenRefreshes.WithLabelValues(metrics.ResultFailure).Inc()
+		return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid refresh token")
 	}
 
 	log = log.WithField("user_id", stored.UserID)
 
 	if stored.Revoked || stored.ExpiresAt.Before(time.Now()) {
 		log.Warn("Refresh failed: token expired or revoked")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token expired or revoked"})
-		return
+		metrics.TokenRefreshes.WithLabelValues(metrics.ResultFailure).Inc()
+		return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Refresh token expired or revoked")
 	}
 
 	// Load user
-	var user models.User
-	if err := database.DB.First(&user, stored.UserID).Error; err != nil {
+	user, err := h.users.FindByID(ctx, stored.UserID)
+	if err != nil {
 		log.WithError(err).Error("Refresh failed: user not found")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
-		return
+		metrics.TokenRefreshes.WithLabelValues(metrics.ResultFailure).Inc()
+		return apierror.Internal(err, "Failed to load user")
+	}
+	if user.DisabledAt != nil {
+		log.Warn("Refresh failed: account disabled")
+		metrics.TokenRefreshes.WithLabelValues(metrics.ResultFailure).Inc()
+		return apierror.New(http.StatusForbidden, apierror.CodeAccountDisabled, "Account disabled")
 	}
 
 	// Revoke old refresh token (rotation)
-	stored.Revoked = true
-	if err := database.DB.Save(&stored).Error; err != nil {
+	if err := h.tokens.Revoke(ctx, stored); err != nil {
 		log.WithError(err).Warn("Failed to revoke old refresh token")
 	}
 
 	// Create new refresh token
-	newRT, err := generateAndStoreRefreshToken(user.ID)
+	newRT, err := h.newRefreshToken(c, user.ID)
 	if err != nil {
 		log.WithError(err).Error("Failed to generate new refresh token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate new refresh token"})
-		return
+		return apierror.Internal(err, "Failed to generate new refresh token")
 	}
 
-	// Set cookie
-	maxAge := int(cfg.RefreshTokenTTL.Seconds())
-	secure := cfg.Environment == config.EnvProduction
-	c.SetCookie(cfg.RefreshTokenCookieName, newRT, maxAge, "/", "", secure, true)
-
 	// Create new access token
-	accessToken, err := generateAccessToken(user.ID, user.Email)
+	accessToken, err := generateAccessToken(ctx, *user)
 	if err != nil {
 		log.WithError(err).Error("Failed to generate access token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
-		return
+		return apierror.Internal(err, "Failed to generate access token")
 	}
 
+	h.record(c, audit.ActionRefresh, user.ID, nil)
+	metrics.TokenRefreshes.WithLabelValues(metrics.ResultSuccess).Inc()
+
 	log.Info("Token refreshed successfully")
 
 	c.JSON(http.StatusOK, gin.H{
 		"token":         accessToken,
 		"refresh_token": newRT,
 	})
+	return nil
 }
 
-// Logout revokes the refresh token (if present) and clears the cookie.
-func Logout(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
+// Logout revokes the refresh token of the cookie or, like Refresh, of the
+// JSON body, if any, and clears the cookie. A token that doesn't exist is
+// ignored; a failure to revoke one is an error, so the client knows the
+// session may still be valid.
+func (h *AuthHandler) Logout(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"handler": "Logout",
 		"ip":      c.ClientIP(),
 	})
 
 	// Try to get user_id from context if available (from AuthMiddleware)
-	if userID, exists := c.Get("user_id"); exists {
+	userID := c.GetInt64("user_id")
+	if userID != 0 {
 		log = log.WithField("user_id", userID)
 	}
 
 	cfg := config.Get()
 	rt, err := c.Cookie(cfg.RefreshTokenCookieName)
-	if err == nil && rt != "" {
-		hash := hashToken(rt)
-		var stored models.RefreshToken
-		if err := database.DB.Where("token_hash = ?", hash).First(&stored).Error; err == nil {
-			stored.Revoked = true
-			if err := database.DB.Save(&stored).Error; err != nil {
-				log.WithError(err).Warn("Failed to revoke refresh token during logout")
-			} else {
-				log.Debug("Refresh token revoked")
+	if err != nil || rt == "" {
+		// fallback to JSON body; a logout without a token is fine
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := c.ShouldBindJSON(&body); err == nil {
+			rt = body.RefreshToken
+		}
+	}
+	if rt != "" {
+		ctx := c.Request.Context()
+		stored, err := h.tokens.FindByHash(ctx, hashToken(rt))
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			log.Debug("Unknown refresh token")
+		case err != nil:
+			log.WithError(err).Error("Failed to look up refresh token during logout")
+			return apierror.Internal(err, "Failed to revoke refresh token")
+		default:
+			userID = stored.UserID
+			if err := h.tokens.Revoke(ctx, stored); err != nil {
+				log.WithError(err).Error("Failed to revoke refresh token during logout")
+				return apierror.Internal(err, "Failed to revoke refresh token")
 			}
+			log.Debug("Refresh token revoked")
 		}
 	}
 
+	// A logout without a session has nobody to attribute it to
+	if userID != 0 {
+		h.record(c, audit.ActionLogout, userID, nil)
+	}
+
 	// Clear cookie
 	c.SetCookie(cfg.RefreshTokenCookieName, "", -1, "/", "", cfg.Environment == config.EnvProduction, true)
 
 	log.Info("User logged out successfully")
 
 	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+	return nil
+}
+
+// record adds an event about the account of userID to the audit log. The
+// user is also the actor: these endpoints only act on the caller's account.
+func (h *AuthHandler) record(c *gin.Context, action string, userID int64, after map[string]interface{}) {
+	h.audit.Record(auditContext(c), models.AuditEvent{
+		ActorID: audit.ID(userID),
+		UserID:  audit.ID(userID),
+		Action:  action,
+		After:   after,
+	})
+}
+
+// recordFailedLogin adds a failed login to the audit log and the metrics.
+// userID is 0 when no account has the email.
+func (h *AuthHandler) recordFailedLogin(c *gin.Context, email string, userID int64, reason string) {
+	metrics.Logins.WithLabelValues(metrics.ResultFailure).Inc()
+
+	event := models.AuditEvent{
+		Action: audit.ActionLoginFailed,
+		After:  map[string]interface{}{"email": email, "reason": reason},
+	}
+	if userID != 0 {
+		event.UserID = audit.ID(userID)
+	}
+	h.audit.Record(auditContext(c), event)
+}
+
+// issueSession creates an access token and a refresh token for user, sets the
+// refresh cookie and returns the response body shared by all auth endpoints.
+func (h *AuthHandler) issueSession(c *gin.Context, user models.User) (models.AuthResponse, error) {
+	accessToken, err := generateAccessToken(c.Request.Context(), user)
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("generate access token: %w", err)
+	}
+
+	refreshToken, err := h.newRefreshToken(c, user.ID)
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	return models.AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken, // also return in body for convenience (frontend should prefer cookie)
+		User:         user.ToDTO(),
+	}, nil
 }
 
-func generateAccessToken(userID int64, email string) (string, error) {
+// newRefreshToken creates a random refresh token, stores its hash, sets it
+// as a secure httpOnly cookie and returns the raw token.
+func (h *AuthHandler) newRefreshToken(c *gin.Context, userID int64) (_ string, err error) {
+	ctx, span := tracing.Start(c.Request.Context(), "auth.refresh_token")
+	defer func() { tracing.End(span, err) }()
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	cfg := config.Get()
+	rt := models.RefreshToken{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(cfg.RefreshTokenTTL),
+	}
+	if err := h.tokens.Create(ctx, &rt); err != nil {
+		return "", err
+	}
+
+	maxAge := int(cfg.RefreshTokenTTL.Seconds())
+	secure := cfg.Environment == config.EnvProduction
+	c.SetCookie(cfg.RefreshTokenCookieName, token, maxAge, "/", "", secure, true)
+
+	return token, nil
+}
+
+func generateAccessToken(ctx context.Context, user models.User) (_ string, err error) {
+	_, span := tracing.Start(ctx, "auth.access_token")
+	defer func() { tracing.End(span, err) }()
+
 	cfg := config.Get()
 	expirationTime := time.Now().Add(cfg.AccessTokenTTL)
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID: user.ID,
+		Email:  user.Email,
+		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -297,29 +406,13 @@ func generateAccessToken(userID int64, email string) (string, error) {
 	return token.SignedString([]byte(cfg.JWTSecret))
 }
 
-// generateAndStoreRefreshToken creates a random refresh token, stores its hash and returns the raw token.
-func generateAndStoreRefreshToken(userID int64) (string, error) {
-	// generate 64 random bytes
+// randomToken returns 64 random bytes, base64 encoded to make it URL-safe.
+func randomToken() (string, error) {
 	raw := make([]byte, 64)
 	if _, err := rand.Read(raw); err != nil {
 		return "", err
 	}
-	// base64 encode to make it URL-safe
-	token := base64.RawURLEncoding.EncodeToString(raw)
-	hash := hashToken(token)
-
-	cfg := config.Get()
-	rt := models.RefreshToken{
-		TokenHash: hash,
-		UserID:    userID,
-		ExpiresAt: time.Now().Add(cfg.RefreshTokenTTL),
-	}
-
-	if err := database.DB.Create(&rt).Error; err != nil {
-		return "", err
-	}
-
-	return token, nil
+	return base64.RawURLEncoding.EncodeToString(raw), nil
 }
 
 func hashToken(t string) string {
@@ -327,9 +420,12 @@ func hashToken(t string) string {
 	return hex.EncodeToString(h[:])
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts requests with a valid access token of an enabled
+// user. The user is looked up on each request, so disabling an account
+// takes effect at once rather than when its access tokens expire.
+func AuthMiddleware(users repository.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log := logger.WithFields(logrus.Fields{
+		log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"middleware": "AuthMiddleware",
 			"path":       c.Request.URL.Path,
 			"ip":         c.ClientIP(),
@@ -339,8 +435,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
 			log.Warn("Authorization header missing")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			apierror.Abort(c, apierror.Unauthenticated("Authorization header required"))
 			return
 		}
 
@@ -356,18 +451,93 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		if err != nil || !token.Valid {
 			log.WithError(err).Warn("Invalid token")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			apierror.Abort(c, apierror.Unauthenticated("Invalid token"))
 			return
 		}
 
+		user, err := users.FindByID(c.Request.Context(), claims.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			log.WithField("user_id", claims.UserID).Warn("Token of a deleted user")
+			apierror.Abort(c, apierror.Unauthenticated("Invalid token"))
+			return
+		}
+		if err != nil {
+			log.WithError(err).Error("Failed to load user")
+			apierror.Abort(c, apierror.Internal(err, "Failed to authenticate"))
+			return
+		}
+		if user.DisabledAt != nil {
+			log.WithField("user_id", claims.UserID).Warn("Request of a disabled account")
+			apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeAccountDisabled, "Account disabled"))
+			return
+		}
+
+		// Tokens issued before roles existed carry none
+		if claims.Role == "" {
+			claims.Role = models.RoleUser
+		}
+
 		log.WithFields(logrus.Fields{
 			"user_id": claims.UserID,
 			"email":   claims.Email,
+			"role":    claims.Role,
 		}).Debug("Token validated successfully")
 
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Next()
+	}
+}
+
+// RequireRole allows the request only for users with one of roles. It runs
+// after AuthMiddleware. The role in the access token is checked first and
+// then confirmed against the database, so a demoted or disabled user loses
+// access at once rather than when the token expires.
+func RequireRole(users repository.UserRepository, roles ...string) gin.HandlerFunc {
+	allowed := func(role string) bool {
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(c *gin.Context) {
+		log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"middleware": "RequireRole",
+			"path":       c.Request.URL.Path,
+			"ip":         c.ClientIP(),
+		})
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			log.Warn("User not authenticated")
+			apierror.Abort(c, apierror.Unauthenticated("User not authenticated"))
+			return
+		}
+		log = log.WithField("user_id", userID)
+
+		if !allowed(c.GetString("role")) {
+			log.WithField("role", c.GetString("role")).Warn("Access denied: missing role")
+			apierror.Abort(c, apierror.Forbidden("Insufficient permissions"))
+			return
+		}
+
+		user, err := users.FindByID(c.Request.Context(), userID.(int64))
+		if err != nil {
+			log.WithError(err).Warn("Access denied: user not found")
+			apierror.Abort(c, apierror.Forbidden("Insufficient permissions"))
+			return
+		}
+		if !allowed(user.Role) || user.DisabledAt != nil {
+			log.WithField("role", user.Role).Warn("Access denied: role revoked or account disabled")
+			apierror.Abort(c, apierror.Forbidden("Insufficient permissions"))
+			return
+		}
+
+		c.Set("role", user.Role)
 		c.Next()
 	}
 }
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// BatchNotes applies one operation to many notes in a single transaction.
+// Ownership of every ID is checked before anything is changed. In atomic
+// mode (the default) any failure rolls back the whole batch; in partial mode
+// each note is applied in its own savepoint and failures are reported per ID.
+func (h *NoteHandler) BatchNotes(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "BatchNotes",
+		"ip":      c.ClientIP(),
+	})
+
+	var req models.BatchNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid batch request")
+		return apierror.Bind(err)
+	}
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"operation": req.Operation,
+		"mode":      req.Mode,
+		"count":     len(req.IDs),
+	})
+
+	resp, failures, err := h.notes.Batch(auditContext(c), userID.(int64), req)
+	if err != nil {
+		return noteError(log, err, "Failed to run batch operation")
+	}
+	for id, err := range failures {
+		log.WithError(err).WithField("note_id", id).Warn("Batch operation failed for note")
+	}
+
+	log.WithFields(logrus.Fields{
+		"succeeded":   resp.Succeeded,
+		"failed":      resp.Failed,
+		"rolled_back": resp.RolledBack,
+	}).Info("Batch operation completed")
+
+	status := http.StatusOK
+	if resp.RolledBack {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, resp)
+	return nil
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/export"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+	"github.com/tgogbera/google_keep_clone-backend/internal/service"
+)
+
+// noteSource implements export.Source for one user's notes.
+type noteSource struct {
+	ctx    context.Context
+	notes  *service.NoteService
+	userID int64
+	filter *repository.NoteFilter // nil exports every note
+}
+
+func (s noteSource) EachNote(fn func(note models.Note) error) error {
+	return s.notes.Each(s.ctx, s.userID, s.filter, fn)
+}
+
+func (s noteSource) AttachmentData(id int64) ([]byte, error) {
+	attachment, err := s.notes.Attachment(s.ctx, s.userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return attachment.Data, nil
+}
+
+// exportFormats maps the format parameter to the writer, content type and file extension.
+var exportFormats = map[string]struct {
+	write       func(w io.Writer, src export.Source) error
+	contentType string
+	ext         string
+}{
+	export.FormatMarkdown: {export.Markdown, "application/zip", "zip"},
+	export.FormatJSON:     {export.JSON, "application/zip", "zip"},
+	export.FormatENEX:     {export.ENEX, "application/enex+xml", "enex"},
+}
+
+// ExportNotes streams the user's notes as a Markdown or JSON archive or as an
+// Evernote ENEX file. It accepts the same filters as GetAllNotes; without
+// any, every note is exported, archived and trashed ones included.
+func (h *NoteHandler) ExportNotes(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "ExportNotes",
+		"ip":      c.ClientIP(),
+	})
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	formatName := c.DefaultQuery("format", export.FormatMarkdown)
+	format, ok := exportFormats[formatName]
+	if !ok {
+		log.WithField("format", formatName).Warn("Unsupported export format")
+		return apierror.BadRequest("format must be markdown, json or enex")
+	}
+
+	log = log.WithField("format", formatName)
+
+	filter, err := parseNoteFilter(c)
+	if err != nil {
+		log.WithError(err).Warn("Invalid note filter")
+		return err
+	}
+	if !hasNoteFilter(c) {
+		// A full export must not lose the notes the listing hides by default
+		filter.Archived, filter.Trashed = nil, nil
+	}
+
+	filename := fmt.Sprintf("notes-%s-%s.%s", formatName, time.Now().UTC().Format("20060102"), format.ext)
+	c.Header("Content-Type", format.contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// From here on the response is committed; errors can only be logged
+	src := noteSource{ctx: c.Request.Context(), notes: h.notes, userID: userID.(int64), filter: &filter}
+	if err := format.write(c.Writer, src); err != nil {
+		log.WithError(err).Error("Failed to export notes")
+		return nil
+	}
+
+	log.Info("Notes exported")
+	return nil
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+	"github.com/tgogbera/google_keep_clone-backend/internal/service"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	if err := config.Load(config.Options{Flags: map[string]string{"auth.jwt_secret": "test"}}); err != nil {
+		panic(err)
+	}
+	if err := RegisterValidators(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// memoryHandlers are handlers on the in-memory repositories.
+type memoryHandlers struct {
+	users    *repository.MemoryUserRepository
+	emails   *repository.MemoryEmailChangeRepository
+	events   *repository.MemoryAuditRepository
+	notes    *NoteHandler
+	accounts *AccountHandler
+}
+
+func newMemoryHandlers() *memoryHandlers {
+	h := &memoryHandlers{
+		users:  repository.NewMemoryUserRepository(),
+		events: repository.NewMemoryAuditRepository(),
+	}
+	h.emails = repository.NewMemoryEmailChangeRepository(h.users)
+	auditLog := audit.New(h.events)
+	notes := service.NewNoteService(repository.NewMemoryNoteRepository(), h.users, auditLog)
+	h.notes = NewNoteHandler(notes)
+	h.accounts = NewAccountHandler(h.users, repository.NewMemoryTokenRepository(), h.emails, notes, auditLog)
+	return h
+}
+
+// user creates a user with email and returns its ID.
+func (h *memoryHandlers) user(t *testing.T, email string) int64 {
+	t.Helper()
+	user := models.User{Email: email, PasswordHash: "x"}
+	if err := h.users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+// call runs handler for a request by userID (0 for none) with body encoded
+// as JSON, routed by pattern, and decodes the response into out, if given.
+func call(t *testing.T, handler func(*gin.Context) error, method, pattern, path string, userID int64, body, out interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.Use(apierror.Middleware())
+	router.Handle(method, pattern, func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+	}, apierror.Handler(handler))
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+// problemCode returns the code of a problem document response.
+func problemCode(t *testing.T, rec *httptest.ResponseRecorder) apierror.Code {
+	t.Helper()
+	var problem apierror.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body.String(), err)
+	}
+	return problem.Code
+}
+
+func TestNoteHandlerOwnership(t *testing.T) {
+	h := newMemoryHandlers()
+	alice, bob := h.user(t, "alice@example.com"), h.user(t, "bob@example.com")
+
+	var note models.Note
+	rec := call(t, h.notes.CreateNote, http.MethodPost, "/notes", "/notes", alice, map[string]interface{}{"title": "Mine"}, &note)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+
+	path := "/notes/" + strconv.FormatInt(note.ID, 10)
+	rec = call(t, h.notes.GetNote, http.MethodGet, "/notes/:id", path, bob, nil, nil)
+	if rec.Code != http.StatusNotFound || problemCode(t, rec) != apierror.CodeNotFound {
+		t.Errorf("get by another user: status %d: %s", rec.Code, rec.Body)
+	}
+	rec = call(t, h.notes.UpdateNote, http.MethodPut, "/notes/:id", path, bob, map[string]interface{}{"title": "Taken"}, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("update by another user: status %d: %s", rec.Code, rec.Body)
+	}
+	rec = call(t, h.notes.DeleteNote, http.MethodDelete, "/notes/:id", path, bob, nil, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("delete by another user: status %d: %s", rec.Code, rec.Body)
+	}
+
+	var got models.Note
+	if rec := call(t, h.notes.GetNote, http.MethodGet, "/notes/:id", path, alice, nil, &got); rec.Code != http.StatusOK || got.Title != "Mine" {
+		t.Errorf("get by the owner: status %d, title %q", rec.Code, got.Title)
+	}
+}
+
+func TestNoteHandlerValidation(t *testing.T) {
+	h := newMemoryHandlers()
+	alice := h.user(t, "alice@example.com")
+
+	var note models.Note
+	call(t, h.notes.CreateNote, http.MethodPost, "/notes", "/notes", alice, map[string]interface{}{"title": "Mine"}, &note)
+	path := "/notes/" + strconv.FormatInt(note.ID, 10)
+
+	var problem apierror.Problem
+	rec := call(t, h.notes.UpdateNote, http.MethodPut, "/notes/:id", path, alice, map[string]interface{}{"color": "chartreuse"}, &problem)
+	if rec.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "color" {
+		t.Errorf("invalid color: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = call(t, h.notes.GetNote, http.MethodGet, "/notes/:id", "/notes/abc", alice, nil, nil)
+	if rec.Code != http.StatusBadRequest || problemCode(t, rec) != apierror.CodeInvalidRequest {
+		t.Errorf("invalid ID: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = call(t, h.notes.CreateNote, http.MethodPost, "/notes", "/notes", 0, map[string]interface{}{"title": "Anonymous"}, nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("create without a user: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestNoteHandlerBatch(t *testing.T) {
+	h := newMemoryHandlers()
+	alice, bob := h.user(t, "alice@example.com"), h.user(t, "bob@example.com")
+
+	var mine, theirs models.Note
+	call(t, h.notes.CreateNote, http.MethodPost, "/notes", "/notes", alice, map[string]interface{}{"title": "Mine"}, &mine)
+	call(t, h.notes.CreateNote, http.MethodPost, "/notes", "/notes", bob, map[string]interface{}{"title": "Theirs"}, &theirs)
+
+	var resp models.BatchNotesResponse
+	rec := call(t, h.notes.BatchNotes, http.MethodPost, "/notes/batch", "/notes/batch", alice, models.BatchNotesRequest{
+		IDs: []int64{mine.ID, theirs.ID}, Operation: models.BatchPin,
+	}, &resp)
+	if rec.Code != http.StatusUnprocessableEntity || !resp.RolledBack {
+		t.Errorf("atomic batch with another user's note: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = call(t, h.notes.BatchNotes, http.MethodPost, "/notes/batch", "/notes/batch", alice, models.BatchNotesRequest{
+		IDs: []int64{mine.ID}, Operation: models.BatchColor,
+	}, nil)
+	if rec.Code != http.StatusBadRequest || problemCode(t, rec) != apierror.CodeValidationFailed {
+		t.Errorf("color batch without a color: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = call(t, h.notes.BatchNotes, http.MethodPost, "/notes/batch", "/notes/batch", alice, models.BatchNotesRequest{
+		IDs: []int64{mine.ID}, Operation: models.BatchPin,
+	}, &resp)
+	if rec.Code != http.StatusOK || resp.Succeeded != 1 {
+		t.Errorf("batch of own notes: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAccountHandlerProfile(t *testing.T) {
+	h := newMemoryHandlers()
+	alice := h.user(t, "alice@example.com")
+
+	var me models.UserDTO
+	rec := call(t, h.accounts.UpdateMe, http.MethodPatch, "/me", "/me", alice, map[string]interface{}{
+		"display_name": "  Alice ",
+		"locale":       "de-ch",
+	}, &me)
+	if rec.Code != http.StatusOK || me.DisplayName != "Alice" || me.Locale != "de-CH" {
+		t.Errorf("update: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = call(t, h.accounts.UpdateMe, http.MethodPatch, "/me", "/me", alice, map[string]interface{}{"timezone": "Mars/Olympus"}, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid timezone: status %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := call(t, h.accounts.GetMe, http.MethodGet, "/me", "/me", alice, nil, &me); rec.Code != http.StatusOK || me.DisplayName != "Alice" {
+		t.Errorf("get: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := call(t, h.accounts.GetMe, http.MethodGet, "/me", "/me", alice+100, nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("get of a missing user: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAccountHandlerConfirmEmailChange(t *testing.T) {
+	h := newMemoryHandlers()
+	ctx := context.Background()
+	alice := h.user(t, "alice@example.com")
+	h.user(t, "taken@example.com")
+
+	change := func(email, token string, ttl time.Duration) {
+		t.Helper()
+		if err := h.emails.Replace(ctx, &models.EmailChange{
+			UserID: alice, NewEmail: email, TokenHash: hashToken(token), ExpiresAt: time.Now().Add(ttl),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	confirm := func(token string, out interface{}) *httptest.ResponseRecorder {
+		return call(t, h.accounts.ConfirmEmailChange, http.MethodPost, "/email/confirm", "/email/confirm", 0,
+			map[string]string{"token": token}, out)
+	}
+
+	change("taken@example.com", "taken-token", time.Hour)
+	if rec := confirm("taken-token", nil); rec.Code != http.StatusConflict || problemCode(t, rec) != apierror.CodeEmailTaken {
+		t.Errorf("taken address: status %d: %s", rec.Code, rec.Body)
+	}
+
+	change("new@example.com", "expired-token", -time.Minute)
+	if rec := confirm("expired-token", nil); rec.Code != http.StatusBadRequest || problemCode(t, rec) != apierror.CodeInvalidToken {
+		t.Errorf("expired token: status %d: %s", rec.Code, rec.Body)
+	}
+
+	change("new@example.com", "good-token", time.Hour)
+	var me models.UserDTO
+	if rec := confirm("good-token", &me); rec.Code != http.StatusOK || me.Email != "new@example.com" {
+		t.Errorf("confirm: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := confirm("good-token", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("token used twice: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAuthMiddlewareRejectsDisabledUsers(t *testing.T) {
+	h := newMemoryHandlers()
+	ctx := context.Background()
+	alice := h.user(t, "alice@example.com")
+	user, err := h.users.FindByID(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := generateAccessToken(ctx, *user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(apierror.Middleware())
+	router.GET("/me", AuthMiddleware(h.users), apierror.Handler(h.accounts.GetMe))
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get(token); rec.Code != http.StatusOK {
+		t.Fatalf("enabled user: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := get("not-a-token"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token: status %d: %s", rec.Code, rec.Body)
+	}
+
+	if err := h.users.Update(ctx, user, map[string]interface{}{"disabled_at": time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get(token); rec.Code != http.StatusForbidden || problemCode(t, rec) != apierror.CodeAccountDisabled {
+		t.Errorf("token issued before the user was disabled: status %d: %s", rec.Code, rec.Body)
+	}
+}
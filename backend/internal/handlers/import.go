@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/importer"
+	"github.com/tgogbera/google_keep_clone-backend/internal/jobs"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+)
+
+// staleImportAfter is how long an unfinished import may go without progress
+// before another import is allowed.
+const staleImportAfter = 15 * time.Minute
+
+// ImportHandler starts background imports and reports their progress.
+type ImportHandler struct {
+	imports  repository.ImportJobRepository
+	importer *importer.Importer
+}
+
+// NewImportHandler returns an ImportHandler storing jobs in imports and
+// running them with im.
+func NewImportHandler(imports repository.ImportJobRepository, im *importer.Importer) *ImportHandler {
+	return &ImportHandler{imports: imports, importer: im}
+}
+
+// ImportKeep accepts a Google Keep Takeout ZIP (multipart field "file") and
+// imports it in the background. The response is the job to poll for progress.
+func (h *ImportHandler) ImportKeep(c *gin.Context) error {
+	return h.startImport(c, "ImportKeep", importer.SourceGoogleKeep)
+}
+
+// ImportJSON accepts an archive produced by the JSON export
+// (GET /api/notes/export?format=json) and imports it like ImportKeep.
+func (h *ImportHandler) ImportJSON(c *gin.Context) error {
+	return h.startImport(c, "ImportJSON", importer.SourceJSON)
+}
+
+// startImport stores the uploaded archive and starts a background job for source.
+func (h *ImportHandler) startImport(c *gin.Context, handler, source string) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": handler,
+		"ip":      c.ClientIP(),
+	})
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	// One import at a time per user keeps duplicate uploads from doubling notes.
+	// Jobs without progress for a while were interrupted (e.g. by a restart).
+	// This check spares reading the upload; Start repeats it atomically.
+	since := time.Now().Add(-staleImportAfter)
+	running, err := h.imports.Active(c.Request.Context(), userID.(int64), since)
+	if err != nil {
+		log.WithError(err).Error("Failed to check running imports")
+		return apierror.Internal(err, "Failed to start import")
+	}
+	if running {
+		log.Warn("Import refused: another import is in progress")
+		return apierror.New(http.StatusConflict, apierror.CodeImportInProgress, "Another import is already in progress")
+	}
+
+	cfg := config.Get()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.ImportMaxBytes)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			log.Warn("Import refused: archive too large")
+			return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "Archive is too large")
+		}
+		log.WithError(err).Warn("Invalid import request")
+		return apierror.BadRequest("A ZIP archive must be uploaded in the \"file\" field")
+	}
+
+	tmp, err := os.CreateTemp("", "notes-import-*.zip")
+	if err != nil {
+		log.WithError(err).Error("Failed to create temporary file")
+		return apierror.Internal(err, "Failed to store archive")
+	}
+	archivePath := tmp.Name()
+	tmp.Close()
+
+	if err := c.SaveUploadedFile(fileHeader, archivePath); err != nil {
+		os.Remove(archivePath)
+		log.WithError(err).Error("Failed to store uploaded archive")
+		return apierror.Internal(err, "Failed to store archive")
+	}
+
+	// Reject non-archives right away instead of failing in the background
+	f, err := os.Open(archivePath)
+	if err == nil {
+		_, err = importer.OpenArchive(source, f, fileHeader.Size)
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(archivePath)
+		log.WithError(err).Warn("Uploaded file is not a valid archive")
+		return apierror.BadRequest("Uploaded file is not a valid archive: " + err.Error())
+	}
+
+	job := models.ImportJob{
+		UserID: userID.(int64),
+		Source: source,
+		Status: models.ImportStatusPending,
+	}
+	if err := h.imports.Start(c.Request.Context(), &job, since); err != nil {
+		os.Remove(archivePath)
+		if errors.Is(err, repository.ErrImportInProgress) {
+			log.Warn("Import refused: another import started meanwhile")
+			return apierror.New(http.StatusConflict, apierror.CodeImportInProgress, "Another import is already in progress")
+		}
+		log.WithError(err).Error("Failed to create import job")
+		return apierror.Internal(err, "Failed to start import")
+	}
+
+	// The worker gets its own copy so the response below doesn't race with
+	// it. On shutdown it is interrupted and the job marked failed.
+	worker := job
+	if !jobs.Go(func(ctx context.Context) { h.importer.Run(ctx, &worker, archivePath) }) {
+		os.Remove(archivePath)
+		if err := h.imports.Fail(c.Request.Context(), &job, "server shutting down"); err != nil {
+			log.WithError(err).Warn("Failed to mark import job as failed")
+		}
+		log.Warn("Import refused: server is shutting down")
+		return apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, "Server is shutting down, try again")
+	}
+
+	log.WithFields(logrus.Fields{
+		"job_id": job.ID,
+		"bytes":  fileHeader.Size,
+	}).Info("Import started")
+
+	c.JSON(http.StatusAccepted, job)
+	return nil
+}
+
+// ListImportJobs returns the user's import jobs, newest first, without reports.
+func (h *ImportHandler) ListImportJobs(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "ListImportJobs",
+		"ip":      c.ClientIP(),
+	})
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	jobs, err := h.imports.List(c.Request.Context(), userID.(int64))
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve import jobs")
+		return apierror.Internal(err, "Failed to retrieve import jobs")
+	}
+
+	c.JSON(http.StatusOK, jobs)
+	return nil
+}
+
+// GetImportJob returns the progress of an import job and its per-note report.
+func (h *ImportHandler) GetImportJob(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "GetImportJob",
+		"ip":      c.ClientIP(),
+	})
+
+	jobID, err := idParam(c, "id", "job")
+	if err != nil {
+		log.WithField("job_id_str", c.Param("id")).Warn("Invalid job ID format")
+		return err
+	}
+
+	log = log.WithField("job_id", jobID)
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	job, err := h.imports.Get(c.Request.Context(), userID.(int64), jobID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn("Import job not found or does not belong to user")
+		return apierror.NotFound("Import job not found")
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve import job")
+		return apierror.Internal(err, "Failed to retrieve import job")
+	}
+
+	c.JSON(http.StatusOK, job)
+	return nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+)
+
+// parseNoteFilter reads q, label, color, pinned, archived, trashed and sort from
+// the query string. archived and trashed default to false and accept "any";
+// sort defaults to created. Errors are *apierror.Error.
+func parseNoteFilter(c *gin.Context) (repository.NoteFilter, error) {
+	no := false
+	filter := repository.NoteFilter{
+		Query:    strings.TrimSpace(c.Query("q")),
+		Label:    c.Query("label"),
+		Color:    c.Query("color"),
+		Archived: &no,
+		Trashed:  &no,
+		Sort:     c.DefaultQuery("sort", repository.SortCreated),
+	}
+
+	if filter.Color != "" && !models.IsNoteColor(filter.Color) {
+		return filter, apierror.Invalid("color", "note_color", "must be a note color")
+	}
+	if !repository.IsNoteSort(filter.Sort) {
+		return filter, apierror.Invalid("sort", "oneof", "must be one of: created, updated, title, manual")
+	}
+
+	var err error
+	if filter.Pinned, err = queryBool(c, "pinned", nil); err != nil {
+		return filter, err
+	}
+	if filter.Archived, err = queryBool(c, "archived", filter.Archived); err != nil {
+		return filter, err
+	}
+	if filter.Trashed, err = queryBool(c, "trashed", filter.Trashed); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// noteFilterParams are the query parameters of parseNoteFilter that select
+// notes, as opposed to sort.
+var noteFilterParams = []string{"q", "label", "color", "pinned", "archived", "trashed"}
+
+// hasNoteFilter reports whether any of noteFilterParams is set.
+func hasNoteFilter(c *gin.Context) bool {
+	for _, key := range noteFilterParams {
+		if _, ok := c.GetQuery(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// queryBool parses a boolean query parameter; "any" yields nil.
+func queryBool(c *gin.Context, key string, fallback *bool) (*bool, error) {
+	raw, ok := c.GetQuery(key)
+	if !ok || raw == "" {
+		return fallback, nil
+	}
+	if raw == "any" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, apierror.Invalid(key, "oneof", "must be one of: true, false, any")
+	}
+	return &v, nil
+}
@@ -1,18 +1,30 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
 	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
 	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/service"
 )
 
-func CreateNote(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
+// NoteHandler serves the note endpoints. The rules live in service.NoteService.
+type NoteHandler struct {
+	notes *service.NoteService
+}
+
+// NewNoteHandler returns a NoteHandler using notes.
+func NewNoteHandler(notes *service.NoteService) *NoteHandler {
+	return &NoteHandler{notes: notes}
+}
+
+func (h *NoteHandler) CreateNote(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"handler": "CreateNote",
 		"ip":      c.ClientIP(),
 	})
@@ -20,40 +32,32 @@ func CreateNote(c *gin.Context) {
 	var req models.CreateNoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.WithError(err).Warn("Invalid create note request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return apierror.Bind(err)
 	}
 
 	// Get user ID from context (set by AuthMiddleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
 		log.Warn("User not authenticated")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
+		return apierror.Unauthenticated("User not authenticated")
 	}
 
 	log = log.WithField("user_id", userID)
 
-	// Create note
-	note := models.Note{
-		Title:   req.Title,
-		Content: req.Content,
-		UserID:  userID.(int64),
-	}
-
-	if err := database.DB.Create(&note).Error; err != nil {
+	note, err := h.notes.Create(auditContext(c), userID.(int64), req)
+	if err != nil {
 		log.WithError(err).Error("Failed to create note")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
-		return
+		return apierror.Internal(err, "Failed to create note")
 	}
 
 	log.WithField("note_id", note.ID).Info("Note created successfully")
 
 	c.JSON(http.StatusCreated, note)
+	return nil
 }
 
-func GetAllNotes(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
+func (h *NoteHandler) GetAllNotes(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"handler": "GetAllNotes",
 		"ip":      c.ClientIP(),
 	})
@@ -62,111 +66,143 @@ func GetAllNotes(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		log.Warn("User not authenticated")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
+		return apierror.Unauthenticated("User not authenticated")
 	}
 
 	log = log.WithField("user_id", userID)
 
-	var notes []models.Note
-	if err := database.DB.Where("user_id = ?", userID.(int64)).Order("created_at DESC").Find(&notes).Error; err != nil {
+	filter, err := parseNoteFilter(c)
+	if err != nil {
+		log.WithError(err).Warn("Invalid note filter")
+		return err
+	}
+
+	notes, err := h.notes.List(c.Request.Context(), userID.(int64), filter)
+	if err != nil {
 		log.WithError(err).Error("Failed to retrieve notes")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notes"})
-		return
+		return apierror.Internal(err, "Failed to retrieve notes")
 	}
 
 	log.WithField("count", len(notes)).Debug("Notes retrieved successfully")
 
 	c.JSON(http.StatusOK, notes)
+	return nil
 }
 
-func UpdateNote(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
-		"handler": "UpdateNote",
+// GetNote returns a single note. The expand query parameter is a
+// comma-separated list of related data to include: labels, attachments,
+// checklist_items, revision_count.
+func (h *NoteHandler) GetNote(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "GetNote",
 		"ip":      c.ClientIP(),
 	})
 
-	// Get note ID from URL parameter
-	noteIDStr := c.Param("id")
-	noteID, err := strconv.ParseInt(noteIDStr, 10, 64)
+	userID, noteID, log, err := noteParams(c, log)
 	if err != nil {
-		log.WithField("note_id_str", noteIDStr).Warn("Invalid note ID format")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
-		return
+		return err
 	}
 
-	log = log.WithField("note_id", noteID)
-
-	// Get user ID from context (set by AuthMiddleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		log.Warn("User not authenticated")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
+	note, err := h.notes.Get(c.Request.Context(), userID, noteID, c.Query("expand"))
+	if err != nil {
+		return noteError(log, err, "Failed to load note")
 	}
 
-	log = log.WithField("user_id", userID)
+	log.Debug("Note retrieved successfully")
 
-	// Check if note exists and belongs to user
-	var note models.Note
-	if err := database.DB.Where("id = ? AND user_id = ?", noteID, userID.(int64)).First(&note).Error; err != nil {
-		log.Warn("Note not found or does not belong to user")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
-		return
+	c.JSON(http.StatusOK, note)
+	return nil
+}
+
+func (h *NoteHandler) UpdateNote(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "UpdateNote",
+		"ip":      c.ClientIP(),
+	})
+
+	userID, noteID, log, err := noteParams(c, log)
+	if err != nil {
+		return err
 	}
 
 	// Read raw JSON to check which fields are provided
 	var jsonData map[string]interface{}
 	if err := c.ShouldBindJSON(&jsonData); err != nil {
 		log.WithError(err).Warn("Invalid update request body")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return apierror.Bind(err)
 	}
 
-	// Build updates map - only include fields that are present in JSON
-	updates := make(map[string]interface{})
-	if title, exists := jsonData["title"]; exists {
-		updates["title"] = title
-	}
-	if content, exists := jsonData["content"]; exists {
-		updates["content"] = content
+	note, err := h.notes.Update(auditContext(c), userID, noteID, jsonData)
+	if err != nil {
+		return noteError(log, err, "Failed to update note")
 	}
 
-	// Check if at least one field is being updated
-	if len(updates) == 0 {
-		log.Warn("No fields provided for update")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one field (title or content) must be provided"})
-		return
-	}
+	log.Info("Note updated successfully")
 
-	// Apply updates
-	if err := database.DB.Model(&note).Updates(updates).Error; err != nil {
-		log.WithError(err).Error("Failed to update note")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
-		return
+	c.JSON(http.StatusOK, note)
+	return nil
+}
+
+func (h *NoteHandler) DeleteNote(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "DeleteNote",
+		"ip":      c.ClientIP(),
+	})
+
+	userID, noteID, log, err := noteParams(c, log)
+	if err != nil {
+		return err
 	}
 
-	// Reload note to get updated values
-	database.DB.First(&note, note.ID)
+	// Delete note together with its label links and attachments
+	if err := h.notes.Delete(auditContext(c), userID, noteID); err != nil {
+		return noteError(log, err, "Failed to delete note")
+	}
 
-	log.Info("Note updated successfully")
+	log.Info("Note deleted successfully")
 
-	c.JSON(http.StatusOK, note)
+	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
+	return nil
 }
 
-func DeleteNote(c *gin.Context) {
-	log := logger.WithFields(logrus.Fields{
-		"handler": "DeleteNote",
+// MoveNote places a note in the manual order (sort=manual) after the note
+// given as "after" and/or before the note given as "before".
+func (h *NoteHandler) MoveNote(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "MoveNote",
 		"ip":      c.ClientIP(),
 	})
 
+	userID, noteID, log, err := noteParams(c, log)
+	if err != nil {
+		return err
+	}
+
+	var req models.MoveNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid request body")
+		return apierror.Bind(err)
+	}
+
+	note, err := h.notes.Move(auditContext(c), userID, noteID, req)
+	if err != nil {
+		return noteError(log, err, "Failed to move note")
+	}
+
+	log.WithField("position", note.Position).Info("Note moved successfully")
+
+	c.JSON(http.StatusOK, note)
+	return nil
+}
+
+// noteParams reads the authenticated user and the :id URL parameter. The
+// returned entry carries both IDs.
+func noteParams(c *gin.Context, log *logrus.Entry) (int64, int64, *logrus.Entry, error) {
 	// Get note ID from URL parameter
-	noteIDStr := c.Param("id")
-	noteID, err := strconv.ParseInt(noteIDStr, 10, 64)
+	noteID, err := idParam(c, "id", "note")
 	if err != nil {
-		log.WithField("note_id_str", noteIDStr).Warn("Invalid note ID format")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
-		return
+		log.WithField("note_id_str", c.Param("id")).Warn("Invalid note ID format")
+		return 0, 0, log, err
 	}
 
 	log = log.WithField("note_id", noteID)
@@ -175,28 +211,44 @@ func DeleteNote(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		log.Warn("User not authenticated")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
+		return 0, 0, log, apierror.Unauthenticated("User not authenticated")
 	}
 
-	log = log.WithField("user_id", userID)
+	return userID.(int64), noteID, log.WithField("user_id", userID), nil
+}
 
-	// Check if note exists and belongs to user
-	var note models.Note
-	if err := database.DB.Where("id = ? AND user_id = ?", noteID, userID.(int64)).First(&note).Error; err != nil {
+// noteError converts an error from service.NoteService. failure is the
+// detail used for unexpected errors.
+func noteError(log *logrus.Entry, err error, failure string) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrNoteNotFound):
 		log.Warn("Note not found or does not belong to user")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
-		return
+		return apierror.NotFound("Note not found")
+	case errors.Is(err, service.ErrAnchorNotFound):
+		log.WithError(err).Warn("Move anchor not found")
+		return apierror.BadRequest("Anchor note not found")
+	case errors.Is(err, service.ErrAnchorOrder):
+		log.WithError(err).Warn("Move anchors out of order")
+		return apierror.BadRequest("The after note must come before the before note")
+	case errors.As(err, &validationErr):
+		log.WithError(err).Warn("Invalid request")
+		if validationErr.Field != "" {
+			return apierror.Invalid(validationErr.Field, "invalid", validationErr.Message)
+		}
+		return apierror.BadRequest(validationErr.Message)
+	default:
+		log.WithError(err).Error(failure)
+		return apierror.Internal(err, failure)
 	}
+}
 
-	// Delete note
-	if err := database.DB.Delete(&note).Error; err != nil {
-		log.WithError(err).Error("Failed to delete note")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
-		return
+// idParam reads a positive ID from the URL parameter name. what names the
+// resource in the error, such as "note".
+func idParam(c *gin.Context, name, what string) (int64, error) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, apierror.BadRequest("Invalid " + what + " ID")
 	}
-
-	log.Info("Note deleted successfully")
-
-	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
+	return id, nil
 }
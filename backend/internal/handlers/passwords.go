@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPassword hashes a password with bcrypt. It is deliberately slow, so
+// it gets its own span.
+func hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "bcrypt.hash")
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	tracing.End(span, err)
+	return string(hash), err
+}
+
+// checkPassword compares a password with its bcrypt hash and returns nil
+// when they match. A mismatch is not marked as a failed span.
+func checkPassword(ctx context.Context, hash, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.compare")
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	span.SetAttributes(attribute.Bool("password.match", err == nil))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		tracing.End(span, nil)
+	} else {
+		tracing.End(span, err)
+	}
+	return err
+}
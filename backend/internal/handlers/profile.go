@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/mailer"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+	"golang.org/x/text/language"
+)
+
+// GetMe returns the profile of the authenticated user.
+func (h *AccountHandler) GetMe(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "GetMe",
+		"ip":      c.ClientIP(),
+	})
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	user, err := h.users.FindByID(c.Request.Context(), userID.(int64))
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn("User not found")
+		return apierror.NotFound("User not found")
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch user")
+		return apierror.Internal(err, "Failed to fetch user")
+	}
+
+	c.JSON(http.StatusOK, user.ToDTO())
+	return nil
+}
+
+// UpdateMe partially updates the display name, locale, timezone and UI preferences.
+func (h *AccountHandler) UpdateMe(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "UpdateMe",
+		"ip":      c.ClientIP(),
+	})
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid update profile request")
+		return apierror.Bind(err)
+	}
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	// Build updates map - only include fields that are present in JSON
+	updates := make(map[string]interface{})
+	if req.DisplayName != nil {
+		updates["display_name"] = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Locale != nil {
+		tag, err := language.Parse(*req.Locale)
+		if err != nil {
+			log.WithField("locale", *req.Locale).Warn("Invalid locale")
+			return apierror.Invalid("locale", "locale", "must be a BCP 47 language tag")
+		}
+		updates["locale"] = tag.String()
+	}
+	if req.Timezone != nil {
+		if *req.Timezone == "" || *req.Timezone == "Local" {
+			return apierror.Invalid("timezone", "timezone", "must be an IANA time zone name")
+		}
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			log.WithField("timezone", *req.Timezone).Warn("Invalid timezone")
+			return apierror.Invalid("timezone", "timezone", "must be an IANA time zone name")
+		}
+		updates["timezone"] = *req.Timezone
+	}
+	if req.Preferences != nil {
+		if req.Preferences.DefaultNoteColor != nil {
+			updates["pref_default_note_color"] = *req.Preferences.DefaultNoteColor
+		}
+		if req.Preferences.NoteView != nil {
+			updates["pref_note_view"] = *req.Preferences.NoteView
+		}
+	}
+
+	if len(updates) == 0 {
+		log.Warn("No fields provided for update")
+		return apierror.BadRequest("At least one field must be provided")
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.users.FindByID(ctx, userID.(int64))
+	if err != nil {
+		log.WithError(err).Warn("User not found")
+		return apierror.NotFound("User not found")
+	}
+
+	if err := h.users.Update(ctx, user, updates); err != nil {
+		log.WithError(err).Error("Failed to update profile")
+		return apierror.Internal(err, "Failed to update profile")
+	}
+
+	// Reload user to get updated values
+	if user, err = h.users.FindByID(ctx, user.ID); err != nil {
+		log.WithError(err).Error("Failed to reload profile")
+		return apierror.Internal(err, "Failed to update profile")
+	}
+
+	log.Info("Profile updated successfully")
+
+	c.JSON(http.StatusOK, user.ToDTO())
+	return nil
+}
+
+// ChangePassword sets a new password after verifying the current one. All
+// refresh tokens are revoked and the caller receives a fresh session, so
+// every other device has to log in again.
+func (h *AuthHandler) ChangePassword(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "ChangePassword",
+		"ip":      c.ClientIP(),
+	})
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid change password request")
+		return apierror.Bind(err)
+	}
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	ctx := c.Request.Context()
+
+	user, err := h.users.FindByID(ctx, userID.(int64))
+	if err != nil {
+		log.WithError(err).Warn("User not found")
+		return apierror.NotFound("User not found")
+	}
+
+	if err := checkPassword(ctx, user.PasswordHash, req.CurrentPassword); err != nil {
+		log.Warn("Password change refused: invalid current password")
+		return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid current password")
+	}
+
+	hashedPassword, err := hashPassword(ctx, req.NewPassword)
+	if err != nil {
+		log.WithError(err).Error("Failed to hash password")
+		return apierror.Internal(err, "Failed to hash password")
+	}
+
+	// Revoke sessions first: if the password update then fails, the worst
+	// case is signing in again with the old password
+	if err := h.tokens.RevokeAll(ctx, user.ID); err != nil {
+		log.WithError(err).Error("Failed to revoke sessions")
+		return apierror.Internal(err, "Failed to change password")
+	}
+	if err := h.users.Update(ctx, user, map[string]interface{}{"password_hash": hashedPassword}); err != nil {
+		log.WithError(err).Error("Failed to change password")
+		return apierror.Internal(err, "Failed to change password")
+	}
+
+	resp, err := h.issueSession(c, *user)
+	if err != nil {
+		log.WithError(err).Error("Failed to issue new session")
+		return apierror.Internal(err, "Failed to issue new session")
+	}
+
+	h.record(c, audit.ActionPasswordChange, user.ID, nil)
+
+	log.Info("Password changed, other sessions revoked")
+
+	c.JSON(http.StatusOK, resp)
+	return nil
+}
+
+// RequestEmailChange sends a confirmation link to the new address. The email
+// is only changed once that link is used (see ConfirmEmailChange).
+func (h *AccountHandler) RequestEmailChange(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "RequestEmailChange",
+		"ip":      c.ClientIP(),
+	})
+
+	var req models.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid change email request")
+		return apierror.Bind(err)
+	}
+
+	// Get user ID from context (set by AuthMiddleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		log.Warn("User not authenticated")
+		return apierror.Unauthenticated("User not authenticated")
+	}
+
+	log = log.WithField("user_id", userID)
+
+	ctx := c.Request.Context()
+	user, err := h.users.FindByID(ctx, userID.(int64))
+	if err != nil {
+		log.WithError(err).Warn("User not found")
+		return apierror.NotFound("User not found")
+	}
+
+	if err := checkPassword(ctx, user.PasswordHash, req.Password); err != nil {
+		log.Warn("Email change refused: invalid password")
+		return apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid password")
+	}
+
+	if strings.EqualFold(req.NewEmail, user.Email) {
+		return apierror.Invalid("new_email", "unchanged", "must differ from the current email")
+	}
+
+	if _, err := h.users.FindByEmail(ctx, req.NewEmail); err == nil {
+		log.Warn("Email change refused: email already in use")
+		return apierror.New(http.StatusConflict, apierror.CodeEmailTaken, "User with this email already exists")
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		log.WithError(err).Error("Failed to generate confirmation token")
+		return apierror.Internal(err, "Failed to generate confirmation token")
+	}
+
+	cfg := config.Get()
+	change := models.EmailChange{
+		UserID:    user.ID,
+		NewEmail:  req.NewEmail,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(cfg.EmailChangeTTL),
+	}
+
+	// Only the latest request stays valid
+	if err := h.emails.Replace(ctx, &change); err != nil {
+		log.WithError(err).Error("Failed to store email change request")
+		return apierror.Internal(err, "Failed to request email change")
+	}
+
+	link := strings.TrimRight(cfg.PublicURL, "/") + "/confirm-email?token=" + url.QueryEscape(token)
+	msg := mailer.Message{
+		To:      req.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Someone asked to use this address for their Keep account (currently %s).\n\n"+
+			"Open the link below to confirm the change. It expires in %s.\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.Email, cfg.EmailChangeTTL, link),
+	}
+	if err := mailer.Send(ctx, msg); err != nil {
+		log.WithError(err).Error("Failed to send confirmation email")
+		return apierror.Wrap(err, http.StatusBadGateway, apierror.CodeEmailFailed, "Failed to send confirmation email")
+	}
+
+	log.Info("Email change requested, confirmation sent")
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation email sent to the new address"})
+	return nil
+}
+
+// ConfirmEmailChange applies a pending email change. It is public: the
+// token from the confirmation email is the proof of ownership.
+func (h *AccountHandler) ConfirmEmailChange(c *gin.Context) error {
+	log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"handler": "ConfirmEmailChange",
+		"ip":      c.ClientIP(),
+	})
+
+	var req models.ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("Invalid confirm email request")
+		return apierror.Bind(err)
+	}
+
+	ctx := c.Request.Context()
+	change, err := h.emails.FindByHash(ctx, hashToken(req.Token))
+	if err != nil {
+		log.Warn("Email confirmation failed: invalid token")
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidToken, "Invalid or expired confirmation token")
+	}
+
+	log = log.WithField("user_id", change.UserID)
+
+	if change.ExpiresAt.Before(time.Now()) {
+		if err := h.emails.Delete(ctx, change); err != nil {
+			log.WithError(err).Warn("Failed to delete expired email change")
+		}
+		log.Warn("Email confirmation failed: token expired")
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidToken, "Invalid or expired confirmation token")
+	}
+
+	// The address may have been taken since the request was made
+	user, err := h.emails.Apply(ctx, change)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		log.Warn("Email confirmation failed: email already in use")
+		return apierror.New(http.StatusConflict, apierror.CodeEmailTaken, "User with this email already exists")
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to change email")
+		return apierror.Internal(err, "Failed to change email")
+	}
+
+	log.Info("Email changed successfully")
+
+	c.JSON(http.StatusOK, user.ToDTO())
+	return nil
+}
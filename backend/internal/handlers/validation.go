@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// RegisterValidators adds the custom binding tags used by request models
+// and reports invalid fields by their JSON name. It must be called once
+// before the router starts serving.
+func RegisterValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+
+	v.RegisterTagNameFunc(apierror.JSONFieldName)
+
+	return v.RegisterValidation("note_color", func(fl validator.FieldLevel) bool {
+		return models.IsNoteColor(fl.Field().String())
+	})
+}
@@ -0,0 +1,137 @@
+// Package health serves the liveness and readiness probes. Liveness only
+// says the process is up; readiness runs the dependency checks with a
+// timeout and turns false once a graceful shutdown has started, so load
+// balancers stop sending traffic to an instance that can't serve it.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkTimeout bounds each check, so a hung dependency fails the probe
+// instead of stalling it.
+const checkTimeout = 2 * time.Second
+
+// Statuses of a readiness report and of its components.
+const (
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+)
+
+// Check is one dependency of the server.
+type Check struct {
+	Name string
+	// Optional checks are reported but don't make the server not ready,
+	// for dependencies only a few requests need.
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+// Component is the result of one check.
+type Component struct {
+	Status     string `json:"status"`
+	Optional   bool   `json:"optional,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the readiness of the server with the result of every check.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Checker runs the readiness checks.
+type Checker struct {
+	checks       []Check
+	shuttingDown atomic.Bool
+}
+
+// NewChecker returns a Checker running checks.
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// Add registers another check.
+func (h *Checker) Add(check Check) {
+	h.checks = append(h.checks, check)
+}
+
+// SetShuttingDown makes the server report not ready from now on, without
+// running the checks.
+func (h *Checker) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Check runs every check in parallel and reports the server ready when
+// all the required ones pass.
+func (h *Checker) Check(ctx context.Context) Report {
+	if h.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	components := make([]Component, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			components[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Components: make(map[string]Component, len(h.checks))}
+	for i, check := range h.checks {
+		report.Components[check.Name] = components[i]
+		if components[i].Status != StatusOK && !check.Optional {
+			report.Status = StatusNotReady
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) Component {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	component := Component{
+		Status:     StatusOK,
+		Optional:   check.Optional,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		component.Status = StatusFail
+		component.Error = err.Error()
+	}
+	return component
+}
+
+// Live serves the liveness probe: it answers as long as the process can
+// serve HTTP, also during a shutdown, so a slow dependency never gets the
+// instance restarted.
+func Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Ready serves the readiness probe, with 503 when the server is not ready.
+func (h *Checker) Ready(c *gin.Context) {
+	report := h.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
@@ -0,0 +1,270 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxAttachmentBytes is the largest single attachment that is imported.
+const MaxAttachmentBytes = 25 << 20
+
+// progressEvery controls how often job progress is written to the database.
+const progressEvery = 10
+
+// maxTitleLength matches the size of the notes.title column.
+const maxTitleLength = 255
+
+// maxLabelLength matches the size of the labels.name column.
+const maxLabelLength = 100
+
+// Import sources, stored in ImportJob.Source.
+const (
+	SourceGoogleKeep = "google_keep"
+	SourceJSON       = "json"
+)
+
+// OpenArchive opens an uploaded archive of the given source.
+func OpenArchive(source string, r io.ReaderAt, size int64) (Archive, error) {
+	switch source {
+	case SourceGoogleKeep:
+		return OpenKeepArchive(r, size)
+	case SourceJSON:
+		return OpenJSONArchive(r, size)
+	default:
+		return nil, fmt.Errorf("unknown import source %q", source)
+	}
+}
+
+// Importer runs import jobs, writing the notes to a database.
+type Importer struct {
+	db    *gorm.DB
+	audit *audit.Log
+}
+
+// New returns an Importer storing notes in db and recording their creation
+// in auditLog.
+func New(db *gorm.DB, auditLog *audit.Log) *Importer {
+	return &Importer{db: db, audit: auditLog}
+}
+
+// Run imports the archive at archivePath for the job's user, updating the
+// job row with progress and a per-note report. The archive file is removed
+// when the import ends.
+func (im *Importer) Run(ctx context.Context, job *models.ImportJob, archivePath string) {
+	db := im.db
+	log := logger.WithFields(logrus.Fields{
+		"job":     "import",
+		"source":  job.Source,
+		"job_id":  job.ID,
+		"user_id": job.UserID,
+	})
+	defer os.Remove(archivePath)
+
+	started := time.Now()
+	job.Status = models.ImportStatusRunning
+	job.StartedAt = &started
+	if err := db.WithContext(ctx).Save(job).Error; err != nil {
+		log.WithError(err).Error("Failed to mark import job as running")
+		return
+	}
+
+	err := im.runImport(ctx, job, archivePath)
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Status = models.ImportStatusFailed
+		job.Error = err.Error()
+		log.WithError(err).Error("Import job failed")
+	} else {
+		job.Status = models.ImportStatusCompleted
+		log.WithFields(logrus.Fields{
+			"total":    job.Total,
+			"imported": job.Imported,
+			"failed":   job.Failed,
+		}).Info("Import job completed")
+	}
+
+	// Use a fresh context: the final state must be saved even if ctx was cancelled
+	if err := db.WithContext(context.Background()).Save(job).Error; err != nil {
+		log.WithError(err).Error("Failed to save import job result")
+	}
+}
+
+func (im *Importer) runImport(ctx context.Context, job *models.ImportJob, archivePath string) error {
+	db := im.db
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	archive, err := OpenArchive(job.Source, f, info.Size())
+	if err != nil {
+		return err
+	}
+
+	job.Total = archive.Count()
+	if err := db.WithContext(ctx).Model(job).Update("total", job.Total).Error; err != nil {
+		return err
+	}
+
+	return archive.Each(func(file string, parsed *ParsedNote, parseErr error) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("import interrupted: %w", err)
+		}
+
+		result := im.importNote(ctx, job, file, parsed, parseErr)
+		job.Report = append(job.Report, result)
+		job.Processed++
+		switch result.Status {
+		case models.ImportNoteImported:
+			job.Imported++
+		case models.ImportNoteFailed:
+			job.Failed++
+		}
+
+		if job.Processed%progressEvery == 0 {
+			if err := db.WithContext(ctx).Model(job).Updates(map[string]interface{}{
+				"processed": job.Processed,
+				"imported":  job.Imported,
+				"failed":    job.Failed,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// importNote stores one parsed note on top of the user's notes and records
+// its creation, attributed to the user who started the import.
+func (im *Importer) importNote(ctx context.Context, job *models.ImportJob, file string, parsed *ParsedNote, parseErr error) models.ImportNoteResult {
+	userID := job.UserID
+	result := models.ImportNoteResult{File: file}
+	if parseErr != nil {
+		result.Status = models.ImportNoteFailed
+		result.Error = parseErr.Error()
+		return result
+	}
+
+	result.Title = parsed.Title
+	result.Warnings = parsed.Warnings
+
+	if parsed.Title == "" && parsed.Content == "" && len(parsed.Attachments) == 0 {
+		result.Status = models.ImportNoteSkipped
+		result.Error = "note is empty"
+		return result
+	}
+
+	title := parsed.Title
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		title = string([]rune(title)[:maxTitleLength])
+		result.Warnings = append(result.Warnings, "title truncated to 255 characters")
+	}
+
+	labelNames := make([]string, len(parsed.Labels))
+	for i, name := range parsed.Labels {
+		name = strings.TrimSpace(name)
+		if utf8.RuneCountInString(name) > maxLabelLength {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("label %q truncated to 100 characters", name))
+			name = string([]rune(name)[:maxLabelLength])
+		}
+		labelNames[i] = name
+	}
+
+	note := models.Note{
+		Title:     title,
+		Content:   parsed.Content,
+		Color:     parsed.Color,
+		Pinned:    parsed.Pinned,
+		Archived:  parsed.Archived,
+		TrashedAt: parsed.TrashedAt,
+		Revision:  parsed.Revision,
+		UserID:    userID,
+		CreatedAt: parsed.CreatedAt,
+		UpdatedAt: parsed.UpdatedAt,
+	}
+
+	err := im.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		labels, err := database.FindOrCreateLabels(tx, userID, labelNames)
+		if err != nil {
+			return fmt.Errorf("failed to create labels: %w", err)
+		}
+		note.Labels = labels
+		// An exported position keeps the note where it was; the order is
+		// respaced later if it collides with the user's other notes
+		note.Position = parsed.Position
+		if note.Position == "" {
+			if note.Position, err = database.TopNotePosition(tx, userID); err != nil {
+				return fmt.Errorf("failed to position note: %w", err)
+			}
+		}
+
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to create note: %w", err)
+		}
+
+		for _, att := range parsed.Attachments {
+			if att.File.UncompressedSize64 > MaxAttachmentBytes {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("attachment %q skipped: larger than %d MB", att.Filename, MaxAttachmentBytes>>20))
+				continue
+			}
+			data, err := readZipFile(att.File, MaxAttachmentBytes)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("attachment %q skipped: %v", att.Filename, err))
+				continue
+			}
+			attachment := models.Attachment{
+				NoteID:    note.ID,
+				UserID:    userID,
+				Filename:  att.Filename,
+				MimeType:  att.MimeType,
+				Size:      int64(len(data)),
+				Data:      data,
+				CreatedAt: att.CreatedAt,
+			}
+			if err := tx.Create(&attachment).Error; err != nil {
+				return fmt.Errorf("failed to store attachment %q: %w", att.Filename, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		result.Status = models.ImportNoteFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	after := audit.NoteSummary(&note)
+	after["import_job_id"] = job.ID
+	im.audit.Record(ctx, models.AuditEvent{
+		ActorID:    audit.ID(userID),
+		UserID:     audit.ID(userID),
+		Action:     audit.ActionNoteCreate,
+		TargetType: models.AuditTargetNote,
+		TargetID:   audit.ID(note.ID),
+		After:      after,
+	})
+
+	result.Status = models.ImportNoteImported
+	result.NoteID = note.ID
+	return result
+}
@@ -0,0 +1,197 @@
+package importer_test
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/export"
+	"github.com/tgogbera/google_keep_clone-backend/internal/importer"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	os.Exit(m.Run())
+}
+
+// writeArchive writes a JSON export archive holding doc and files.
+func writeArchive(t *testing.T, doc export.Document, files map[string]string) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "export.zip")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(export.DocumentFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+// runImport imports the archive at archivePath for a new user and returns
+// the finished job.
+func runImport(t *testing.T, db *gorm.DB, archivePath string) *models.ImportJob {
+	t.Helper()
+	ctx := context.Background()
+	user := models.User{Email: "alice@example.com", PasswordHash: "x"}
+	if err := repository.NewUserRepository(db).Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	job := models.ImportJob{UserID: user.ID, Source: importer.SourceJSON, Status: models.ImportStatusPending}
+	if err := repository.NewImportJobRepository(db).Start(ctx, &job, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	importer.New(db, audit.New(repository.NewAuditRepository(db))).Run(ctx, &job, archivePath)
+	if job.Status != models.ImportStatusCompleted {
+		t.Fatalf("import %s: %s", job.Status, job.Error)
+	}
+	return &job
+}
+
+func TestImportPositionsAndAuditsNotes(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		created := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+		job := runImport(t, db, writeArchive(t, export.Document{
+			Version: export.DocumentVersion,
+			Notes: []export.NoteRecord{
+				{Title: "First", CreatedAt: created, UpdatedAt: created},
+				{Title: "Second", CreatedAt: created, UpdatedAt: created},
+			},
+		}, nil))
+		if job.Imported != 2 {
+			t.Fatalf("imported %d notes, want 2: %+v", job.Imported, job.Report)
+		}
+
+		var notes []models.Note
+		if err := db.Where("user_id = ?", job.UserID).Order("position").Find(&notes).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 2 || notes[0].Position == "" || notes[0].Position == notes[1].Position {
+			t.Fatalf("imported notes = %+v, want distinct positions", notes)
+		}
+		// Each note is put on top, so the last one imported comes first
+		if notes[0].Title != "Second" {
+			t.Errorf("first note by position = %q, want Second", notes[0].Title)
+		}
+
+		var events []models.AuditEvent
+		if err := db.Where("action = ?", audit.ActionNoteCreate).Order("id").Find(&events).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("%d note.create events, want 2", len(events))
+		}
+		for i, event := range events {
+			if event.TargetID == nil || *event.TargetID != job.Report[i].NoteID {
+				t.Errorf("event %d target = %v, want note %d", i, event.TargetID, job.Report[i].NoteID)
+			}
+			if event.ActorID == nil || *event.ActorID != job.UserID {
+				t.Errorf("event %d actor = %v, want user %d", i, event.ActorID, job.UserID)
+			}
+			if id, _ := event.After["import_job_id"].(float64); int64(id) != job.ID {
+				t.Errorf("event %d import_job_id = %v, want %d", i, event.After["import_job_id"], job.ID)
+			}
+		}
+	})
+}
+
+func TestImportSanitizesAttachments(t *testing.T) {
+	db := testdb.SQLite(t)
+	job := runImport(t, db, writeArchive(t, export.Document{
+		Version: export.DocumentVersion,
+		Notes: []export.NoteRecord{{
+			Title: "Files",
+			Attachments: []export.AttachmentRecord{
+				{Filename: "../../evil.txt", MimeType: "text/html\r\nX-Injected: 1", Path: "attachments/1"},
+				{Filename: `..\..\windows.png`, MimeType: "IMAGE/PNG", Path: "attachments/2"},
+				{Filename: "..", MimeType: "text/plain", Path: "attachments/3"},
+				{Path: "attachments/4-from-path.bin"},
+			},
+		}},
+	}, map[string]string{
+		"attachments/1":               "a",
+		"attachments/2":               "b",
+		"attachments/3":               "c",
+		"attachments/4-from-path.bin": "d",
+	}))
+	if job.Imported != 1 || len(job.Report[0].Warnings) != 1 {
+		t.Fatalf("report = %+v, want one note with one warning", job.Report)
+	}
+
+	var attachments []models.Attachment
+	if err := db.Order("id").Find(&attachments).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ filename, mimeType string }{
+		{"evil.txt", "text/plain; charset=utf-8"},
+		{"windows.png", "image/png"},
+		{"4-from-path.bin", "application/octet-stream"},
+	}
+	if len(attachments) != len(want) {
+		t.Fatalf("%d attachments stored, want %d", len(attachments), len(want))
+	}
+	for i, w := range want {
+		if a := attachments[i]; a.Filename != w.filename || a.MimeType != w.mimeType {
+			t.Errorf("attachment %d = %q (%s), want %q (%s)", i, a.Filename, a.MimeType, w.filename, w.mimeType)
+		}
+	}
+}
+
+func TestImportTruncatesLongLabels(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		long := strings.Repeat("é", 120)
+		job := runImport(t, db, writeArchive(t, export.Document{
+			Version: export.DocumentVersion,
+			Notes:   []export.NoteRecord{{Title: "Labelled", Labels: []string{"short", long}}},
+		}, nil))
+		if job.Imported != 1 || len(job.Report[0].Warnings) != 1 {
+			t.Fatalf("report = %+v, want one note with one warning", job.Report)
+		}
+
+		var note models.Note
+		if err := db.Preload("Labels").First(&note, job.Report[0].NoteID).Error; err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, l := range note.Labels {
+			names = append(names, l.Name)
+		}
+		sort.Strings(names)
+		if want := []string{"short", strings.Repeat("é", 100)}; !reflect.DeepEqual(names, want) {
+			t.Errorf("labels = %q, want %q", names, want)
+		}
+	})
+}
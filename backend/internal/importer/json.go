@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/export"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/rank"
+)
+
+// maxDocumentBytes bounds the size of notes.json in a JSON export archive.
+const maxDocumentBytes = 256 << 20
+
+// JSONArchive is an archive produced by the JSON export (export.JSON).
+type JSONArchive struct {
+	files map[string]*zip.File
+	doc   export.Document
+}
+
+// OpenJSONArchive reads notes.json from a JSON export archive.
+func OpenJSONArchive(r io.ReaderAt, size int64) (*JSONArchive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid zip archive: %w", err)
+	}
+
+	a := &JSONArchive{files: make(map[string]*zip.File)}
+	for _, f := range zr.File {
+		a.files[f.Name] = f
+	}
+
+	f, ok := a.files[export.DocumentFile]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s", export.DocumentFile)
+	}
+	data, err := readZipFile(f, maxDocumentBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &a.doc); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", export.DocumentFile, err)
+	}
+	if a.doc.Version != export.DocumentVersion {
+		return nil, fmt.Errorf("unsupported export version %d", a.doc.Version)
+	}
+	return a, nil
+}
+
+// Count implements Archive.
+func (a *JSONArchive) Count() int {
+	return len(a.doc.Notes)
+}
+
+// Each implements Archive.
+func (a *JSONArchive) Each(fn func(file string, note *ParsedNote, err error) error) error {
+	for i, rec := range a.doc.Notes {
+		file := fmt.Sprintf("%s#%d", export.DocumentFile, i)
+		note, err := a.parse(file, rec)
+		if err := fn(file, note, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *JSONArchive) parse(file string, rec export.NoteRecord) (*ParsedNote, error) {
+	if rec.Color != "" && !models.IsNoteColor(rec.Color) {
+		return nil, fmt.Errorf("invalid color %q", rec.Color)
+	}
+
+	note := &ParsedNote{
+		File:      file,
+		Title:     rec.Title,
+		Content:   rec.Content,
+		Color:     rec.Color,
+		Pinned:    rec.Pinned,
+		Archived:  rec.Archived,
+		TrashedAt: rec.TrashedAt,
+		Position:  rec.Position,
+		Revision:  rec.Revision,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+		Labels:    rec.Labels,
+	}
+	if note.Color == "" {
+		note.Color = "default"
+	}
+	if note.Revision < 0 {
+		note.Revision = 0
+	}
+	if len(note.Position) > database.MaxPositionLength || !rank.Valid(note.Position) {
+		note.Warnings = append(note.Warnings, fmt.Sprintf("invalid position %q, note placed on top", note.Position))
+		note.Position = ""
+	}
+
+	for _, att := range rec.Attachments {
+		zf, ok := a.files[att.Path]
+		if !ok {
+			note.Warnings = append(note.Warnings, fmt.Sprintf("attachment %q not found in archive", att.Path))
+			continue
+		}
+		name := att.Filename
+		if name == "" {
+			name = att.Path
+		}
+		// The document is user input: a name like "../x" must not be stored
+		filename := export.CleanFilename(name)
+		if filename == "" {
+			note.Warnings = append(note.Warnings, fmt.Sprintf("attachment %q skipped: invalid filename", att.Path))
+			continue
+		}
+		if att.Size != 0 && uint64(att.Size) != zf.UncompressedSize64 {
+			note.Warnings = append(note.Warnings, fmt.Sprintf("attachment %q is %d bytes, the document says %d", att.Path, zf.UncompressedSize64, att.Size))
+		}
+		note.Attachments = append(note.Attachments, ParsedAttachment{
+			File:      zf,
+			Filename:  filename,
+			MimeType:  attachmentMimeType(att.MimeType, filename),
+			CreatedAt: att.CreatedAt,
+		})
+	}
+
+	return note, nil
+}
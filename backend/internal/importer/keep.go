@@ -0,0 +1,414 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/export"
+	"golang.org/x/net/html"
+)
+
+// maxNoteFileBytes bounds how much of a single note file is read.
+const maxNoteFileBytes = 10 << 20
+
+// keepNote mirrors the per-note JSON files in a Google Keep Takeout archive.
+type keepNote struct {
+	Title                   string            `json:"title"`
+	TextContent             string            `json:"textContent"`
+	ListContent             []keepListItem    `json:"listContent"`
+	Color                   string            `json:"color"`
+	IsPinned                bool              `json:"isPinned"`
+	IsArchived              bool              `json:"isArchived"`
+	IsTrashed               bool              `json:"isTrashed"`
+	CreatedTimestampUsec    int64             `json:"createdTimestampUsec"`
+	UserEditedTimestampUsec int64             `json:"userEditedTimestampUsec"`
+	Labels                  []keepLabel       `json:"labels"`
+	Attachments             []keepAttachment  `json:"attachments"`
+	Annotations             []keepAnnotation  `json:"annotations"`
+	Sharees                 []json.RawMessage `json:"sharees"`
+}
+
+type keepListItem struct {
+	Text      string `json:"text"`
+	IsChecked bool   `json:"isChecked"`
+}
+
+type keepLabel struct {
+	Name string `json:"name"`
+}
+
+type keepAttachment struct {
+	FilePath string `json:"filePath"`
+	MimeType string `json:"mimetype"`
+}
+
+type keepAnnotation struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ParsedNote is a note read from an archive, mapped onto our fields.
+type ParsedNote struct {
+	File        string
+	Title       string
+	Content     string
+	Color       string
+	Pinned      bool
+	Archived    bool
+	TrashedAt   *time.Time
+	Position    string // empty puts the note on top
+	Revision    int    // zero starts a new history
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Labels      []string
+	Attachments []ParsedAttachment
+	Warnings    []string
+}
+
+// ParsedAttachment references a file inside the archive.
+type ParsedAttachment struct {
+	File      *zip.File
+	Filename  string
+	MimeType  string
+	CreatedAt time.Time // zero means the time of the import
+}
+
+// maxMimeTypeLength matches the size of the attachments.mime_type column.
+const maxMimeTypeLength = 100
+
+// attachmentMimeType returns the declared MIME type in canonical form. When
+// it is missing or invalid the type is guessed from the filename.
+func attachmentMimeType(declared, filename string) string {
+	if mediaType, params, err := mime.ParseMediaType(declared); err == nil {
+		if canonical := mime.FormatMediaType(mediaType, params); canonical != "" && len(canonical) <= maxMimeTypeLength {
+			return canonical
+		}
+	}
+	if guessed := mime.TypeByExtension(path.Ext(filename)); guessed != "" && len(guessed) <= maxMimeTypeLength {
+		return guessed
+	}
+	return "application/octet-stream"
+}
+
+// keepColors maps Keep's color constants onto models.NoteColors.
+var keepColors = map[string]string{
+	"DEFAULT":  "default",
+	"RED":      "red",
+	"ORANGE":   "orange",
+	"YELLOW":   "yellow",
+	"GREEN":    "green",
+	"TEAL":     "teal",
+	"BLUE":     "blue",
+	"CERULEAN": "cerulean",
+	"PURPLE":   "purple",
+	"PINK":     "pink",
+	"BROWN":    "brown",
+	"GRAY":     "gray",
+}
+
+// Archive is an opened import archive.
+type Archive interface {
+	// Count returns the number of notes found.
+	Count() int
+	// Each parses every note and calls fn with the result, or with the parse
+	// error for that file. Returning an error from fn stops the iteration.
+	Each(fn func(file string, note *ParsedNote, err error) error) error
+}
+
+// KeepArchive is an opened Takeout ZIP.
+type KeepArchive struct {
+	files map[string]*zip.File
+	notes []*zip.File
+}
+
+// OpenKeepArchive indexes the note files of a Takeout archive. Each note is
+// exported as JSON and HTML; the JSON file is preferred and the HTML file is
+// only used for notes that have no JSON counterpart (older exports).
+func OpenKeepArchive(r io.ReaderAt, size int64) (*KeepArchive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid zip archive: %w", err)
+	}
+
+	a := &KeepArchive{files: make(map[string]*zip.File)}
+	hasKeepDir := false
+	for _, f := range zr.File {
+		a.files[f.Name] = f
+		if isKeepPath(f.Name) {
+			hasKeepDir = true
+		}
+	}
+
+	for _, f := range zr.File {
+		// A full Takeout contains other products; only look inside Keep/ when present
+		if f.FileInfo().IsDir() || (hasKeepDir && !isKeepPath(f.Name)) {
+			continue
+		}
+		ext := strings.ToLower(path.Ext(f.Name))
+		base := strings.TrimSuffix(f.Name, path.Ext(f.Name))
+		switch ext {
+		case ".json":
+			a.notes = append(a.notes, f)
+		case ".html":
+			if _, ok := a.files[base+".json"]; !ok {
+				a.notes = append(a.notes, f)
+			}
+		}
+	}
+
+	sort.Slice(a.notes, func(i, j int) bool { return a.notes[i].Name < a.notes[j].Name })
+	return a, nil
+}
+
+func isKeepPath(name string) bool {
+	return strings.HasPrefix(name, "Keep/") || strings.Contains(name, "/Keep/")
+}
+
+// Count implements Archive.
+func (a *KeepArchive) Count() int {
+	return len(a.notes)
+}
+
+// Each implements Archive.
+func (a *KeepArchive) Each(fn func(file string, note *ParsedNote, err error) error) error {
+	for _, f := range a.notes {
+		var note *ParsedNote
+		var err error
+		if strings.EqualFold(path.Ext(f.Name), ".json") {
+			note, err = a.parseJSON(f)
+		} else {
+			note, err = a.parseHTML(f)
+		}
+		if err := fn(f.Name, note, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("file exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+func (a *KeepArchive) parseJSON(f *zip.File) (*ParsedNote, error) {
+	data, err := readZipFile(f, maxNoteFileBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var kn keepNote
+	if err := json.Unmarshal(data, &kn); err != nil {
+		return nil, fmt.Errorf("invalid note JSON: %w", err)
+	}
+
+	note := &ParsedNote{
+		File:     f.Name,
+		Title:    kn.Title,
+		Pinned:   kn.IsPinned,
+		Archived: kn.IsArchived,
+		Color:    "default",
+	}
+
+	if color, ok := keepColors[strings.ToUpper(kn.Color)]; ok {
+		note.Color = color
+	} else if kn.Color != "" {
+		note.Warnings = append(note.Warnings, fmt.Sprintf("unknown color %q, using default", kn.Color))
+	}
+
+	// Checklists have no dedicated model yet; they become Markdown task lists
+	var content strings.Builder
+	content.WriteString(kn.TextContent)
+	if len(kn.ListContent) > 0 {
+		if content.Len() > 0 {
+			content.WriteString("\n\n")
+		}
+		for i, item := range kn.ListContent {
+			if i > 0 {
+				content.WriteString("\n")
+			}
+			box := "[ ]"
+			if item.IsChecked {
+				box = "[x]"
+			}
+			content.WriteString("- " + box + " " + item.Text)
+		}
+	}
+	for _, an := range kn.Annotations {
+		if an.URL == "" {
+			continue
+		}
+		if content.Len() > 0 {
+			content.WriteString("\n\n")
+		}
+		content.WriteString(an.URL)
+	}
+	note.Content = content.String()
+
+	if kn.CreatedTimestampUsec > 0 {
+		note.CreatedAt = time.UnixMicro(kn.CreatedTimestampUsec)
+	}
+	if kn.UserEditedTimestampUsec > 0 {
+		note.UpdatedAt = time.UnixMicro(kn.UserEditedTimestampUsec)
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = note.UpdatedAt
+	}
+	if kn.IsTrashed {
+		// Keep doesn't record when a note was trashed; the last edit is the best guess
+		trashedAt := note.UpdatedAt
+		if trashedAt.IsZero() {
+			trashedAt = time.Now()
+		}
+		note.TrashedAt = &trashedAt
+	}
+
+	for _, l := range kn.Labels {
+		if name := strings.TrimSpace(l.Name); name != "" {
+			note.Labels = append(note.Labels, name)
+		}
+	}
+
+	dir := path.Dir(f.Name)
+	for _, att := range kn.Attachments {
+		zf := a.findAttachment(dir, att.FilePath)
+		if zf == nil {
+			note.Warnings = append(note.Warnings, fmt.Sprintf("attachment %q not found in archive", att.FilePath))
+			continue
+		}
+		filename := export.CleanFilename(zf.Name)
+		if filename == "" {
+			note.Warnings = append(note.Warnings, fmt.Sprintf("attachment %q skipped: invalid filename", att.FilePath))
+			continue
+		}
+		note.Attachments = append(note.Attachments, ParsedAttachment{
+			File:     zf,
+			Filename: filename,
+			MimeType: attachmentMimeType(att.MimeType, filename),
+		})
+	}
+
+	if len(kn.Sharees) > 0 {
+		note.Warnings = append(note.Warnings, "collaborators are not supported and were not imported")
+	}
+
+	return note, nil
+}
+
+// findAttachment resolves an attachment path relative to the note. Takeout
+// sometimes records ".jpeg" for files stored as ".jpg" and vice versa.
+func (a *KeepArchive) findAttachment(dir, name string) *zip.File {
+	if name == "" {
+		return nil
+	}
+	candidates := []string{path.Join(dir, name), name}
+	ext := path.Ext(name)
+	switch strings.ToLower(ext) {
+	case ".jpeg":
+		candidates = append(candidates, path.Join(dir, strings.TrimSuffix(name, ext)+".jpg"))
+	case ".jpg":
+		candidates = append(candidates, path.Join(dir, strings.TrimSuffix(name, ext)+".jpeg"))
+	}
+	for _, c := range candidates {
+		if f, ok := a.files[c]; ok {
+			return f
+		}
+	}
+	return nil
+}
+
+// parseHTML reads the title, text and labels of an HTML-only note. HTML
+// exports carry no reliable state flags, so those keep their defaults.
+func (a *KeepArchive) parseHTML(f *zip.File) (*ParsedNote, error) {
+	data, err := readZipFile(f, maxNoteFileBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid note HTML: %w", err)
+	}
+
+	note := &ParsedNote{
+		File:     f.Name,
+		Color:    "default",
+		Warnings: []string{"imported from HTML: color, state and timestamps are not available"},
+	}
+	if !f.Modified.IsZero() {
+		note.CreatedAt = f.Modified
+		note.UpdatedAt = f.Modified
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch class := htmlClass(n); {
+			case n.Data == "div" && class == "title":
+				note.Title = strings.TrimSpace(htmlText(n))
+				return
+			case n.Data == "div" && class == "content":
+				note.Content = strings.TrimSpace(htmlText(n))
+				return
+			case n.Data == "span" && class == "label-name":
+				if name := strings.TrimSpace(htmlText(n)); name != "" {
+					note.Labels = append(note.Labels, name)
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return note, nil
+}
+
+func htmlClass(n *html.Node) string {
+	for _, attr := range n.Attr {
+		if attr.Key == "class" {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlText returns the text of n, turning <br> into newlines.
+func htmlText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			b.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errDeletionCancelled = errors.New("account deletion cancelled")
+
+// PurgeDeletedAccounts permanently removes users whose deletion grace period
+// has expired, together with their notes, labels, attachments and refresh tokens. Each account is
+// removed in its own transaction so one failure doesn't block the others.
+func PurgeDeletedAccounts(ctx context.Context, db *gorm.DB) error {
+	var users []models.User
+	if err := db.WithContext(ctx).
+		Where("delete_after IS NOT NULL AND delete_after <= ?", time.Now()).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		log := logger.WithField("user_id", user.ID)
+
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Re-check under lock: the user may have cancelled the deletion by logging in
+			var locked models.User
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND delete_after IS NOT NULL AND delete_after <= ?", user.ID, time.Now()).
+				First(&locked).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errDeletionCancelled
+			}
+			if err != nil {
+				return err
+			}
+
+			noteIDs := tx.Model(&models.Note{}).Select("id").Where("user_id = ?", user.ID)
+			if err := tx.Exec("DELETE FROM note_labels WHERE note_id IN (?)", noteIDs).Error; err != nil {
+				return err
+			}
+			for _, model := range []interface{}{&models.Attachment{}, &models.Note{}, &models.Label{}, &models.ImportJob{}, &models.EmailChange{}} {
+				if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("user_id = ?", user.ID).Delete(&models.RefreshToken{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.User{}, user.ID).Error
+		})
+		if errors.Is(err, errDeletionCancelled) {
+			log.Info("Account deletion was cancelled, skipping purge")
+			continue
+		}
+		if err != nil {
+			log.WithError(err).Error("Failed to purge deleted account")
+			continue
+		}
+
+		log.Info("Deleted account purged")
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+)
+
+// RunPeriodic calls fn every interval until ctx is cancelled. Errors are
+// logged and do not stop the loop.
+func RunPeriodic(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	log := logger.WithField("job", name)
+	log.WithField("interval", interval.String()).Info("Background job started")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Background job stopped")
+			return
+		case <-ticker.C:
+			start := time.Now()
+			if err := fn(ctx); err != nil {
+				log.WithError(err).Error("Background job failed")
+				continue
+			}
+			log.WithField("elapsed_ms", time.Since(start).Milliseconds()).Debug("Background job run completed")
+		}
+	}
+}
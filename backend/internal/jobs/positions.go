@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxPositionLength is the longest note position left alone by
+// RebalanceNotePositions. Fresh positions are a few characters long.
+const maxPositionLength = 16
+
+// RebalanceNotePositions respaces the manual note order of users whose
+// positions have grown long through repeated moves, are duplicated after
+// concurrent moves or imports, or are missing. Each user is handled in its
+// own transaction.
+func RebalanceNotePositions(ctx context.Context, db *gorm.DB) error {
+	var userIDs []int64
+	if err := db.WithContext(ctx).Model(&models.Note{}).
+		Group("user_id").
+		Having("MAX(LENGTH(position)) > ? OR MIN(position) = '' OR COUNT(DISTINCT position) < COUNT(*)", maxPositionLength).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		log := logger.WithField("user_id", userID)
+
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return database.RespaceNotePositions(tx, userID)
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to rebalance note positions")
+			continue
+		}
+
+		log.Debug("Note positions rebalanced")
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package jobs_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/jobs"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/rank"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	os.Exit(m.Run())
+}
+
+func TestRebalanceNotePositions(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		users := map[string][]string{
+			"duplicated": {"b", "i", "i"},
+			"empty":      {"", "c", "e"},
+			"long":       {"a", "a" + strings.Repeat("z", 20), "b"},
+			"fine":       {"3", "f", "q"},
+		}
+		userIDs := make(map[string]int64)
+		for name, positions := range users {
+			user := models.User{Email: name + "@example.com", PasswordHash: "x"}
+			if err := db.Create(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			userIDs[name] = user.ID
+			for i, position := range positions {
+				note := models.Note{UserID: user.ID, Title: string(rune('A' + i)), Position: position}
+				if err := db.Create(&note).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		if err := jobs.RebalanceNotePositions(context.Background(), db); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, userID := range userIDs {
+			var notes []models.Note
+			if err := db.Where("user_id = ?", userID).Order("position, id").Find(&notes).Error; err != nil {
+				t.Fatal(err)
+			}
+			var titles, positions []string
+			for _, n := range notes {
+				titles = append(titles, n.Title)
+				positions = append(positions, n.Position)
+			}
+
+			if name == "fine" {
+				if !reflect.DeepEqual(positions, users[name]) {
+					t.Errorf("%s: positions changed to %q", name, positions)
+				}
+				continue
+			}
+			// The order is kept; ties go newest first
+			want := []string{"A", "B", "C"}
+			if name == "duplicated" {
+				want = []string{"A", "C", "B"}
+			}
+			if !reflect.DeepEqual(titles, want) {
+				t.Errorf("%s: order = %v, want %v", name, titles, want)
+			}
+			for i, position := range positions {
+				if position == "" || !rank.Valid(position) || len(position) > 2 || (i > 0 && positions[i-1] >= position) {
+					t.Errorf("%s: positions not respaced: %q", name, positions)
+					break
+				}
+			}
+		}
+	})
+}
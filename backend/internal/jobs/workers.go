@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+)
+
+// Background goroutines of the server share one context, cancelled by
+// Shutdown, so they can stop between writes instead of being killed.
+var (
+	workersCtx, cancelWorkers = context.WithCancel(context.Background())
+
+	workersMu      sync.Mutex
+	workersStopped bool
+	workers        sync.WaitGroup
+)
+
+// Go runs fn in a goroutine that Shutdown waits for. fn must return soon
+// after its context is cancelled. After Shutdown, Go doesn't start fn and
+// returns false.
+func Go(fn func(ctx context.Context)) bool {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	if workersStopped {
+		return false
+	}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		fn(workersCtx)
+	}()
+	return true
+}
+
+// Shutdown cancels the context of the goroutines started with Go and waits
+// for them to return, or for ctx to end.
+func Shutdown(ctx context.Context) error {
+	workersMu.Lock()
+	workersStopped = true
+	workersMu.Unlock()
+	cancelWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
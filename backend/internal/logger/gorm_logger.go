@@ -3,9 +3,12 @@ package logger
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/metrics"
+	"github.com/tgogbera/google_keep_clone-backend/internal/tracing"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
 )
@@ -59,7 +62,7 @@ func (l *GormLoggerAdapter) LogMode(level gormlogger.LogLevel) gormlogger.Interf
 // Info implements gorm logger.Interface
 func (l *GormLoggerAdapter) Info(ctx context.Context, msg string, data ...interface{}) {
 	if l.LogLevel >= gormlogger.Info {
-		Log.WithFields(logrus.Fields{
+		Log.WithContext(ctx).WithFields(logrus.Fields{
 			"component": "gorm",
 		}).Infof(msg, data...)
 	}
@@ -68,7 +71,7 @@ func (l *GormLoggerAdapter) Info(ctx context.Context, msg string, data ...interf
 // Warn implements gorm logger.Interface
 func (l *GormLoggerAdapter) Warn(ctx context.Context, msg string, data ...interface{}) {
 	if l.LogLevel >= gormlogger.Warn {
-		Log.WithFields(logrus.Fields{
+		Log.WithContext(ctx).WithFields(logrus.Fields{
 			"component": "gorm",
 		}).Warnf(msg, data...)
 	}
@@ -77,21 +80,25 @@ func (l *GormLoggerAdapter) Warn(ctx context.Context, msg string, data ...interf
 // Error implements gorm logger.Interface
 func (l *GormLoggerAdapter) Error(ctx context.Context, msg string, data ...interface{}) {
 	if l.LogLevel >= gormlogger.Error {
-		Log.WithFields(logrus.Fields{
+		Log.WithContext(ctx).WithFields(logrus.Fields{
 			"component": "gorm",
 		}).Errorf(msg, data...)
 	}
 }
 
-// Trace implements gorm logger.Interface
+// Trace implements gorm logger.Interface. Every query is also measured in
+// the metrics and traced, whatever the log level.
 func (l *GormLoggerAdapter) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	operation := queryOperation(sql)
+	metrics.ObserveQuery(operation, elapsed, err)
+	tracing.QuerySpan(ctx, begin, operation, rows, err)
+
 	if l.LogLevel <= gormlogger.Silent {
 		return
 	}
 
-	elapsed := time.Since(begin)
-	sql, rows := fc()
-
 	fields := logrus.Fields{
 		"component":     "gorm",
 		"elapsed_ms":    elapsed.Milliseconds(),
@@ -106,10 +113,22 @@ func (l *GormLoggerAdapter) Trace(ctx context.Context, begin time.Time, fc func(
 	switch {
 	case err != nil && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.SkipErrRecordNotFound):
 		fields["error"] = err.Error()
-		Log.WithFields(fields).Error("Database query failed")
+		Log.WithContext(ctx).WithFields(fields).Error("Database query failed")
 	case elapsed > l.SlowThreshold && l.SlowThreshold != 0:
-		Log.WithFields(fields).Warn("Slow database query")
+		Log.WithContext(ctx).WithFields(fields).Warn("Slow database query")
 	case l.LogLevel >= gormlogger.Info:
-		Log.WithFields(fields).Debug("Database query executed")
+		Log.WithContext(ctx).WithFields(fields).Debug("Database query executed")
+	}
+}
+
+// queryOperation returns the statement type of a query, keeping the label
+// set of the metrics small: select, insert, update, delete or other.
+func queryOperation(sql string) string {
+	verb, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	switch verb = strings.ToLower(verb); verb {
+	case "select", "insert", "update", "delete":
+		return verb
+	default:
+		return "other"
 	}
 }
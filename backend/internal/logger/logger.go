@@ -1,12 +1,16 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Log is the global logger instance
@@ -26,12 +30,18 @@ const (
 type Config struct {
 	Level       LogLevel
 	Environment string // "development" or "production"
+	// Output receives the log; nil means stdout
+	Output io.Writer
 }
 
 // Init initializes the global logger with the given configuration
 func Init(cfg Config) {
 	Log = logrus.New()
-	Log.SetOutput(os.Stdout)
+	if cfg.Output != nil {
+		Log.SetOutput(cfg.Output)
+	} else {
+		Log.SetOutput(os.Stdout)
+	}
 
 	// Set log level
 	switch cfg.Level {
@@ -58,12 +68,43 @@ func Init(cfg Config) {
 		})
 	}
 
+	Log.AddHook(contextHook{})
+
 	Log.Info("Logger initialized",
 		" level=", cfg.Level,
 		" environment=", cfg.Environment,
 	)
 }
 
+// contextHook adds the request ID and the trace and span IDs to entries
+// created with a request context, so all log lines of a request can be
+// found together and from its trace.
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := RequestID(entry.Context); id != "" {
+		entry.Data["request_id"] = id
+	}
+	if sc := trace.SpanContextFromContext(entry.Context); sc.IsValid() {
+		entry.Data["trace_id"] = sc.TraceID().String()
+		entry.Data["span_id"] = sc.SpanID().String()
+	}
+	return nil
+}
+
+// WithContext creates a new entry carrying ctx. Entries created with a
+// request context get its request and trace IDs.
+func WithContext(ctx context.Context) *logrus.Entry {
+	return Log.WithContext(ctx)
+}
+
 // WithField creates a new entry with a single field
 func WithField(key string, value interface{}) *logrus.Entry {
 	return Log.WithField(key, value)
@@ -174,7 +215,7 @@ func RequestLogger() gin.HandlerFunc {
 		}
 
 		// Log based on status code
-		entry := Log.WithFields(fields)
+		entry := Log.WithContext(c.Request.Context()).WithFields(fields)
 		switch {
 		case statusCode >= 500:
 			entry.Error("Server error")
@@ -193,14 +234,17 @@ func RecoveryLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				Log.WithFields(logrus.Fields{
+				Log.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 					"error":  err,
 					"path":   c.Request.URL.Path,
 					"method": c.Request.Method,
 					"ip":     c.ClientIP(),
 				}).Error("Panic recovered")
 
-				c.AbortWithStatus(500)
+				// Left unwritten for the error middleware to answer
+				c.Error(fmt.Errorf("panic: %v", err))
+				c.Status(http.StatusInternalServerError)
+				c.Abort()
 			}
 		}()
 		c.Next()
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the IDs accepted from clients and proxies.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID id. Entries
+// created with the context get a request_id field.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware returns a Gin middleware that takes the request ID
+// from the X-Request-ID header set by a client or proxy, or generates one,
+// stores it in the request context and echoes it in the response. It must
+// come before the middleware that logs.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so they
+// can't forge log fields or response headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns 16 random bytes in hex.
+func newRequestID() string {
+	raw := make([]byte, 16)
+	rand.Read(raw) // never fails since Go 1.24
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config holds mailer configuration
+type Config struct {
+	Host     string // empty disables SMTP and logs messages instead
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Default is the global mailer used by the handlers.
+var Default Mailer = LogMailer{}
+
+// Init configures the global mailer. Without an SMTP host, messages are
+// written to the log, which is enough for local development.
+func Init(cfg Config) {
+	if cfg.Host == "" {
+		Default = LogMailer{}
+		logger.Info("SMTP not configured, emails will be logged")
+		return
+	}
+
+	Default = &SMTPMailer{cfg: cfg}
+	logger.WithFields(logrus.Fields{
+		"host": cfg.Host,
+		"port": cfg.Port,
+	}).Info("SMTP mailer initialized")
+}
+
+// Send delivers msg with the global mailer.
+func Send(ctx context.Context, msg Message) error {
+	return Default.Send(ctx, msg)
+}
+
+// Check reports whether the SMTP server of the global mailer accepts
+// connections. There is nothing to check when emails are only logged.
+func Check(ctx context.Context) error {
+	if m, ok := Default.(*SMTPMailer); ok {
+		return m.Check(ctx)
+	}
+	return nil
+}
+
+// LogMailer writes emails to the log instead of sending them.
+type LogMailer struct{}
+
+// Send implements Mailer.
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	logger.WithFields(logrus.Fields{
+		"component": "mailer",
+		"to":        msg.To,
+		"subject":   msg.Subject,
+	}).Info("Email (not sent, SMTP disabled):\n" + msg.Body)
+	return nil
+}
+
+// SMTPMailer sends emails through an SMTP server using STARTTLS when offered.
+type SMTPMailer struct {
+	cfg Config
+}
+
+// Send implements Mailer.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	// net/smtp has no context support; run it in a goroutine so callers can give up
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, m.cfg.From, []string{msg.To}, buildMessage(m.cfg.From, msg))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check connects to the SMTP server and waits for its greeting, without
+// authenticating or sending anything.
+func (m *SMTPMailer) Check(ctx context.Context) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		return fmt.Errorf("SMTP server did not greet: %w", err)
+	}
+	return client.Quit()
+}
+
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
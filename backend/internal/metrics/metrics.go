@@ -0,0 +1,109 @@
+// Package metrics defines the Prometheus metrics of the server: HTTP
+// requests, database queries and connection pool, and business counters.
+// Metrics are registered in the default registry, which also carries the Go
+// runtime and process metrics.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+const namespace = "keep"
+
+// Results of logins and token refreshes.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being served.",
+	})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by statement type.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	dbQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Failed database queries by statement type, not counting missing records.",
+	}, []string{"operation"})
+
+	// NotesCreated counts notes created through the API.
+	NotesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notes_created_total",
+		Help:      "Notes created through the API.",
+	})
+
+	// Logins counts login attempts by result.
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result.",
+	}, []string{"result"})
+
+	// TokenRefreshes counts refresh token exchanges by result.
+	TokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_refreshes_total",
+		Help:      "Refresh token exchanges by result.",
+	}, []string{"result"})
+
+	// Registrations counts accounts created through the API.
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Accounts created through the API.",
+	})
+)
+
+func init() {
+	// Export both results from the start so rates work before the first failure
+	for _, result := range []string{ResultSuccess, ResultFailure} {
+		Logins.WithLabelValues(result)
+		TokenRefreshes.WithLabelValues(result)
+	}
+}
+
+// ObserveQuery records a database query of the given statement type. It is
+// called by the GORM logger for every statement.
+func ObserveQuery(operation string, elapsed time.Duration, err error) {
+	dbQueryDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		dbQueryErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterDBStats exports the connection pool statistics of db, labeled
+// db_name="main".
+func RegisterDBStats(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, "main"))
+}
@@ -0,0 +1,357 @@
+// Package migrations applies the versioned SQL schema migrations embedded in
+// the binary. Each dialect has its own directory of NNNN_name.up.sql and
+// NNNN_name.down.sql files; applied versions are recorded in the
+// schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// lockID identifies the Postgres advisory lock held while migrating, so
+// instances starting at the same time apply each migration only once.
+const lockID = 727_274_001
+
+// Migration is one schema change with the SQL to apply and revert it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration together with when it was applied.
+type Status struct {
+	Migration
+	AppliedAt *time.Time // nil if pending
+}
+
+// ErrNoDownMigration is returned by Down for a migration without a down file.
+var ErrNoDownMigration = errors.New("migration cannot be reverted")
+
+// Load returns the migrations for a GORM dialect name ("postgres" or
+// "sqlite"), ordered by version.
+func Load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := cutDirection(name)
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", name)
+		}
+		prefix, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", name)
+		}
+
+		body, err := fs.ReadFile(files, path.Join(dialect, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func cutDirection(name string) (base, direction string, ok bool) {
+	if base, ok = strings.CutSuffix(name, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok = strings.CutSuffix(name, ".down.sql"); ok {
+		return base, "down", true
+	}
+	return "", "", false
+}
+
+// Up applies all pending migrations in order and returns the ones applied.
+func Up(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	var applied []Migration
+	err := withRunner(ctx, db, func(r *runner) error {
+		done, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			ran, err := r.apply(ctx, m, true)
+			if err != nil {
+				return err
+			}
+			if ran {
+				applied = append(applied, m)
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones reverted.
+func Down(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := withRunner(ctx, db, func(r *runner) error {
+		done, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(r.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := r.migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(m.Down) == "" {
+				return fmt.Errorf("%d_%s: %w", m.Version, m.Name, ErrNoDownMigration)
+			}
+			ran, err := r.apply(ctx, m, false)
+			if err != nil {
+				return err
+			}
+			if ran {
+				reverted = append(reverted, m)
+			}
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// List returns every known migration with its applied time. Versions
+// recorded in the database but unknown to this binary are left out.
+func List(ctx context.Context, db *gorm.DB) ([]Status, error) {
+	var statuses []Status
+	err := withRunner(ctx, db, func(r *runner) error {
+		done, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			status := Status{Migration: m}
+			if at, ok := done[m.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending returns the migrations not yet applied.
+func Pending(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	statuses, err := List(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns the migrations not yet applied like Pending, but without
+// taking the migration lock or creating schema_migrations, so it is cheap
+// enough for readiness probes and never waits for a running migration.
+func Check(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sqlDB.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// runner applies migrations over a single connection, which holds the
+// advisory lock on Postgres.
+type runner struct {
+	conn       *sql.Conn
+	dialect    string
+	migrations []Migration
+}
+
+func withRunner(ctx context.Context, db *gorm.DB, fn func(*runner) error) error {
+	dialect := db.Dialector.Name()
+	migrations, err := Load(dialect)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r := &runner{conn: conn, dialect: dialect, migrations: migrations}
+
+	// SQLite needs no lock: every migration runs in a transaction that takes
+	// the database write lock up front (_txlock=immediate).
+	if dialect == "postgres" {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		applied_at timestamp NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(r)
+}
+
+// applied returns the applied versions and when they were applied.
+func (r *runner) applied(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := r.conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+// apply runs one migration up or down in a transaction together with its
+// schema_migrations bookkeeping. It reports false, doing nothing, if another
+// process got there first.
+func (r *runner) apply(ctx context.Context, m Migration, up bool) (bool, error) {
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRowContext(ctx, r.bind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), m.Version).
+		Scan(&count); err != nil {
+		return false, err
+	}
+	if (count > 0) == up {
+		return false, nil
+	}
+
+	script, record, args := m.Down, r.bind("DELETE FROM schema_migrations WHERE version = ?"), []interface{}{m.Version}
+	if up {
+		script = m.Up
+		record = r.bind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)")
+		args = []interface{}{m.Version, m.Name, time.Now().UTC()}
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return false, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return false, fmt.Errorf("failed to record migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	return true, tx.Commit()
+}
+
+// bind rewrites ? placeholders as $1, $2, ... for Postgres.
+func (r *runner) bind(query string) string {
+	if r.dialect != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package migrations_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/migrations"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	os.Exit(m.Run())
+}
+
+// The models of the first release, whose tables AutoMigrate created before
+// there were migrations.
+type baselineUser struct {
+	ID           int64  `gorm:"primaryKey"`
+	Email        string `gorm:"uniqueIndex;size:255;not null"`
+	PasswordHash string `gorm:"size:255;not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineNote struct {
+	ID        int64        `gorm:"primaryKey"`
+	Title     string       `gorm:"size:255;not null"`
+	Content   string       `gorm:"type:text"`
+	UserID    int64        `gorm:"not null;index"`
+	User      baselineUser `gorm:"foreignKey:UserID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (baselineNote) TableName() string { return "notes" }
+
+type baselineRefreshToken struct {
+	ID        int64  `gorm:"primaryKey"`
+	TokenHash string `gorm:"size:255;not null;index"`
+	UserID    int64  `gorm:"index;not null"`
+	ExpiresAt time.Time
+	Revoked   bool `gorm:"default:false"`
+	CreatedAt time.Time
+}
+
+func (baselineRefreshToken) TableName() string { return "refresh_tokens" }
+
+func TestUpAdoptsBaselineSchema(t *testing.T) {
+	testdb.RunEmpty(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		if err := db.AutoMigrate(&baselineUser{}, &baselineNote{}, &baselineRefreshToken{}); err != nil {
+			t.Fatal(err)
+		}
+		user := baselineUser{Email: "alice@example.com", PasswordHash: "x"}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		note := baselineNote{Title: "Kept", Content: "from before", UserID: user.ID}
+		if err := db.Omit("User").Create(&note).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := migrations.Up(ctx, db); err != nil {
+			t.Fatalf("migrate a baseline database: %v", err)
+		}
+		if pending, err := migrations.Pending(ctx, db); err != nil || len(pending) != 0 {
+			t.Fatalf("pending after up = %v, %v", pending, err)
+		}
+
+		// Existing rows get the defaults of the columns added since
+		var migrated models.User
+		if err := db.First(&migrated, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if migrated.Email != user.Email || migrated.Role != models.RoleUser || migrated.Locale != "en" || migrated.DisabledAt != nil {
+			t.Errorf("migrated user = %+v", migrated)
+		}
+		var kept models.Note
+		if err := db.First(&kept, note.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if kept.Title != "Kept" || kept.Color != "default" || kept.Pinned || kept.Position != "" || kept.Revision != 1 {
+			t.Errorf("migrated note = %+v", kept)
+		}
+
+		// The tables added since work with the current models
+		labels, err := database.FindOrCreateLabels(db, user.ID, []string{"old"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&kept).Association("Labels").Append(labels); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestDownRevertsEveryMigration(t *testing.T) {
+	testdb.RunEmpty(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		applied, err := migrations.Up(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reverted, err := migrations.Down(ctx, db, len(applied))
+		if err != nil {
+			t.Fatalf("down: %v", err)
+		}
+		if len(reverted) != len(applied) {
+			t.Fatalf("reverted %d of %d migrations", len(reverted), len(applied))
+		}
+		for _, table := range []string{"users", "notes", "labels", "audit_events"} {
+			if db.Migrator().HasTable(table) {
+				t.Errorf("table %s left after reverting everything", table)
+			}
+		}
+		if _, err := migrations.Up(ctx, db); err != nil {
+			t.Fatalf("up after down: %v", err)
+		}
+	})
+}
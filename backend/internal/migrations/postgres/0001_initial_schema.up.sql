@@ -0,0 +1,34 @@
+-- Baseline: the schema GORM AutoMigrate created before versioned migrations.
+-- IF NOT EXISTS lets databases created that way adopt migrations unchanged;
+-- everything added since comes in later migrations.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    email varchar(255) NOT NULL,
+    password_hash varchar(255) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id bigserial PRIMARY KEY,
+    title varchar(255) NOT NULL,
+    content text,
+    user_id bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_notes_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_notes_user_id ON notes (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigserial PRIMARY KEY,
+    token_hash varchar(255) NOT NULL,
+    user_id bigint NOT NULL,
+    expires_at timestamptz,
+    revoked boolean DEFAULT false,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
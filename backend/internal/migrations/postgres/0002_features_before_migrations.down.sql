@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS rate_limit_lockouts;
+DROP TABLE IF EXISTS rate_limit_buckets;
+DROP TABLE IF EXISTS email_changes;
+DROP TABLE IF EXISTS import_jobs;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS note_labels;
+DROP TABLE IF EXISTS labels;
+
+DROP INDEX IF EXISTS idx_notes_search;
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS position,
+    DROP COLUMN IF EXISTS trashed_at,
+    DROP COLUMN IF EXISTS archived,
+    DROP COLUMN IF EXISTS pinned,
+    DROP COLUMN IF EXISTS color;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS delete_after,
+    DROP COLUMN IF EXISTS pref_note_view,
+    DROP COLUMN IF EXISTS pref_default_note_color,
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS display_name;
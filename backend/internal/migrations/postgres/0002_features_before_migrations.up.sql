@@ -0,0 +1,97 @@
+-- Columns and tables added while AutoMigrate still managed the schema. A
+-- database may have any of them already, so each one is added only if
+-- missing.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name varchar(100),
+    ADD COLUMN IF NOT EXISTS locale varchar(35) NOT NULL DEFAULT 'en',
+    ADD COLUMN IF NOT EXISTS timezone varchar(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN IF NOT EXISTS pref_default_note_color varchar(20) NOT NULL DEFAULT 'default',
+    ADD COLUMN IF NOT EXISTS pref_note_view varchar(10) NOT NULL DEFAULT 'grid',
+    ADD COLUMN IF NOT EXISTS delete_after timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_delete_after ON users (delete_after);
+
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS color varchar(20) NOT NULL DEFAULT 'default',
+    ADD COLUMN IF NOT EXISTS pinned boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS archived boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS trashed_at timestamptz,
+    ADD COLUMN IF NOT EXISTS position varchar(255) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_notes_trashed_at ON notes (trashed_at);
+CREATE INDEX IF NOT EXISTS idx_notes_position ON notes (position);
+CREATE INDEX IF NOT EXISTS idx_notes_search ON notes
+    USING GIN (to_tsvector('simple', title || ' ' || coalesce(content, '')));
+
+CREATE TABLE IF NOT EXISTS labels (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_labels_user_name ON labels (user_id, name);
+
+CREATE TABLE IF NOT EXISTS note_labels (
+    note_id bigint,
+    label_id bigint,
+    PRIMARY KEY (note_id, label_id),
+    CONSTRAINT fk_note_labels_note FOREIGN KEY (note_id) REFERENCES notes (id),
+    CONSTRAINT fk_note_labels_label FOREIGN KEY (label_id) REFERENCES labels (id)
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id bigserial PRIMARY KEY,
+    note_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    filename varchar(255) NOT NULL,
+    mime_type varchar(100) NOT NULL,
+    size bigint,
+    data bytea,
+    created_at timestamptz,
+    CONSTRAINT fk_notes_attachments FOREIGN KEY (note_id) REFERENCES notes (id)
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_note_id ON attachments (note_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments (user_id);
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    source varchar(50) NOT NULL,
+    status varchar(20) NOT NULL,
+    total bigint,
+    processed bigint,
+    imported bigint,
+    failed bigint,
+    error text,
+    report text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    started_at timestamptz,
+    finished_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs (user_id);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs (status);
+
+CREATE TABLE IF NOT EXISTS email_changes (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    new_email varchar(255) NOT NULL,
+    token_hash varchar(255) NOT NULL,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_changes_user_id ON email_changes (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_token_hash ON email_changes (token_hash);
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key varchar(255) PRIMARY KEY,
+    tokens decimal NOT NULL,
+    refilled_at timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_lockouts (
+    key varchar(255) PRIMARY KEY,
+    failures bigint NOT NULL DEFAULT 0,
+    last_failure_at timestamptz NOT NULL,
+    locked_until timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_lockouts_locked_until ON rate_limit_lockouts (locked_until);
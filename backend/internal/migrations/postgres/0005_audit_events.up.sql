@@ -0,0 +1,20 @@
+-- Append-only record of security-relevant and data-changing events. There
+-- are no foreign keys so events outlive the users and notes they mention.
+CREATE TABLE audit_events (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    actor_id bigint,
+    user_id bigint,
+    action varchar(64) NOT NULL,
+    target_type varchar(32),
+    target_id bigint,
+    ip varchar(45),
+    user_agent varchar(255),
+    before text,
+    after text
+);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_user_id ON audit_events (user_id);
+CREATE INDEX idx_audit_events_action ON audit_events (action);
+CREATE INDEX idx_audit_events_target ON audit_events (target_type, target_id);
@@ -0,0 +1,33 @@
+-- Baseline: the tables of the first release, which GORM AutoMigrate created.
+-- Everything added since comes in later migrations.
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    email text NOT NULL,
+    password_hash text NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    title text NOT NULL,
+    content text,
+    user_id integer NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_notes_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_notes_user_id ON notes (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    token_hash text NOT NULL,
+    user_id integer NOT NULL,
+    expires_at datetime,
+    revoked numeric DEFAULT false,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,29 @@
+DROP TABLE IF EXISTS rate_limit_lockouts;
+DROP TABLE IF EXISTS rate_limit_buckets;
+DROP TABLE IF EXISTS email_changes;
+DROP TABLE IF EXISTS import_jobs;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS note_labels;
+DROP TABLE IF EXISTS labels;
+
+DROP TRIGGER IF EXISTS notes_fts_update;
+DROP TRIGGER IF EXISTS notes_fts_delete;
+DROP TRIGGER IF EXISTS notes_fts_insert;
+DROP TABLE IF EXISTS notes_fts;
+
+-- Indexed columns can only be dropped once their index is gone
+DROP INDEX IF EXISTS idx_notes_position;
+DROP INDEX IF EXISTS idx_notes_trashed_at;
+ALTER TABLE notes DROP COLUMN position;
+ALTER TABLE notes DROP COLUMN trashed_at;
+ALTER TABLE notes DROP COLUMN archived;
+ALTER TABLE notes DROP COLUMN pinned;
+ALTER TABLE notes DROP COLUMN color;
+
+DROP INDEX IF EXISTS idx_users_delete_after;
+ALTER TABLE users DROP COLUMN delete_after;
+ALTER TABLE users DROP COLUMN pref_note_view;
+ALTER TABLE users DROP COLUMN pref_default_note_color;
+ALTER TABLE users DROP COLUMN timezone;
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN display_name;
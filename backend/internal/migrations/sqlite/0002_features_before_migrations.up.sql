@@ -0,0 +1,107 @@
+-- Columns and tables added while AutoMigrate still managed the schema.
+-- SQLite can't add a column only if it is missing; the SQLite backend shipped
+-- together with versioned migrations, so its databases start from 0001.
+
+ALTER TABLE users ADD COLUMN display_name text;
+ALTER TABLE users ADD COLUMN locale text NOT NULL DEFAULT 'en';
+ALTER TABLE users ADD COLUMN timezone text NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN pref_default_note_color text NOT NULL DEFAULT 'default';
+ALTER TABLE users ADD COLUMN pref_note_view text NOT NULL DEFAULT 'grid';
+ALTER TABLE users ADD COLUMN delete_after datetime;
+CREATE INDEX IF NOT EXISTS idx_users_delete_after ON users (delete_after);
+
+ALTER TABLE notes ADD COLUMN color text NOT NULL DEFAULT 'default';
+ALTER TABLE notes ADD COLUMN pinned numeric NOT NULL DEFAULT false;
+ALTER TABLE notes ADD COLUMN archived numeric NOT NULL DEFAULT false;
+ALTER TABLE notes ADD COLUMN trashed_at datetime;
+ALTER TABLE notes ADD COLUMN position text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_notes_trashed_at ON notes (trashed_at);
+CREATE INDEX IF NOT EXISTS idx_notes_position ON notes (position);
+
+-- Full-text search over title and content, kept in sync with notes by triggers
+CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(title, content, content='notes', content_rowid='id');
+CREATE TRIGGER IF NOT EXISTS notes_fts_insert AFTER INSERT ON notes BEGIN
+    INSERT INTO notes_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+CREATE TRIGGER IF NOT EXISTS notes_fts_delete AFTER DELETE ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+END;
+CREATE TRIGGER IF NOT EXISTS notes_fts_update AFTER UPDATE OF title, content ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+    INSERT INTO notes_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+INSERT INTO notes_fts (notes_fts) VALUES ('rebuild');
+
+CREATE TABLE IF NOT EXISTS labels (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    name text NOT NULL,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_labels_user_name ON labels (user_id, name);
+
+CREATE TABLE IF NOT EXISTS note_labels (
+    note_id integer,
+    label_id integer,
+    PRIMARY KEY (note_id, label_id),
+    CONSTRAINT fk_note_labels_note FOREIGN KEY (note_id) REFERENCES notes (id),
+    CONSTRAINT fk_note_labels_label FOREIGN KEY (label_id) REFERENCES labels (id)
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    note_id integer NOT NULL,
+    user_id integer NOT NULL,
+    filename text NOT NULL,
+    mime_type text NOT NULL,
+    size integer,
+    data blob,
+    created_at datetime,
+    CONSTRAINT fk_notes_attachments FOREIGN KEY (note_id) REFERENCES notes (id)
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_note_id ON attachments (note_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments (user_id);
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    source text NOT NULL,
+    status text NOT NULL,
+    total integer,
+    processed integer,
+    imported integer,
+    failed integer,
+    error text,
+    report text,
+    created_at datetime,
+    updated_at datetime,
+    started_at datetime,
+    finished_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs (user_id);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs (status);
+
+CREATE TABLE IF NOT EXISTS email_changes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    new_email text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_email_changes_user_id ON email_changes (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_token_hash ON email_changes (token_hash);
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key text PRIMARY KEY,
+    tokens real NOT NULL,
+    refilled_at datetime NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_lockouts (
+    key text PRIMARY KEY,
+    failures integer NOT NULL DEFAULT 0,
+    last_failure_at datetime NOT NULL,
+    locked_until datetime
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_lockouts_locked_until ON rate_limit_lockouts (locked_until);
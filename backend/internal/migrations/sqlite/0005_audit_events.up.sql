@@ -0,0 +1,20 @@
+-- Append-only record of security-relevant and data-changing events. There
+-- are no foreign keys so events outlive the users and notes they mention.
+CREATE TABLE audit_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    actor_id integer,
+    user_id integer,
+    action text NOT NULL,
+    target_type text,
+    target_id integer,
+    ip text,
+    user_agent text,
+    before text,
+    after text
+);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_user_id ON audit_events (user_id);
+CREATE INDEX idx_audit_events_action ON audit_events (action);
+CREATE INDEX idx_audit_events_target ON audit_events (target_type, target_id);
@@ -0,0 +1 @@
+ALTER TABLE notes ADD COLUMN revision integer NOT NULL DEFAULT 1;
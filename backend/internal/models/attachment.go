@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Attachment is a file attached to a note. The content is stored in the
+// database and excluded from JSON; it is served by its own endpoint.
+type Attachment struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	NoteID    int64     `json:"note_id" gorm:"not null;index"`
+	UserID    int64     `json:"-" gorm:"not null;index"`
+	Filename  string    `json:"filename" gorm:"size:255;not null"`
+	MimeType  string    `json:"mime_type" gorm:"size:100;not null"`
+	Size      int64     `json:"size"`
+	Data      []byte    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
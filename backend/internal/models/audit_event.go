@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Audit event target types.
+const (
+	AuditTargetUser = "user"
+	AuditTargetNote = "note"
+)
+
+// AuditEvent records a security-relevant or data-changing event. Events are
+// only ever inserted. Before and After summarize the target around a change;
+// note contents are never copied into them.
+type AuditEvent struct {
+	ID         int64                  `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"not null;index"`
+	ActorID    *int64                 `json:"actor_id,omitempty" gorm:"index"` // nil when nobody is logged in
+	UserID     *int64                 `json:"user_id,omitempty" gorm:"index"`  // account the event concerns
+	Action     string                 `json:"action" gorm:"size:64;not null;index"`
+	TargetType string                 `json:"target_type,omitempty" gorm:"size:32"`
+	TargetID   *int64                 `json:"target_id,omitempty"`
+	IP         string                 `json:"ip,omitempty" gorm:"size:45"`
+	UserAgent  string                 `json:"user_agent,omitempty" gorm:"size:255"`
+	Before     map[string]interface{} `json:"before,omitempty" gorm:"type:text;serializer:json"`
+	After      map[string]interface{} `json:"after,omitempty" gorm:"type:text;serializer:json"`
+}
@@ -0,0 +1,57 @@
+package models
+
+// Batch note operations.
+const (
+	BatchArchive      = "archive"
+	BatchUnarchive    = "unarchive"
+	BatchPin          = "pin"
+	BatchUnpin        = "unpin"
+	BatchTrash        = "trash"
+	BatchRestore      = "restore"
+	BatchDelete       = "delete"
+	BatchColor        = "color"
+	BatchAddLabels    = "add_labels"
+	BatchRemoveLabels = "remove_labels"
+)
+
+// Batch failure modes.
+const (
+	// BatchModeAtomic rolls back every change when any note fails.
+	BatchModeAtomic = "atomic"
+	// BatchModePartial keeps the successful changes and reports the failures.
+	BatchModePartial = "partial"
+)
+
+// Per-note batch outcomes.
+const (
+	BatchResultOK         = "ok"
+	BatchResultNotFound   = "not_found"
+	BatchResultFailed     = "failed"
+	BatchResultRolledBack = "rolled_back"
+)
+
+// BatchNotesRequest applies one operation to up to 500 notes.
+type BatchNotesRequest struct {
+	IDs       []int64  `json:"ids" binding:"required,min=1,max=500,dive,gt=0"`
+	Operation string   `json:"operation" binding:"required,oneof=archive unarchive pin unpin trash restore delete color add_labels remove_labels"`
+	Color     string   `json:"color" binding:"omitempty,note_color"`          // required for "color"
+	Labels    []string `json:"labels" binding:"omitempty,dive,min=1,max=100"` // required for "add_labels"/"remove_labels"
+	Mode      string   `json:"mode" binding:"omitempty,oneof=atomic partial"`
+}
+
+// BatchNoteResult is the outcome of a batch operation for one note.
+type BatchNoteResult struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchNotesResponse reports the per-note results of a batch request.
+type BatchNotesResponse struct {
+	Operation  string            `json:"operation"`
+	Mode       string            `json:"mode"`
+	Succeeded  int               `json:"succeeded"`
+	Failed     int               `json:"failed"`
+	RolledBack bool              `json:"rolled_back"`
+	Results    []BatchNoteResult `json:"results"`
+}
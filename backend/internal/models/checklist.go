@@ -0,0 +1,29 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ChecklistItem is an entry of a note's checklist. Checklists are stored in
+// the note content as Markdown task list items ("- [ ] milk", "- [x] eggs"),
+// which is also how the Google Keep import writes them.
+type ChecklistItem struct {
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+}
+
+var taskListItem = regexp.MustCompile(`^\s*[-*+] \[([ xX])\](?:\s+(.*))?$`)
+
+// ParseChecklist returns the task list items in content, in order.
+func ParseChecklist(content string) []ChecklistItem {
+	items := []ChecklistItem{}
+	for _, line := range strings.Split(content, "\n") {
+		m := taskListItem.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		items = append(items, ChecklistItem{Text: strings.TrimSpace(m[2]), Checked: m[1] != " "})
+	}
+	return items
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// EmailChange is a pending change of a user's email address. Like refresh
+// tokens, only a hash of the confirmation token is stored.
+type EmailChange struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	UserID    int64     `json:"user_id" gorm:"not null;index"`
+	NewEmail  string    `json:"new_email" gorm:"size:255;not null"`
+	TokenHash string    `json:"-" gorm:"size:255;not null;uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Import job statuses.
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// Per-note import outcomes.
+const (
+	ImportNoteImported = "imported"
+	ImportNoteSkipped  = "skipped"
+	ImportNoteFailed   = "failed"
+)
+
+// ImportJob tracks a background import of an uploaded archive.
+type ImportJob struct {
+	ID         int64              `json:"id" gorm:"primaryKey"`
+	UserID     int64              `json:"-" gorm:"not null;index"`
+	Source     string             `json:"source" gorm:"size:50;not null"`
+	Status     string             `json:"status" gorm:"size:20;not null;index"`
+	Total      int                `json:"total"`
+	Processed  int                `json:"processed"`
+	Imported   int                `json:"imported"`
+	Failed     int                `json:"failed"`
+	Error      string             `json:"error,omitempty" gorm:"type:text"`
+	Report     []ImportNoteResult `json:"report,omitempty" gorm:"type:text;serializer:json"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+// ImportNoteResult is the outcome for one note of an import.
+type ImportNoteResult struct {
+	File     string   `json:"file"`
+	Title    string   `json:"title,omitempty"`
+	Status   string   `json:"status"`
+	NoteID   int64    `json:"note_id,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Label is a user-defined tag; a note can carry several labels.
+type Label struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	UserID    int64     `json:"-" gorm:"not null;uniqueIndex:idx_labels_user_name"`
+	Name      string    `json:"name" gorm:"size:100;not null;uniqueIndex:idx_labels_user_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -2,19 +2,58 @@ package models
 
 import "time"
 
+// NoteColors lists the note background colors, named after Google Keep's palette.
+var NoteColors = []string{
+	"default", "red", "orange", "yellow", "green", "teal",
+	"blue", "cerulean", "purple", "pink", "brown", "gray",
+}
+
+// IsNoteColor reports whether c is one of NoteColors.
+func IsNoteColor(c string) bool {
+	for _, color := range NoteColors {
+		if c == color {
+			return true
+		}
+	}
+	return false
+}
+
 type Note struct {
-	ID        int64     `json:"id" gorm:"primaryKey"`
-	Title     string    `json:"title" gorm:"size:255;not null"`
-	Content   string    `json:"content" gorm:"type:text"`
-	UserID    int64     `json:"user_id" gorm:"not null;index"`
-	User      User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64        `json:"id" gorm:"primaryKey"`
+	Title       string       `json:"title" gorm:"size:255;not null"`
+	Content     string       `json:"content" gorm:"type:text"`
+	Color       string       `json:"color" gorm:"size:20;not null;default:'default'"`
+	Pinned      bool         `json:"pinned" gorm:"not null;default:false"`
+	Archived    bool         `json:"archived" gorm:"not null;default:false"`
+	TrashedAt   *time.Time   `json:"trashed_at,omitempty" gorm:"index"`
+	Position    string       `json:"position" gorm:"size:255;not null;default:'';index"`
+	Revision    int          `json:"-" gorm:"not null;default:1"` // 1 when created, +1 per edit
+	UserID      int64        `json:"user_id" gorm:"not null;index"`
+	User        User         `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Labels      []Label      `json:"labels,omitempty" gorm:"many2many:note_labels"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	// Only set when asked for with expand
+	RevisionCount  *int            `json:"revision_count,omitempty" gorm:"-"`
+	ChecklistItems []ChecklistItem `json:"checklist_items,omitempty" gorm:"-"`
 }
 
 type CreateNoteRequest struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content"`
+	Title    string   `json:"title" binding:"required"`
+	Content  string   `json:"content"`
+	Color    string   `json:"color" binding:"omitempty,note_color"`
+	Pinned   bool     `json:"pinned"`
+	Archived bool     `json:"archived"`
+	Labels   []string `json:"labels" binding:"omitempty,dive,max=100"`
+}
+
+// MoveNoteRequest places a note in the manual order, after the note with ID
+// After and/or before the note with ID Before.
+type MoveNoteRequest struct {
+	After  *int64 `json:"after" binding:"omitempty,gt=0"`
+	Before *int64 `json:"before" binding:"omitempty,gt=0"`
 }
 
 type UpdateNoteRequest struct {
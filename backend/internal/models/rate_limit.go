@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// RateLimitBucket persists token bucket state so limits hold across instances.
+type RateLimitBucket struct {
+	Key        string    `gorm:"primaryKey;size:255"`
+	Tokens     float64   `gorm:"not null"`
+	RefilledAt time.Time `gorm:"not null"`
+}
+
+// RateLimitLockout tracks consecutive failures for a key (e.g. a login email)
+// and the time until which further attempts are refused.
+type RateLimitLockout struct {
+	Key           string    `gorm:"primaryKey;size:255"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null"`
+	LockedUntil   time.Time `gorm:"index"`
+}
@@ -6,8 +6,46 @@ type User struct {
 	ID           int64     `json:"id" gorm:"primaryKey"`
 	Email        string    `json:"email" gorm:"uniqueIndex;size:255;not null"`
 	PasswordHash string    `json:"-" gorm:"size:255;not null"`
+	Role         string    `json:"role" gorm:"size:20;not null;default:'user'"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Profile
+	DisplayName string          `json:"display_name" gorm:"size:100"`
+	Locale      string          `json:"locale" gorm:"size:35;not null;default:'en'"`
+	Timezone    string          `json:"timezone" gorm:"size:64;not null;default:'UTC'"`
+	Preferences UserPreferences `json:"preferences" gorm:"embedded;embeddedPrefix:pref_"`
+
+	// DeleteAfter is set when the user asked for account deletion; the
+	// account and its data are purged once this time has passed.
+	DeleteAfter *time.Time `json:"delete_after,omitempty" gorm:"index"`
+
+	// DisabledAt is set when an operator disabled the account; disabled
+	// users can't log in or refresh their session.
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// User roles. Admins can use the /api/admin endpoints.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// IsRole reports whether r is a known role.
+func IsRole(r string) bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
+// Note view modes for the notes overview.
+const (
+	NoteViewGrid = "grid"
+	NoteViewList = "list"
+)
+
+// UserPreferences holds UI settings that follow the user across devices.
+type UserPreferences struct {
+	DefaultNoteColor string `json:"default_note_color" gorm:"size:20;not null;default:'default'"`
+	NoteView         string `json:"note_view" gorm:"size:10;not null;default:'grid'"`
 }
 
 type RegisterRequest struct {
@@ -20,21 +58,76 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// DeleteAccountRequest re-confirms the password before scheduling deletion.
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UpdateProfileRequest is a partial update of the profile; omitted fields are left unchanged.
+type UpdateProfileRequest struct {
+	DisplayName *string                   `json:"display_name" binding:"omitempty,max=100"`
+	Locale      *string                   `json:"locale" binding:"omitempty,max=35"`
+	Timezone    *string                   `json:"timezone" binding:"omitempty,max=64"`
+	Preferences *UpdatePreferencesRequest `json:"preferences"`
+}
+
+// UpdatePreferencesRequest is a partial update of UserPreferences.
+type UpdatePreferencesRequest struct {
+	DefaultNoteColor *string `json:"default_note_color" binding:"omitempty,note_color"`
+	NoteView         *string `json:"note_view" binding:"omitempty,oneof=grid list"`
+}
+
+// ChangePasswordRequest changes the password after checking the current one.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest starts an email change; the new address must be confirmed.
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ConfirmEmailRequest completes an email change with the token sent to the new address.
+type ConfirmEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SetRoleRequest changes the role of a user (admin only).
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
 // UserDTO represents a user data transfer object without sensitive information
 type UserDTO struct {
-	ID        int64     `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64           `json:"id"`
+	Email       string          `json:"email"`
+	Role        string          `json:"role"`
+	DisplayName string          `json:"display_name"`
+	Locale      string          `json:"locale"`
+	Timezone    string          `json:"timezone"`
+	Preferences UserPreferences `json:"preferences"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	DeleteAfter *time.Time      `json:"delete_after,omitempty"`
+	DisabledAt  *time.Time      `json:"disabled_at,omitempty"`
 }
 
 // ToDTO converts a User model to UserDTO
 func (u *User) ToDTO() UserDTO {
 	return UserDTO{
-		ID:        u.ID,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:          u.ID,
+		Email:       u.Email,
+		Role:        u.Role,
+		DisplayName: u.DisplayName,
+		Locale:      u.Locale,
+		Timezone:    u.Timezone,
+		Preferences: u.Preferences,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		DeleteAfter: u.DeleteAfter,
+		DisabledAt:  u.DisabledAt,
 	}
 }
 
@@ -0,0 +1,132 @@
+// Package openapi serves the OpenAPI document of the API, which is written
+// by hand in openapi.yaml, and checks it against the routes of the router.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+)
+
+//go:embed openapi.yaml
+var source []byte
+
+// document is the OpenAPI document as JSON, converted once at startup so a
+// broken openapi.yaml fails every command rather than the first request.
+var document = mustJSON(source)
+
+func mustJSON(yamlDoc []byte) []byte {
+	doc, err := yaml.YAMLToJSON(yamlDoc)
+	if err != nil {
+		panic(fmt.Sprintf("openapi: invalid openapi.yaml: %v", err))
+	}
+	return doc
+}
+
+// JSON returns the OpenAPI document.
+func JSON() []byte {
+	return document
+}
+
+// Spec serves the OpenAPI document.
+func Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", document)
+}
+
+// docsPage renders the document with Swagger UI, loaded from a CDN.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Keep clone API</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// Docs serves the interactive documentation of the API.
+func Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// methods are the keys of a path item that are operations.
+var methods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// Operations returns the operations of the document as "METHOD /path",
+// sorted, with path parameters in OpenAPI form such as /api/notes/{id}.
+func Operations() ([]string, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+	var ops []string
+	for path, item := range doc.Paths {
+		for key := range item {
+			if methods[key] {
+				ops = append(ops, strings.ToUpper(key)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops, nil
+}
+
+// Compare returns the routes that the document doesn't describe and the
+// operations of the document that no route serves, both sorted.
+func Compare(routes gin.RoutesInfo) (missing, stale []string, err error) {
+	ops, err := Operations()
+	if err != nil {
+		return nil, nil, err
+	}
+	documented := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		documented[op] = true
+	}
+
+	served := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		op := route.Method + " " + Path(route.Path)
+		served[op] = true
+		if !documented[op] {
+			missing = append(missing, op)
+		}
+	}
+	for _, op := range ops {
+		if !served[op] {
+			stale = append(stale, op)
+		}
+	}
+	sort.Strings(missing)
+	return missing, stale, nil
+}
+
+// Path converts a gin route path to OpenAPI form: /notes/:id becomes
+// /notes/{id} and /files/*path becomes /files/{path}.
+func Path(route string) string {
+	segments := strings.Split(route, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
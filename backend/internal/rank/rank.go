@@ -0,0 +1,119 @@
+// Package rank generates lexicographic sort keys for user-defined ordering.
+//
+// Keys are strings over [0-9a-z] compared byte by byte. A key can always be
+// generated between two others, so moving an item only rewrites that item's
+// key. Keys grow by roughly one character every five moves into the same
+// gap; Spread produces short, evenly spaced keys to start over.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// ErrInvalidRange is returned by Between when the bounds aren't valid,
+// ordered keys (e.g. two items share the same key).
+var ErrInvalidRange = errors.New("rank: bounds are not valid ordered keys")
+
+// Between returns a key that sorts after a and before b. An empty a means
+// "before everything" and an empty b means "after everything".
+func Between(a, b string) (string, error) {
+	if !Valid(a) || !Valid(b) || (a != "" && b != "" && a >= b) {
+		return "", ErrInvalidRange
+	}
+	return midpoint(a, b), nil
+}
+
+// midpoint follows the fractional indexing algorithm: keys are read as
+// base-36 fractions, so "" behaves like 0 for a and like 1 for b.
+func midpoint(a, b string) string {
+	if b != "" {
+		// Keep the common prefix, treating a as padded with zeros
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	da := 0
+	if a != "" {
+		da = strings.IndexByte(digits, a[0])
+	}
+	db := base
+	if b != "" {
+		db = strings.IndexByte(digits, b[0])
+	}
+	if db-da > 1 {
+		return string(digits[(da+db+1)/2])
+	}
+
+	// The first digits are adjacent
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[da]) + midpoint(rest, "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+// Valid reports whether s is empty or a key Between can work with. Keys may
+// not end in the zero digit, otherwise nothing would fit right before them.
+func Valid(s string) bool {
+	if s == "" {
+		return true
+	}
+	if s[len(s)-1] == digits[0] {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(digits, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Spread returns n ascending keys spaced evenly over the key space, using as
+// few characters as possible while leaving room between neighbours.
+func Spread(n int) []string {
+	width := 1
+	space := base
+	for space < (n+1)*base {
+		width++
+		space *= base
+	}
+	step := space / (n + 1)
+
+	keys := make([]string, n)
+	buf := make([]byte, width)
+	for i := range keys {
+		v := (i + 1) * step
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = digits[v%base]
+			v /= base
+		}
+		// Trailing zeros carry no value and would make the key invalid
+		keys[i] = strings.TrimRight(string(buf), digits[:1])
+	}
+	return keys
+}
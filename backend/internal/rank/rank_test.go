@@ -0,0 +1,120 @@
+package rank
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	for _, tt := range []struct {
+		a, b, want string
+	}{
+		{"", "", "i"},
+		{"", "1", "0i"},
+		{"z", "", "zi"},
+		{"zz", "", "zzi"},
+		{"1", "3", "2"},
+
+		// Adjacent digits
+		{"1", "2", "1i"},
+		{"a", "b", "ai"},
+		{"az", "b", "azi"},
+		{"a1", "b", "aj"},
+
+		// Common prefix
+		{"ab", "ac", "abi"},
+		{"abc", "abd", "abci"},
+
+		// a is a prefix of b
+		{"a", "ab", "a6"},
+		{"a", "a1", "a0i"},
+
+		// Keys starting with the zero digit
+		{"01", "02", "01i"},
+		{"", "01", "00i"},
+		{"", "001", "000i"},
+	} {
+		got, err := Between(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("Between(%q, %q) = %q, %v, want %q", tt.a, tt.b, got, err, tt.want)
+			continue
+		}
+		checkBetween(t, tt.a, tt.b, got)
+	}
+}
+
+func TestBetweenInvalidRange(t *testing.T) {
+	for _, tt := range []struct{ a, b string }{
+		{"a", "a"},
+		{"b", "a"},
+		{"0", "1"},
+		{"a0", "b"},
+		{"a", "b0"},
+		{"A", "b"},
+		{"a", "b-"},
+	} {
+		if got, err := Between(tt.a, tt.b); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("Between(%q, %q) = %q, %v, want ErrInvalidRange", tt.a, tt.b, got, err)
+		}
+	}
+}
+
+// Repeatedly moving items into the same gap, from either side, always
+// yields a valid key strictly between the bounds.
+func TestBetweenRepeated(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for run := 0; run < 20; run++ {
+		a, b := "", ""
+		for i := 0; i < 200; i++ {
+			key, err := Between(a, b)
+			if err != nil {
+				t.Fatalf("Between(%q, %q): %v", a, b, err)
+			}
+			checkBetween(t, a, b, key)
+			if rnd.Intn(2) == 0 {
+				a = key
+			} else {
+				b = key
+			}
+		}
+	}
+}
+
+func checkBetween(t *testing.T, a, b, key string) {
+	t.Helper()
+	if !Valid(key) {
+		t.Errorf("Between(%q, %q) = %q, which is not a valid key", a, b, key)
+	}
+	if key <= a || (b != "" && key >= b) {
+		t.Errorf("Between(%q, %q) = %q, which is not between the bounds", a, b, key)
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{0, 1, 35, 36, 1000} {
+		keys := Spread(n)
+		if len(keys) != n {
+			t.Errorf("Spread(%d) returned %d keys", n, len(keys))
+			continue
+		}
+		for i, key := range keys {
+			if key == "" || !Valid(key) {
+				t.Errorf("Spread(%d)[%d] = %q, which is not a valid key", n, i, key)
+			}
+			if i > 0 && keys[i-1] >= key {
+				t.Errorf("Spread(%d)[%d] = %q, not after %q", n, i, key, keys[i-1])
+			}
+		}
+	}
+
+	// The keys are as short as possible
+	for n, width := range map[int]int{35: 1, 36: 2, 1000: 3} {
+		for _, key := range Spread(n) {
+			if len(key) > width {
+				t.Errorf("Spread(%d) has key %q, longer than %d", n, key, width)
+				break
+			}
+		}
+	}
+}
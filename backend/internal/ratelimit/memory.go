@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval controls how often idle entries are evicted from MemoryStore.
+const sweepInterval = 10 * time.Minute
+
+type memoryBucket struct {
+	tokens     float64
+	refilledAt time.Time
+	resetAt    time.Time
+}
+
+// memoryLockout is a failure history with the window of the policy that
+// recorded it, which decides when the history may be dropped.
+type memoryLockout struct {
+	state  LockoutState
+	window time.Duration
+}
+
+// MemoryStore keeps rate limit state in process memory. Limits are only
+// enforced per instance; use PostgresStore when running several replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lockouts  map[string]memoryLockout
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*memoryBucket),
+		lockouts: make(map[string]memoryLockout),
+		now:      time.Now,
+	}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Requests), refilledAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, res := takeToken(b.tokens, b.refilledAt, limit, now)
+	b.tokens = tokens
+	b.refilledAt = now
+	b.resetAt = res.ResetAt
+	return res, nil
+}
+
+// Lockout implements Store.
+func (s *MemoryStore) Lockout(_ context.Context, key string) (LockoutState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lockouts[key].state, nil
+}
+
+// RecordFailure implements Store.
+func (s *MemoryStore) RecordFailure(_ context.Context, key string, policy LockoutPolicy) (LockoutState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := nextLockout(s.lockouts[key].state, policy, s.now())
+	s.lockouts[key] = memoryLockout{state: state, window: policy.Window}
+	return state, nil
+}
+
+// ResetFailures implements Store.
+func (s *MemoryStore) ResetFailures(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.lockouts, key)
+	return nil
+}
+
+// sweep drops buckets that have fully refilled and failure histories that
+// are no longer locked and older than their window, after which
+// nextLockout would forget them anyway, so the maps don't grow with every
+// IP ever seen. Histories without a window are kept. Callers must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !b.resetAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, l := range s.lockouts {
+		if !l.state.Locked(now) && l.window > 0 && now.Sub(l.state.LastFailureAt) > l.window {
+			delete(s.lockouts, key)
+		}
+	}
+}
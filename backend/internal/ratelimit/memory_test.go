@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreKeepsFailuresForTheWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	policy := LockoutPolicy{Threshold: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	for i := 0; i < 2; i++ {
+		if _, err := s.RecordFailure(ctx, "login:alice", policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Past the sweep interval, but within the window: the failures count
+	now = now.Add(30 * time.Minute)
+	if _, err := s.Take(ctx, "sweep", PerMinute(10)); err != nil {
+		t.Fatal(err)
+	}
+	state, err := s.RecordFailure(ctx, "login:alice", policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Failures != 3 || !state.Locked(now) {
+		t.Fatalf("third failure within the window: got %+v, want 3 failures and locked", state)
+	}
+
+	// Past the lockout and the window: the history is swept
+	now = now.Add(2 * time.Hour)
+	if _, err := s.Take(ctx, "sweep", PerMinute(10)); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := s.Lockout(ctx, "login:alice"); state.Failures != 0 {
+		t.Fatalf("failures after the window: got %d, want 0", state.Failures)
+	}
+}
+
+func TestMemoryStoreLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	policy := LockoutPolicy{Threshold: 2, BaseDelay: time.Minute, MaxDelay: 10 * time.Minute, Window: time.Hour}
+	var state LockoutState
+	for i := 0; i < 4; i++ {
+		var err error
+		if state, err = s.RecordFailure(ctx, "login:bob", policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Threshold 2, then doubled twice
+	if want := now.Add(4 * time.Minute); !state.LockedUntil.Equal(want) {
+		t.Fatalf("locked until %v, want %v", state.LockedUntil, want)
+	}
+
+	if err := s.ResetFailures(ctx, "login:bob"); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := s.Lockout(ctx, "login:bob"); state.Locked(now) {
+		t.Fatal("still locked after ResetFailures")
+	}
+}
@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+)
+
+// maxAccountBodyBytes bounds how much of the request body is buffered to find the account email.
+const maxAccountBodyBytes = 64 << 10
+
+// Rule configures rate limiting for a single route.
+type Rule struct {
+	// Name prefixes the bucket keys, so each route has its own buckets.
+	Name string
+	// PerIP limits requests per client IP.
+	PerIP Limit
+	// PerAccount limits requests per account, identified by the "email"
+	// field of the JSON request body.
+	PerAccount Limit
+	// Lockout, when enabled, locks an account after repeated 401 responses
+	// and clears the failures after a successful response.
+	Lockout LockoutPolicy
+}
+
+// Limiter applies rules against a Store.
+type Limiter struct {
+	store Store
+}
+
+// New creates a Limiter backed by store.
+func New(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Middleware returns a Gin middleware enforcing rule. If the store fails the
+// request is let through: an outage of the limiter must not lock everybody out.
+func (l *Limiter) Middleware(rule Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"middleware": "RateLimit",
+			"rule":       rule.Name,
+			"ip":         c.ClientIP(),
+		})
+		ctx := c.Request.Context()
+
+		var account string
+		if rule.PerAccount.Enabled() || rule.Lockout.Enabled() {
+			account = accountFromBody(c)
+		}
+		accountKey := AccountKey(rule.Name, account)
+
+		// Refuse locked accounts before spending any tokens (or a bcrypt compare)
+		if account != "" && rule.Lockout.Enabled() {
+			state, err := l.store.Lockout(ctx, accountKey)
+			if err != nil {
+				log.WithError(err).Error("Failed to read lockout state")
+			} else if now := time.Now(); state.Locked(now) {
+				retryAfter := state.LockedUntil.Sub(now)
+				log.WithField("locked_until", state.LockedUntil).Warn("Request refused: account locked")
+				setRetryAfter(c, retryAfter)
+				apierror.Abort(c, apierror.New(http.StatusTooManyRequests, apierror.CodeAccountLocked, "Too many failed attempts, try again later"))
+				return
+			}
+		}
+
+		var results []Result
+		if rule.PerIP.Enabled() {
+			res, err := l.store.Take(ctx, rule.Name+":ip:"+c.ClientIP(), rule.PerIP)
+			if err != nil {
+				log.WithError(err).Error("Failed to apply per-IP rate limit")
+			} else {
+				results = append(results, res)
+			}
+		}
+		if account != "" && rule.PerAccount.Enabled() {
+			res, err := l.store.Take(ctx, accountKey, rule.PerAccount)
+			if err != nil {
+				log.WithError(err).Error("Failed to apply per-account rate limit")
+			} else {
+				results = append(results, res)
+			}
+		}
+
+		if len(results) > 0 {
+			res := mostRestrictive(results)
+			setLimitHeaders(c, res)
+			if !res.Allowed {
+				log.Warn("Request refused: rate limit exceeded")
+				setRetryAfter(c, res.RetryAfter)
+				apierror.Abort(c, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "Too many requests, try again later"))
+				return
+			}
+		}
+
+		c.Next()
+
+		if account == "" || !rule.Lockout.Enabled() {
+			return
+		}
+
+		switch status := c.Writer.Status(); {
+		case status == http.StatusUnauthorized:
+			state, err := l.store.RecordFailure(ctx, accountKey, rule.Lockout)
+			if err != nil {
+				log.WithError(err).Error("Failed to record failed attempt")
+				return
+			}
+			if state.Locked(time.Now()) {
+				log.WithFields(logrus.Fields{
+					"failures":     state.Failures,
+					"locked_until": state.LockedUntil,
+				}).Warn("Account locked after repeated failures")
+			}
+		case status < 300:
+			if err := l.store.ResetFailures(ctx, accountKey); err != nil {
+				log.WithError(err).Error("Failed to reset failed attempts")
+			}
+		}
+	}
+}
+
+// AccountKey returns the key of the per-account bucket and lockout of the
+// account with the given email under the named rule. The email is used as
+// sent: logins look accounts up by their exact email, so spelling it
+// differently doesn't reach, or lock, the account.
+func AccountKey(rule, email string) string {
+	return rule + ":account:" + email
+}
+
+// accountFromBody reads the email field from a JSON body and restores the
+// body so the handler can bind it again.
+func accountFromBody(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAccountBodyBytes))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.Email
+}
+
+// mostRestrictive picks the result that should be reported to the client:
+// a refusal if there is one, otherwise the bucket with the fewest tokens left.
+func mostRestrictive(results []Result) Result {
+	best := results[0]
+	for _, r := range results[1:] {
+		switch {
+		case !r.Allowed && (best.Allowed || r.RetryAfter > best.RetryAfter):
+			best = r
+		case r.Allowed == best.Allowed && r.Allowed && r.Remaining < best.Remaining:
+			best = r
+		}
+	}
+	return best
+}
+
+func setLimitHeaders(c *gin.Context, res Result) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(res.ResetAt.Unix(), 10))
+}
+
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore keeps rate limit state in the database so limits are shared
+// by every API instance. Each operation runs in its own transaction and locks
+// the affected row, which keeps concurrent requests for one key consistent.
+type PostgresStore struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewPostgresStore creates a store backed by the rate_limit_buckets and
+// rate_limit_lockouts tables.
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db, now: time.Now}
+}
+
+// Take implements Store.
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var res Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := s.now()
+
+		// Make sure the row exists so it can be locked below
+		bucket := models.RateLimitBucket{Key: key, Tokens: float64(limit.Requests), RefilledAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).First(&bucket).Error; err != nil {
+			return err
+		}
+
+		var tokens float64
+		tokens, res = takeToken(bucket.Tokens, bucket.RefilledAt, limit, now)
+
+		return tx.Model(&models.RateLimitBucket{}).Where("key = ?", key).
+			Updates(map[string]interface{}{"tokens": tokens, "refilled_at": now}).Error
+	})
+	return res, err
+}
+
+// Lockout implements Store.
+func (s *PostgresStore) Lockout(ctx context.Context, key string) (LockoutState, error) {
+	var row models.RateLimitLockout
+	err := s.db.WithContext(ctx).Where("key = ?", key).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return LockoutState{}, nil
+	}
+	if err != nil {
+		return LockoutState{}, err
+	}
+	return lockoutFromRow(row), nil
+}
+
+// RecordFailure implements Store.
+func (s *PostgresStore) RecordFailure(ctx context.Context, key string, policy LockoutPolicy) (LockoutState, error) {
+	var state LockoutState
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row := models.RateLimitLockout{Key: key, LastFailureAt: s.now()}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+
+		// A freshly inserted row has no failures yet; drop the placeholder timestamp
+		current := lockoutFromRow(row)
+		if current.Failures == 0 {
+			current.LastFailureAt = time.Time{}
+		}
+
+		state = nextLockout(current, policy, s.now())
+		return tx.Model(&models.RateLimitLockout{}).Where("key = ?", key).
+			Updates(map[string]interface{}{
+				"failures":        state.Failures,
+				"last_failure_at": state.LastFailureAt,
+				"locked_until":    state.LockedUntil,
+			}).Error
+	})
+	return state, err
+}
+
+// ResetFailures implements Store.
+func (s *PostgresStore) ResetFailures(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("key = ?", key).Delete(&models.RateLimitLockout{}).Error
+}
+
+// Cleanup removes buckets idle for longer than maxIdle and expired lockouts.
+func (s *PostgresStore) Cleanup(ctx context.Context, maxIdle time.Duration) error {
+	cutoff := s.now().Add(-maxIdle)
+	db := s.db.WithContext(ctx)
+	if err := db.Where("refilled_at < ?", cutoff).Delete(&models.RateLimitBucket{}).Error; err != nil {
+		return err
+	}
+	return db.Where("locked_until < ? AND last_failure_at < ?", s.now(), cutoff).
+		Delete(&models.RateLimitLockout{}).Error
+}
+
+func lockoutFromRow(row models.RateLimitLockout) LockoutState {
+	return LockoutState{
+		Failures:      row.Failures,
+		LastFailureAt: row.LastFailureAt,
+		LockedUntil:   row.LockedUntil,
+	}
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: Requests tokens are available at once and
+// the bucket refills at Requests per Per.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// PerMinute returns a Limit allowing n requests per minute.
+func PerMinute(n int) Limit {
+	return Limit{Requests: n, Per: time.Minute}
+}
+
+// Enabled reports whether the limit should be enforced.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+func (l Limit) ratePerSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAt    time.Time     // when the bucket will be full again
+	RetryAfter time.Duration // zero when Allowed
+}
+
+// LockoutPolicy configures progressive lockout after repeated failures.
+type LockoutPolicy struct {
+	Threshold int           // failures before the first lockout
+	BaseDelay time.Duration // duration of the first lockout
+	MaxDelay  time.Duration // cap for the doubled lockout duration (default 24h)
+	Window    time.Duration // failures older than this are forgotten
+}
+
+// Enabled reports whether lockout should be enforced.
+func (p LockoutPolicy) Enabled() bool {
+	return p.Threshold > 0 && p.BaseDelay > 0
+}
+
+// LockoutState is the failure history for a key.
+type LockoutState struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Locked reports whether the key is locked at the given time.
+func (s LockoutState) Locked(now time.Time) bool {
+	return s.LockedUntil.After(now)
+}
+
+// Store persists bucket and lockout state. Implementations must be safe for
+// concurrent use and apply each operation atomically.
+type Store interface {
+	// Take removes one token from the bucket identified by key.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Lockout returns the current lockout state for key.
+	Lockout(ctx context.Context, key string) (LockoutState, error)
+	// RecordFailure registers a failed attempt for key and returns the new state.
+	RecordFailure(ctx context.Context, key string, policy LockoutPolicy) (LockoutState, error)
+	// ResetFailures clears the failure history for key.
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// takeToken refills the bucket up to now and tries to consume one token.
+// It returns the updated token count alongside the result.
+func takeToken(tokens float64, refilledAt time.Time, limit Limit, now time.Time) (float64, Result) {
+	rate := limit.ratePerSecond()
+	capacity := float64(limit.Requests)
+
+	elapsed := now.Sub(refilledAt).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	res := Result{Limit: limit.Requests}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	res.Remaining = int(math.Floor(tokens))
+	res.ResetAt = now.Add(time.Duration((capacity - tokens) / rate * float64(time.Second)))
+	return tokens, res
+}
+
+// nextLockout applies one failure to state according to policy.
+func nextLockout(state LockoutState, policy LockoutPolicy, now time.Time) LockoutState {
+	if policy.Window > 0 && !state.LastFailureAt.IsZero() && now.Sub(state.LastFailureAt) > policy.Window {
+		state.Failures = 0
+	}
+
+	state.Failures++
+	state.LastFailureAt = now
+
+	if state.Failures >= policy.Threshold {
+		maxDelay := policy.MaxDelay
+		if maxDelay <= 0 {
+			maxDelay = 24 * time.Hour
+		}
+
+		// Double the delay for every failure past the threshold
+		delay := policy.BaseDelay
+		for i := policy.Threshold; i < state.Failures && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		state.LockedUntil = now.Add(delay)
+	}
+
+	return state
+}
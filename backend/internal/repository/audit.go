@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditFilter selects audit events. Zero fields don't filter.
+type AuditFilter struct {
+	Involving  int64  // events by or about this user
+	ActorID    int64  // events by this user
+	UserID     int64  // events about this user's account
+	Action     string // exact action, or a prefix ending in "." such as "auth."
+	TargetType string
+	TargetID   int64
+	Since      time.Time
+	Until      time.Time
+	BeforeID   int64 // only events older than this one, for paging
+	Limit      int   // 0 means no limit
+}
+
+// Matches reports whether event passes the filter.
+func (f AuditFilter) Matches(e models.AuditEvent) bool {
+	is := func(id *int64, want int64) bool { return id != nil && *id == want }
+
+	// The first failing condition rejects the event
+	switch {
+	case f.Involving != 0 && !is(e.ActorID, f.Involving) && !is(e.UserID, f.Involving):
+	case f.ActorID != 0 && !is(e.ActorID, f.ActorID):
+	case f.UserID != 0 && !is(e.UserID, f.UserID):
+	case f.Action != "" && !f.matchesAction(e.Action):
+	case f.TargetType != "" && e.TargetType != f.TargetType:
+	case f.TargetID != 0 && !is(e.TargetID, f.TargetID):
+	case !f.Since.IsZero() && e.CreatedAt.Before(f.Since):
+	case !f.Until.IsZero() && !e.CreatedAt.Before(f.Until):
+	case f.BeforeID != 0 && e.ID >= f.BeforeID:
+	default:
+		return true
+	}
+	return false
+}
+
+func (f AuditFilter) matchesAction(action string) bool {
+	if strings.HasSuffix(f.Action, ".") {
+		return strings.HasPrefix(action, f.Action)
+	}
+	return action == f.Action
+}
+
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository returns an AuditRepository backed by db.
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &gormAuditRepository{db: db}
+}
+
+func (r *gormAuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *gormAuditRepository) List(ctx context.Context, f AuditFilter) ([]models.AuditEvent, error) {
+	query := r.db.WithContext(ctx).Order("id DESC")
+	if f.Involving != 0 {
+		query = query.Where("actor_id = ? OR user_id = ?", f.Involving, f.Involving)
+	}
+	if f.ActorID != 0 {
+		query = query.Where("actor_id = ?", f.ActorID)
+	}
+	if f.UserID != 0 {
+		query = query.Where("user_id = ?", f.UserID)
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			query = query.Where(`action LIKE ? ESCAPE '\'`, database.EscapeLike(f.Action)+"%")
+		} else {
+			query = query.Where("action = ?", f.Action)
+		}
+	}
+	if f.TargetType != "" {
+		query = query.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != 0 {
+		query = query.Where("target_id = ?", f.TargetID)
+	}
+	if !f.Since.IsZero() {
+		query = query.Where("created_at >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		query = query.Where("created_at < ?", f.Until)
+	}
+	if f.BeforeID != 0 {
+		query = query.Where("id < ?", f.BeforeID)
+	}
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+
+	events := []models.AuditEvent{}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
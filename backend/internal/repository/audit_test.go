@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestAuditListActionFilter(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		events := NewAuditRepository(db)
+		for _, action := range []string{"note.create", "note.update", "notes.export", "user_x.create", "userAx.create", `a\b.create`} {
+			if err := events.Create(ctx, &models.AuditEvent{Action: action, TargetType: models.AuditTargetNote}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for action, want := range map[string][]string{
+			"note.create": {"note.create"},
+			"note.":       {"note.update", "note.create"},
+			"note":        nil,
+			"user_x.":     {"user_x.create"},
+			"%.":          nil,
+			"_.":          nil,
+			`a\b.`:        {`a\b.create`},
+		} {
+			list, err := events.List(ctx, AuditFilter{Action: action})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range list {
+				got = append(got, e.Action)
+			}
+			if len(got) != len(want) {
+				t.Errorf("events with action %q = %q, want %q", action, got, want)
+				continue
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Errorf("events with action %q = %q, want %q", action, got, want)
+					break
+				}
+			}
+		}
+	})
+}
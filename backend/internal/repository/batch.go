@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// errBatchFailed aborts the transaction of an atomic batch.
+var errBatchFailed = errors.New("batch operation failed")
+
+func (r *gormNoteRepository) Batch(ctx context.Context, userID int64, req models.BatchNotesRequest) (*BatchResult, error) {
+	result := newBatchResult(len(req.IDs))
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Check ownership of all IDs up front
+		var notes []models.Note
+		if err := tx.Where("id IN ? AND user_id = ?", req.IDs, userID).Find(&notes).Error; err != nil {
+			return err
+		}
+		for _, note := range notes {
+			result.Notes[note.ID] = note
+		}
+
+		// Label operations share one lookup for the whole batch
+		var labels []models.Label
+		switch req.Operation {
+		case models.BatchAddLabels:
+			var err error
+			if labels, err = database.FindOrCreateLabels(tx, userID, req.Labels); err != nil {
+				return err
+			}
+		case models.BatchRemoveLabels:
+			if err := tx.Where("user_id = ? AND name IN ?", userID, req.Labels).Find(&labels).Error; err != nil {
+				return err
+			}
+		}
+
+		result.apply(req, func(id int64) error {
+			note := result.Notes[id]
+			if req.Mode == models.BatchModePartial {
+				// A savepoint per note keeps one failure from aborting the transaction
+				return tx.Transaction(func(sp *gorm.DB) error {
+					return applyBatchOperation(sp, &note, req, labels)
+				})
+			}
+			return applyBatchOperation(tx, &note, req, labels)
+		})
+
+		if result.RolledBack {
+			return errBatchFailed
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchFailed) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyBatchOperation applies the requested operation to a single note.
+func applyBatchOperation(tx *gorm.DB, note *models.Note, req models.BatchNotesRequest, labels []models.Label) error {
+	update := func(column string, value interface{}) error {
+		return tx.Model(note).Updates(edit(map[string]interface{}{column: value})).Error
+	}
+
+	switch req.Operation {
+	case models.BatchArchive:
+		return update("archived", true)
+	case models.BatchUnarchive:
+		return update("archived", false)
+	case models.BatchPin:
+		return update("pinned", true)
+	case models.BatchUnpin:
+		return update("pinned", false)
+	case models.BatchTrash:
+		return update("trashed_at", time.Now())
+	case models.BatchRestore:
+		return update("trashed_at", nil)
+	case models.BatchColor:
+		return update("color", req.Color)
+	case models.BatchAddLabels:
+		if err := tx.Model(note).Association("Labels").Append(labels); err != nil {
+			return err
+		}
+		return update("updated_at", time.Now())
+	case models.BatchRemoveLabels:
+		if len(labels) == 0 {
+			return nil
+		}
+		if err := tx.Model(note).Association("Labels").Delete(labels); err != nil {
+			return err
+		}
+		return update("updated_at", time.Now())
+	case models.BatchDelete:
+		return tx.Select("Labels", "Attachments").Delete(note).Error
+	default:
+		return fmt.Errorf("unknown batch operation %q", req.Operation)
+	}
+}
+
+func newBatchResult(n int) *BatchResult {
+	return &BatchResult{
+		Results: make([]models.BatchNoteResult, 0, n),
+		Notes:   make(map[int64]models.Note, n),
+		Errors:  make(map[int64]error),
+	}
+}
+
+// apply fills in the results of the IDs of req, calling fn for each note
+// in result.Notes. In atomic mode it stops calling fn after the first
+// failure and reports the batch as rolled back; the caller undoes the
+// changes.
+func (result *BatchResult) apply(req models.BatchNotesRequest, fn func(id int64) error) {
+	failed := false
+	for _, id := range req.IDs {
+		res := models.BatchNoteResult{ID: id, Status: models.BatchResultOK}
+		switch _, ok := result.Notes[id]; {
+		case !ok:
+			res.Status = models.BatchResultNotFound
+			res.Error = "Note not found"
+			failed = true
+		case failed && req.Mode == models.BatchModeAtomic:
+			// The batch will be rolled back; don't bother applying the rest
+		default:
+			if err := fn(id); err != nil {
+				res.Status = models.BatchResultFailed
+				res.Error = "Failed to apply operation"
+				result.Errors[id] = err
+				failed = true
+			}
+		}
+		result.Results = append(result.Results, res)
+	}
+
+	result.RolledBack = failed && req.Mode == models.BatchModeAtomic
+	if result.RolledBack {
+		for i := range result.Results {
+			if result.Results[i].Status == models.BatchResultOK {
+				result.Results[i].Status = models.BatchResultRolledBack
+			}
+		}
+	}
+}
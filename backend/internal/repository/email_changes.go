@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type gormEmailChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailChangeRepository returns an EmailChangeRepository backed by db.
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &gormEmailChangeRepository{db: db}
+}
+
+func (r *gormEmailChangeRepository) Replace(ctx context.Context, change *models.EmailChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", change.UserID).Delete(&models.EmailChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (r *gormEmailChangeRepository) FindByHash(ctx context.Context, hash string) (*models.EmailChange, error) {
+	var change models.EmailChange
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&change).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &change, nil
+}
+
+func (r *gormEmailChangeRepository) Delete(ctx context.Context, change *models.EmailChange) error {
+	return r.db.WithContext(ctx).Delete(change).Error
+}
+
+func (r *gormEmailChangeRepository) Apply(ctx context.Context, change *models.EmailChange) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The address may have been taken since the request was made
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("email = ? AND id <> ?", change.NewEmail, change.UserID).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrEmailTaken
+		}
+		if err := tx.First(&user, change.UserID).Error; err != nil {
+			return translate(err)
+		}
+		if err := tx.Model(&user).Update("email", change.NewEmail).Error; err != nil {
+			return err
+		}
+		return tx.Delete(change).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Note sort orders accepted by NoteFilter.Sort.
+const (
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortTitle   = "title"
+	SortManual  = "manual"
+)
+
+// noteSorts maps a sort order to the ORDER BY clause. Notes that were never
+// placed manually have an empty position and stay newest first.
+var noteSorts = map[string]string{
+	SortCreated: "created_at DESC, id DESC",
+	SortUpdated: "updated_at DESC, id DESC",
+	SortTitle:   "title, id",
+	SortManual:  "position, created_at DESC, id DESC",
+}
+
+// IsNoteSort reports whether s is a supported sort order.
+func IsNoteSort(s string) bool {
+	_, ok := noteSorts[s]
+	return ok
+}
+
+// NoteFilter selects and orders notes when listing or exporting them.
+type NoteFilter struct {
+	Query    string // words that must all appear in the title or content
+	Label    string
+	Color    string
+	Pinned   *bool
+	Archived *bool  // nil means any
+	Trashed  *bool  // nil means any
+	Sort     string // empty means SortCreated
+}
+
+// Apply adds the filter conditions to a query on notes.
+func (f NoteFilter) Apply(db *gorm.DB, userID int64) *gorm.DB {
+	if strings.TrimSpace(f.Query) != "" {
+		db = database.SearchNotes(db, f.Query)
+	}
+	if f.Label != "" {
+		db = db.Where(`EXISTS (SELECT 1 FROM note_labels nl JOIN labels l ON l.id = nl.label_id
+			WHERE nl.note_id = notes.id AND l.user_id = ? AND l.name = ?)`, userID, f.Label)
+	}
+	if f.Color != "" {
+		db = db.Where("color = ?", f.Color)
+	}
+	if f.Pinned != nil {
+		db = db.Where("pinned = ?", *f.Pinned)
+	}
+	if f.Archived != nil {
+		db = db.Where("archived = ?", *f.Archived)
+	}
+	if f.Trashed != nil {
+		if *f.Trashed {
+			db = db.Where("trashed_at IS NOT NULL")
+		} else {
+			db = db.Where("trashed_at IS NULL")
+		}
+	}
+	return db
+}
+
+// Order applies the sort order to a query on notes.
+func (f NoteFilter) Order(db *gorm.DB) *gorm.DB {
+	order, ok := noteSorts[f.Sort]
+	if !ok {
+		order = noteSorts[SortCreated]
+	}
+	return db.Order(order)
+}
+
+// Matches reports whether a note (with its labels loaded) passes the filter.
+// Search is approximated by case-insensitive substring matching.
+func (f NoteFilter) Matches(note models.Note) bool {
+	if f.Query != "" {
+		text := strings.ToLower(note.Title + " " + note.Content)
+		for _, word := range strings.Fields(strings.ToLower(f.Query)) {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+	if f.Label != "" {
+		found := false
+		for _, l := range note.Labels {
+			if l.Name == f.Label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Color != "" && note.Color != f.Color {
+		return false
+	}
+	if f.Pinned != nil && note.Pinned != *f.Pinned {
+		return false
+	}
+	if f.Archived != nil && note.Archived != *f.Archived {
+		return false
+	}
+	if f.Trashed != nil && (note.TrashedAt != nil) != *f.Trashed {
+		return false
+	}
+	return true
+}
+
+// SortNotes orders notes in place the way Order does in SQL.
+func (f NoteFilter) SortNotes(notes []models.Note) {
+	newestFirst := func(a, b models.Note) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+
+	var less func(a, b models.Note) bool
+	switch f.Sort {
+	case SortUpdated:
+		less = func(a, b models.Note) bool {
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.After(b.UpdatedAt)
+			}
+			return a.ID > b.ID
+		}
+	case SortTitle:
+		less = func(a, b models.Note) bool {
+			if c := strings.Compare(a.Title, b.Title); c != 0 {
+				return c < 0
+			}
+			return a.ID < b.ID
+		}
+	case SortManual:
+		less = func(a, b models.Note) bool {
+			if a.Position != b.Position {
+				return a.Position < b.Position
+			}
+			return newestFirst(a, b)
+		}
+	default:
+		less = newestFirst
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool { return less(notes[i], notes[j]) })
+}
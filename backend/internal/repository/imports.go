@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type gormImportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewImportJobRepository returns an ImportJobRepository backed by db.
+func NewImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &gormImportJobRepository{db: db}
+}
+
+func (r *gormImportJobRepository) Start(ctx context.Context, job *models.ImportJob, since time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Concurrent uploads of the user wait here, then see this job
+		if err := database.LockUser(tx, job.UserID); err != nil {
+			return translate(err)
+		}
+		active, err := activeImports(tx, job.UserID, since)
+		if err != nil {
+			return err
+		}
+		if active {
+			return ErrImportInProgress
+		}
+		return tx.Create(job).Error
+	})
+}
+
+func (r *gormImportJobRepository) Active(ctx context.Context, userID int64, since time.Time) (bool, error) {
+	return activeImports(r.db.WithContext(ctx), userID, since)
+}
+
+func activeImports(db *gorm.DB, userID int64, since time.Time) (bool, error) {
+	var active int64
+	err := db.Model(&models.ImportJob{}).
+		Where("user_id = ? AND status IN ?", userID, []string{models.ImportStatusPending, models.ImportStatusRunning}).
+		Where("updated_at > ?", since).
+		Count(&active).Error
+	return active > 0, err
+}
+
+func (r *gormImportJobRepository) List(ctx context.Context, userID int64) ([]models.ImportJob, error) {
+	var jobs []models.ImportJob
+	if err := r.db.WithContext(ctx).Omit("report").Where("user_id = ?", userID).
+		Order("created_at DESC").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *gormImportJobRepository) Get(ctx context.Context, userID, jobID int64) (*models.ImportJob, error) {
+	var job models.ImportJob
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &job, nil
+}
+
+func (r *gormImportJobRepository) Fail(ctx context.Context, job *models.ImportJob, message string) error {
+	now := time.Now()
+	job.Status, job.Error, job.FinishedAt = models.ImportStatusFailed, message, &now
+	return r.db.WithContext(ctx).Model(job).Updates(map[string]interface{}{
+		"status":      job.Status,
+		"error":       job.Error,
+		"finished_at": now,
+	}).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	os.Exit(m.Run())
+}
+
+func TestImportJobStartIsAtomic(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		user := models.User{Email: "alice@example.com", PasswordHash: "x"}
+		if err := NewUserRepository(db).Create(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+		imports := NewImportJobRepository(db)
+		since := time.Now().Add(-time.Hour)
+
+		// Uploads that passed the early check together: only one job starts
+		const uploads = 8
+		var wg sync.WaitGroup
+		errs := make([]error, uploads)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				job := models.ImportJob{UserID: user.ID, Source: "json", Status: models.ImportStatusPending}
+				errs[i] = imports.Start(ctx, &job, since)
+			}()
+		}
+		wg.Wait()
+
+		started := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				started++
+			case !errors.Is(err, ErrImportInProgress):
+				t.Errorf("Start: %v", err)
+			}
+		}
+		if started != 1 {
+			t.Fatalf("%d of %d concurrent imports started, want 1", started, uploads)
+		}
+
+		// A job without progress since is stale and doesn't block the next
+		job := models.ImportJob{UserID: user.ID, Source: "json", Status: models.ImportStatusPending}
+		if err := imports.Start(ctx, &job, time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("Start after a stale job: %v", err)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// MemoryUserRepository is a UserRepository kept in memory, for unit tests
+// and local experiments. It is safe for concurrent use.
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	users  map[int64]models.User
+	nextID int64
+}
+
+// NewMemoryUserRepository returns an empty MemoryUserRepository.
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[int64]models.User)}
+}
+
+func (r *MemoryUserRepository) Create(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Email == user.Email {
+			return fmt.Errorf("user with email %q already exists", user.Email)
+		}
+	}
+
+	// Column defaults
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if user.Locale == "" {
+		user.Locale = "en"
+	}
+	if user.Timezone == "" {
+		user.Timezone = "UTC"
+	}
+	if user.Preferences.DefaultNoteColor == "" {
+		user.Preferences.DefaultNoteColor = "default"
+	}
+	if user.Preferences.NoteView == "" {
+		user.Preferences.NoteView = models.NoteViewGrid
+	}
+
+	r.nextID++
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt, user.UpdatedAt = now, now
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) FindByID(_ context.Context, id int64) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(_ context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) Update(_ context.Context, user *models.User, fields map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	for column, value := range fields {
+		var ok bool
+		switch column {
+		case "email":
+			stored.Email, ok = value.(string)
+		case "password_hash":
+			stored.PasswordHash, ok = value.(string)
+		case "role":
+			stored.Role, ok = value.(string)
+		case "display_name":
+			stored.DisplayName, ok = value.(string)
+		case "locale":
+			stored.Locale, ok = value.(string)
+		case "timezone":
+			stored.Timezone, ok = value.(string)
+		case "pref_default_note_color":
+			stored.Preferences.DefaultNoteColor, ok = value.(string)
+		case "pref_note_view":
+			stored.Preferences.NoteView, ok = value.(string)
+		case "delete_after":
+			stored.DeleteAfter, ok = timeValue(value)
+		case "disabled_at":
+			stored.DisabledAt, ok = timeValue(value)
+		default:
+			return fmt.Errorf("memory user repository: unsupported column %q", column)
+		}
+		if !ok {
+			return fmt.Errorf("memory user repository: invalid value %v for column %q", value, column)
+		}
+	}
+
+	stored.UpdatedAt = time.Now()
+	r.users[user.ID] = stored
+	*user = stored
+	return nil
+}
+
+// MemoryTokenRepository is a TokenRepository kept in memory. It is safe for
+// concurrent use.
+type MemoryTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[int64]models.RefreshToken
+	nextID int64
+}
+
+// NewMemoryTokenRepository returns an empty MemoryTokenRepository.
+func NewMemoryTokenRepository() *MemoryTokenRepository {
+	return &MemoryTokenRepository{tokens: make(map[int64]models.RefreshToken)}
+}
+
+func (r *MemoryTokenRepository) Create(_ context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = time.Now()
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *MemoryTokenRepository) FindByHash(_ context.Context, hash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryTokenRepository) Revoke(_ context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tokens[token.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Revoked = true
+	r.tokens[token.ID] = stored
+	token.Revoked = true
+	return nil
+}
+
+func (r *MemoryTokenRepository) RevokeAll(_ context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID {
+			token.Revoked = true
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+// timeValue converts the values accepted for nullable time columns.
+func timeValue(v interface{}) (*time.Time, bool) {
+	switch t := v.(type) {
+	case nil:
+		return nil, true
+	case time.Time:
+		return &t, true
+	case *time.Time:
+		return t, true
+	}
+	return nil, false
+}
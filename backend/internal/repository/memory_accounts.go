@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// MemoryEmailChangeRepository is an EmailChangeRepository kept in memory,
+// applying changes to a MemoryUserRepository. It is safe for concurrent use.
+type MemoryEmailChangeRepository struct {
+	mu      sync.Mutex
+	users   *MemoryUserRepository
+	changes map[int64]models.EmailChange // by user ID
+	nextID  int64
+}
+
+// NewMemoryEmailChangeRepository returns an empty MemoryEmailChangeRepository
+// changing the emails of users.
+func NewMemoryEmailChangeRepository(users *MemoryUserRepository) *MemoryEmailChangeRepository {
+	return &MemoryEmailChangeRepository{users: users, changes: make(map[int64]models.EmailChange)}
+}
+
+func (r *MemoryEmailChangeRepository) Replace(_ context.Context, change *models.EmailChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	change.ID = r.nextID
+	change.CreatedAt = time.Now()
+	r.changes[change.UserID] = *change
+	return nil
+}
+
+func (r *MemoryEmailChangeRepository) FindByHash(_ context.Context, hash string) (*models.EmailChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, change := range r.changes {
+		if change.TokenHash == hash {
+			return &change, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryEmailChangeRepository) Delete(_ context.Context, change *models.EmailChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.changes[change.UserID]; ok && stored.ID == change.ID {
+		delete(r.changes, change.UserID)
+	}
+	return nil
+}
+
+func (r *MemoryEmailChangeRepository) Apply(ctx context.Context, change *models.EmailChange) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if other, err := r.users.FindByEmail(ctx, change.NewEmail); err == nil && other.ID != change.UserID {
+		return nil, ErrEmailTaken
+	}
+	user, err := r.users.FindByID(ctx, change.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.users.Update(ctx, user, map[string]interface{}{"email": change.NewEmail}); err != nil {
+		return nil, err
+	}
+	delete(r.changes, change.UserID)
+	return user, nil
+}
+
+// MemoryImportJobRepository is an ImportJobRepository kept in memory. It is
+// safe for concurrent use.
+type MemoryImportJobRepository struct {
+	mu     sync.Mutex
+	jobs   map[int64]models.ImportJob
+	nextID int64
+}
+
+// NewMemoryImportJobRepository returns an empty MemoryImportJobRepository.
+func NewMemoryImportJobRepository() *MemoryImportJobRepository {
+	return &MemoryImportJobRepository{jobs: make(map[int64]models.ImportJob)}
+}
+
+func (r *MemoryImportJobRepository) Start(_ context.Context, job *models.ImportJob, since time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active(job.UserID, since) {
+		return ErrImportInProgress
+	}
+	r.nextID++
+	now := time.Now()
+	job.ID = r.nextID
+	job.CreatedAt, job.UpdatedAt = now, now
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *MemoryImportJobRepository) Active(_ context.Context, userID int64, since time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active(userID, since), nil
+}
+
+func (r *MemoryImportJobRepository) active(userID int64, since time.Time) bool {
+	for _, job := range r.jobs {
+		if job.UserID == userID && job.UpdatedAt.After(since) &&
+			(job.Status == models.ImportStatusPending || job.Status == models.ImportStatusRunning) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryImportJobRepository) List(_ context.Context, userID int64) ([]models.ImportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := []models.ImportJob{}
+	for _, job := range r.jobs {
+		if job.UserID == userID {
+			job.Report = nil
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	return jobs, nil
+}
+
+func (r *MemoryImportJobRepository) Get(_ context.Context, userID, jobID int64) (*models.ImportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobID]
+	if !ok || job.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func (r *MemoryImportJobRepository) Fail(_ context.Context, job *models.ImportJob, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	job.Status, job.Error, job.FinishedAt = models.ImportStatusFailed, message, &now
+	job.UpdatedAt = now
+	r.jobs[job.ID] = *job
+	return nil
+}
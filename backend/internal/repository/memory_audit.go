@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// MemoryAuditRepository is an AuditRepository kept in memory. It is safe
+// for concurrent use.
+type MemoryAuditRepository struct {
+	mu     sync.Mutex
+	events []models.AuditEvent // in insertion order
+}
+
+// NewMemoryAuditRepository returns an empty MemoryAuditRepository.
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) Create(_ context.Context, event *models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = int64(len(r.events)) + 1
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *MemoryAuditRepository) List(_ context.Context, filter AuditFilter) ([]models.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []models.AuditEvent{}
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+		if filter.Matches(r.events[i]) {
+			events = append(events, r.events[i])
+		}
+	}
+	return events, nil
+}
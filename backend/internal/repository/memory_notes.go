@@ -0,0 +1,396 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/rank"
+)
+
+// MemoryNoteRepository is a NoteRepository kept in memory, for unit tests
+// and local experiments. It doesn't store attachments. It is safe for
+// concurrent use.
+type MemoryNoteRepository struct {
+	mu          sync.Mutex
+	notes       map[int64]*models.Note
+	labels      map[int64]map[string]models.Label // user ID -> name -> label
+	nextID      int64
+	nextLabelID int64
+}
+
+// NewMemoryNoteRepository returns an empty MemoryNoteRepository.
+func NewMemoryNoteRepository() *MemoryNoteRepository {
+	return &MemoryNoteRepository{
+		notes:  make(map[int64]*models.Note),
+		labels: make(map[int64]map[string]models.Label),
+	}
+}
+
+func (r *MemoryNoteRepository) Create(_ context.Context, note *models.Note, labels []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if note.Color == "" {
+		note.Color = "default"
+	}
+
+	position := ""
+	if first := r.userNotes(note.UserID); len(first) == 0 {
+		position, _ = rank.Between("", "")
+	} else if first[0].Position != "" {
+		var err error
+		if position, err = rank.Between("", first[0].Position); err != nil {
+			return err
+		}
+	}
+
+	r.nextID++
+	now := time.Now()
+	note.ID = r.nextID
+	note.Position = position
+	note.Labels = r.findOrCreateLabels(note.UserID, labels)
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = now
+	}
+	if note.UpdatedAt.IsZero() {
+		note.UpdatedAt = now
+	}
+	if note.Revision == 0 {
+		note.Revision = 1
+	}
+
+	stored := copyNote(*note)
+	r.notes[note.ID] = &stored
+	return nil
+}
+
+func (r *MemoryNoteRepository) List(_ context.Context, userID int64, filter NoteFilter) ([]models.Note, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notes := []models.Note{}
+	for _, note := range r.notes {
+		if note.UserID == userID && filter.Matches(*note) {
+			notes = append(notes, copyNote(*note))
+		}
+	}
+	filter.SortNotes(notes)
+	return notes, nil
+}
+
+func (r *MemoryNoteRepository) Get(_ context.Context, userID, noteID int64, expand NoteExpansion) (*models.Note, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.notes[noteID]
+	if !ok || stored.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	note := copyNote(*stored)
+	if !expand.Labels {
+		note.Labels = nil
+	}
+	if expand.Attachments {
+		note.Attachments = []models.Attachment{}
+	}
+	return &note, nil
+}
+
+func (r *MemoryNoteRepository) Update(_ context.Context, note *models.Note, changes NoteChanges) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.notes[note.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := copyNote(*stored)
+
+	for column, value := range changes.Fields {
+		var ok bool
+		switch column {
+		case "title":
+			updated.Title, ok = value.(string)
+		case "content":
+			updated.Content, ok = value.(string)
+		case "color":
+			updated.Color, ok = value.(string)
+		case "pinned":
+			updated.Pinned, ok = value.(bool)
+		case "archived":
+			updated.Archived, ok = value.(bool)
+		case "trashed_at":
+			updated.TrashedAt, ok = timeValue(value)
+		default:
+			return fmt.Errorf("memory note repository: unsupported column %q", column)
+		}
+		if !ok {
+			return fmt.Errorf("memory note repository: invalid value %v for column %q", value, column)
+		}
+	}
+	if changes.SetLabels {
+		updated.Labels = r.findOrCreateLabels(updated.UserID, changes.Labels)
+	}
+	if len(changes.Fields) > 0 || changes.SetLabels {
+		updated.UpdatedAt = time.Now()
+		updated.Revision++
+	}
+
+	*stored = updated
+	*note = copyNote(updated)
+	return nil
+}
+
+func (r *MemoryNoteRepository) Delete(_ context.Context, note *models.Note) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.notes, note.ID)
+	return nil
+}
+
+func (r *MemoryNoteRepository) Move(_ context.Context, userID, noteID int64, after, before *int64) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, ok := r.notes[noteID]
+	if !ok || note.UserID != userID {
+		return "", ErrNotFound
+	}
+
+	position, err := r.positionBetween(userID, noteID, after, before)
+	if errors.Is(err, rank.ErrInvalidRange) || (err == nil && len(position) > database.MaxPositionLength) {
+		r.respace(userID)
+		position, err = r.positionBetween(userID, noteID, after, before)
+	}
+	if errors.Is(err, rank.ErrInvalidRange) {
+		return "", ErrAnchorOrder
+	}
+	if err != nil {
+		return "", err
+	}
+
+	note.Position = position
+	return position, nil
+}
+
+func (r *MemoryNoteRepository) Batch(_ context.Context, userID int64, req models.BatchNotesRequest) (*BatchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := newBatchResult(len(req.IDs))
+	for _, id := range req.IDs {
+		if note, ok := r.notes[id]; ok && note.UserID == userID {
+			stored := copyNote(*note)
+			stored.Labels = nil
+			result.Notes[id] = stored
+		}
+	}
+
+	// Kept to roll back an atomic batch
+	saved := make(map[int64]models.Note, len(result.Notes))
+	for id := range result.Notes {
+		saved[id] = copyNote(*r.notes[id])
+	}
+
+	result.apply(req, func(id int64) error {
+		return r.applyBatch(r.notes[id], req)
+	})
+
+	if result.RolledBack {
+		for id, note := range saved {
+			restored := note
+			r.notes[id] = &restored
+		}
+	}
+	return result, nil
+}
+
+// applyBatch mirrors applyBatchOperation.
+func (r *MemoryNoteRepository) applyBatch(note *models.Note, req models.BatchNotesRequest) error {
+	now := time.Now()
+	switch req.Operation {
+	case models.BatchArchive, models.BatchUnarchive:
+		note.Archived = req.Operation == models.BatchArchive
+	case models.BatchPin, models.BatchUnpin:
+		note.Pinned = req.Operation == models.BatchPin
+	case models.BatchTrash:
+		note.TrashedAt = &now
+	case models.BatchRestore:
+		note.TrashedAt = nil
+	case models.BatchColor:
+		note.Color = req.Color
+	case models.BatchAddLabels:
+		names := append(labelNames(note.Labels), req.Labels...)
+		note.Labels = r.findOrCreateLabels(note.UserID, names)
+	case models.BatchRemoveLabels:
+		remove := make(map[string]bool, len(req.Labels))
+		for _, name := range req.Labels {
+			remove[name] = true
+		}
+		var kept []string
+		for _, name := range labelNames(note.Labels) {
+			if !remove[name] {
+				kept = append(kept, name)
+			}
+		}
+		note.Labels = r.findOrCreateLabels(note.UserID, kept)
+	case models.BatchDelete:
+		delete(r.notes, note.ID)
+		return nil
+	default:
+		return fmt.Errorf("unknown batch operation %q", req.Operation)
+	}
+	note.UpdatedAt = now
+	note.Revision++
+	return nil
+}
+
+func (r *MemoryNoteRepository) Each(_ context.Context, userID int64, filter *NoteFilter, fn func(note models.Note) error) error {
+	r.mu.Lock()
+	var notes []models.Note
+	for _, note := range r.notes {
+		if note.UserID == userID && (filter == nil || filter.Matches(*note)) {
+			note := copyNote(*note)
+			note.Attachments = []models.Attachment{}
+			notes = append(notes, note)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	for _, note := range notes {
+		if err := fn(note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attachment always returns ErrNotFound, as attachments aren't stored.
+func (r *MemoryNoteRepository) Attachment(_ context.Context, _, _ int64) (*models.Attachment, error) {
+	return nil, ErrNotFound
+}
+
+func (r *MemoryNoteRepository) Labels(_ context.Context, userID int64) ([]models.Label, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	labels := []models.Label{}
+	for _, label := range r.labels[userID] {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
+
+// positionBetween mirrors database.MoveNote.
+func (r *MemoryNoteRepository) positionBetween(userID, noteID int64, after, before *int64) (string, error) {
+	anchor := func(id int64) (string, error) {
+		note, ok := r.notes[id]
+		if !ok || note.UserID != userID {
+			return "", ErrAnchorNotFound
+		}
+		if note.Position == "" {
+			return "", rank.ErrInvalidRange
+		}
+		return note.Position, nil
+	}
+
+	var lower, upper string
+	var err error
+	if after != nil {
+		if lower, err = anchor(*after); err != nil {
+			return "", err
+		}
+	}
+	if before != nil {
+		if upper, err = anchor(*before); err != nil {
+			return "", err
+		}
+	}
+
+	for _, note := range r.userNotes(userID) {
+		if note.ID == noteID {
+			continue
+		}
+		if after == nil && note.Position < upper && note.Position > lower {
+			lower = note.Position
+		}
+		if before == nil && note.Position > lower && (upper == "" || note.Position < upper) {
+			upper = note.Position
+		}
+	}
+
+	return rank.Between(lower, upper)
+}
+
+// respace mirrors database.RespaceNotePositions.
+func (r *MemoryNoteRepository) respace(userID int64) {
+	notes := r.userNotes(userID)
+	for i, position := range rank.Spread(len(notes)) {
+		r.notes[notes[i].ID].Position = position
+	}
+}
+
+// userNotes returns the user's notes in manual order.
+func (r *MemoryNoteRepository) userNotes(userID int64) []models.Note {
+	var notes []models.Note
+	for _, note := range r.notes {
+		if note.UserID == userID {
+			notes = append(notes, *note)
+		}
+	}
+	NoteFilter{Sort: SortManual}.SortNotes(notes)
+	return notes
+}
+
+// findOrCreateLabels mirrors database.FindOrCreateLabels.
+func (r *MemoryNoteRepository) findOrCreateLabels(userID int64, names []string) []models.Label {
+	if r.labels[userID] == nil {
+		r.labels[userID] = make(map[string]models.Label)
+	}
+
+	labels := []models.Label{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		label, ok := r.labels[userID][name]
+		if !ok {
+			r.nextLabelID++
+			label = models.Label{ID: r.nextLabelID, UserID: userID, Name: name, CreatedAt: time.Now()}
+			r.labels[userID][name] = label
+		}
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+func labelNames(labels []models.Label) []string {
+	names := make([]string, len(labels))
+	for i, l := range labels {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// copyNote copies a note so callers can't modify the stored slices.
+func copyNote(note models.Note) models.Note {
+	note.Labels = append([]models.Label(nil), note.Labels...)
+	note.Attachments = nil
+	return note
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/rank"
+	"gorm.io/gorm"
+)
+
+type gormNoteRepository struct {
+	db *gorm.DB
+}
+
+// NewNoteRepository returns a NoteRepository backed by db.
+func NewNoteRepository(db *gorm.DB) NoteRepository {
+	return &gormNoteRepository{db: db}
+}
+
+func (r *gormNoteRepository) Create(ctx context.Context, note *models.Note, labels []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if note.Labels, err = database.FindOrCreateLabels(tx, note.UserID, labels); err != nil {
+			return err
+		}
+		if note.Position, err = database.TopNotePosition(tx, note.UserID); err != nil {
+			return err
+		}
+		return tx.Create(note).Error
+	})
+}
+
+func (r *gormNoteRepository) List(ctx context.Context, userID int64, filter NoteFilter) ([]models.Note, error) {
+	var notes []models.Note
+	query := filter.Apply(r.db.WithContext(ctx).Where("user_id = ?", userID), userID)
+	if err := filter.Order(query).Preload("Labels").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (r *gormNoteRepository) Get(ctx context.Context, userID, noteID int64, expand NoteExpansion) (*models.Note, error) {
+	query := r.db.WithContext(ctx)
+	if expand.Labels {
+		query = query.Preload("Labels", func(db *gorm.DB) *gorm.DB { return db.Order("name") })
+	}
+	if expand.Attachments {
+		query = query.Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Omit("data").Order("id") })
+	}
+
+	var note models.Note
+	if err := query.Where("id = ? AND user_id = ?", noteID, userID).First(&note).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &note, nil
+}
+
+func (r *gormNoteRepository) Update(ctx context.Context, note *models.Note, changes NoteChanges) error {
+	fields := changes.Fields
+	if len(fields) == 0 && changes.SetLabels {
+		// Changing only the labels is an edit too
+		fields = map[string]interface{}{"updated_at": time.Now()}
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(fields) > 0 {
+			if err := tx.Model(note).Updates(edit(fields)).Error; err != nil {
+				return err
+			}
+		}
+		if changes.SetLabels {
+			labels, err := database.FindOrCreateLabels(tx, note.UserID, changes.Labels)
+			if err != nil {
+				return err
+			}
+			return tx.Model(note).Association("Labels").Replace(labels)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reload note to get updated values
+	return r.db.WithContext(ctx).Preload("Labels").First(note, note.ID).Error
+}
+
+// edit returns the columns of an update that bumps updated_at, plus the
+// increment of the note's revision that every such update makes.
+func edit(fields map[string]interface{}) map[string]interface{} {
+	columns := map[string]interface{}{"revision": gorm.Expr("revision + 1")}
+	for column, value := range fields {
+		columns[column] = value
+	}
+	return columns
+}
+
+func (r *gormNoteRepository) Delete(ctx context.Context, note *models.Note) error {
+	return r.db.WithContext(ctx).Select("Labels", "Attachments").Delete(note).Error
+}
+
+func (r *gormNoteRepository) Move(ctx context.Context, userID, noteID int64, after, before *int64) (string, error) {
+	var position string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		position, err = database.MoveNote(tx, userID, noteID, after, before)
+		return err
+	})
+	switch {
+	case errors.Is(err, database.ErrAnchorNotFound):
+		return "", ErrAnchorNotFound
+	case errors.Is(err, rank.ErrInvalidRange):
+		return "", ErrAnchorOrder
+	}
+	return position, err
+}
+
+// eachBatchSize is the number of notes loaded per query by Each.
+const eachBatchSize = 200
+
+func (r *gormNoteRepository) Each(ctx context.Context, userID int64, filter *NoteFilter, fn func(note models.Note) error) error {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if filter != nil {
+		query = filter.Apply(query, userID)
+	}
+
+	var batch []models.Note
+	return query.Preload("Labels").Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("data").Order("id")
+	}).Order("id").
+		FindInBatches(&batch, eachBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, note := range batch {
+				if err := fn(note); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+func (r *gormNoteRepository) Attachment(ctx context.Context, userID, attachmentID int64) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", attachmentID, userID).
+		First(&attachment).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &attachment, nil
+}
+
+func (r *gormNoteRepository) Labels(ctx context.Context, userID int64) ([]models.Label, error) {
+	var labels []models.Label
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// translate maps GORM errors onto the repository errors.
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+// Package repository wraps data access behind interfaces so the rules in
+// the service layer and the HTTP handlers can run without Postgres. Each
+// repository has a GORM implementation and an in-memory one.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// ErrNotFound is returned when a record doesn't exist or, for per-user
+// records, belongs to another user.
+var ErrNotFound = errors.New("record not found")
+
+// ErrAnchorNotFound is returned by NoteRepository.Move when an anchor note
+// doesn't exist or belongs to another user.
+var ErrAnchorNotFound = errors.New("anchor note not found")
+
+// ErrAnchorOrder is returned by NoteRepository.Move when the "after" anchor
+// doesn't come before the "before" anchor.
+var ErrAnchorOrder = errors.New("anchors are out of order")
+
+// ErrEmailTaken is returned by EmailChangeRepository.Apply when another
+// account has the new email address.
+var ErrEmailTaken = errors.New("email already in use")
+
+// ErrImportInProgress is returned by ImportJobRepository.Start when the
+// user already has an import that is still active.
+var ErrImportInProgress = errors.New("another import is in progress")
+
+// NoteExpansion selects the related data loaded with a note.
+type NoteExpansion struct {
+	Labels      bool
+	Attachments bool // metadata only, never the file contents
+}
+
+// NoteChanges is a partial update of a note.
+type NoteChanges struct {
+	// Fields maps column names (title, content, color, pinned, archived,
+	// trashed_at) to their new values.
+	Fields map[string]interface{}
+	// Labels replaces the note's labels when SetLabels is true; missing
+	// labels are created.
+	Labels    []string
+	SetLabels bool
+}
+
+// BatchResult is the outcome of NoteRepository.Batch.
+type BatchResult struct {
+	// Results has an entry per ID of the request, in the same order.
+	Results []models.BatchNoteResult
+	// RolledBack is set when an atomic batch failed and nothing was changed.
+	RolledBack bool
+	// Notes are the user's notes among the IDs as they were before the
+	// batch, without labels.
+	Notes map[int64]models.Note
+	// Errors holds the cause of every result with status failed.
+	Errors map[int64]error
+}
+
+// NoteRepository stores notes. Every method is scoped to one user.
+type NoteRepository interface {
+	// Create stores a new note at the top of the manual order with the named
+	// labels, creating labels that don't exist yet.
+	Create(ctx context.Context, note *models.Note, labels []string) error
+	// List returns the notes matching filter, with their labels.
+	List(ctx context.Context, userID int64, filter NoteFilter) ([]models.Note, error)
+	// Get returns one note with the requested related data.
+	Get(ctx context.Context, userID, noteID int64, expand NoteExpansion) (*models.Note, error)
+	// Update applies changes to note and reloads it with its labels.
+	Update(ctx context.Context, note *models.Note, changes NoteChanges) error
+	// Delete removes a note together with its label links and attachments.
+	Delete(ctx context.Context, note *models.Note) error
+	// Move places a note in the manual order after and/or before the anchor
+	// notes and returns its new position.
+	Move(ctx context.Context, userID, noteID int64, after, before *int64) (string, error)
+	// Batch applies one operation to the notes req.IDs, which must be
+	// unique, in a single transaction. IDs of other users' notes are
+	// reported as not found. In atomic mode any failure rolls back the
+	// whole batch; in partial mode the other notes are still changed.
+	Batch(ctx context.Context, userID int64, req models.BatchNotesRequest) (*BatchResult, error)
+	// Each calls fn for every note matching filter, or every note when
+	// filter is nil, in ID order, with labels and attachment metadata.
+	Each(ctx context.Context, userID int64, filter *NoteFilter, fn func(note models.Note) error) error
+	// Attachment returns an attachment of the user including its contents.
+	Attachment(ctx context.Context, userID, attachmentID int64) (*models.Attachment, error)
+	// Labels returns the user's labels by name.
+	Labels(ctx context.Context, userID int64) ([]models.Label, error)
+}
+
+// UserRepository stores user accounts.
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id int64) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// Update sets the given columns of user and updates the struct.
+	Update(ctx context.Context, user *models.User, fields map[string]interface{}) error
+}
+
+// EmailChangeRepository stores pending email changes by the hash of the
+// confirmation token.
+type EmailChangeRepository interface {
+	// Replace stores change as the only pending change of its user.
+	Replace(ctx context.Context, change *models.EmailChange) error
+	FindByHash(ctx context.Context, hash string) (*models.EmailChange, error)
+	Delete(ctx context.Context, change *models.EmailChange) error
+	// Apply sets the user's email to the new address and removes the
+	// change, or returns ErrEmailTaken when another account has it.
+	Apply(ctx context.Context, change *models.EmailChange) (*models.User, error)
+}
+
+// ImportJobRepository stores background import jobs.
+type ImportJobRepository interface {
+	// Start creates job unless its user has a pending or running job that
+	// was updated after since, and returns ErrImportInProgress then. The
+	// check and the insert are atomic.
+	Start(ctx context.Context, job *models.ImportJob, since time.Time) error
+	// Active reports whether the user has a pending or running job that
+	// was updated after since.
+	Active(ctx context.Context, userID int64, since time.Time) (bool, error)
+	// List returns the user's jobs, newest first, without their reports.
+	List(ctx context.Context, userID int64) ([]models.ImportJob, error)
+	Get(ctx context.Context, userID, jobID int64) (*models.ImportJob, error)
+	// Fail marks a job that never ran as failed with message.
+	Fail(ctx context.Context, job *models.ImportJob, message string) error
+}
+
+// TokenRepository stores refresh tokens by the hash of the raw token.
+type TokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	Revoke(ctx context.Context, token *models.RefreshToken) error
+	// RevokeAll revokes every active refresh token of the user.
+	RevokeAll(ctx context.Context, userID int64) error
+}
+
+// AuditRepository stores audit events. Events can't be changed or removed.
+type AuditRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	// List returns the events matching filter, newest first.
+	List(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error)
+}
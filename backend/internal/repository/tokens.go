@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type gormTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository returns a TokenRepository backed by db.
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &gormTokenRepository{db: db}
+}
+
+func (r *gormTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormTokenRepository) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *gormTokenRepository) Revoke(ctx context.Context, token *models.RefreshToken) error {
+	token.Revoked = true
+	return r.db.WithContext(ctx).Model(token).Update("revoked", true).Error
+}
+
+func (r *gormTokenRepository) RevokeAll(ctx context.Context, userID int64) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked = ?", userID, false).
+		Update("revoked", true).Error
+}
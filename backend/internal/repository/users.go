@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository returns a UserRepository backed by db.
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) Update(ctx context.Context, user *models.User, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(user).Updates(fields).Error
+}
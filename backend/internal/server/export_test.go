@@ -0,0 +1,223 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/export"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/rank"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+// upload posts data as the multipart field "file".
+func (a *api) upload(path string, data []byte, out interface{}) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	w, err := mw.CreateFormFile("file", "export.zip")
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	if err == nil {
+		err = mw.Close()
+	}
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("POST %s: decode %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+// waitForImport polls an import job until it has finished.
+func (a *api) waitForImport(id int64) models.ImportJob {
+	a.t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job models.ImportJob
+		a.expect(http.StatusOK, http.MethodGet, "/api/import/jobs/"+strconv.FormatInt(id, 10), nil, &job)
+		if job.Status == models.ImportStatusCompleted || job.Status == models.ImportStatusFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			a.t.Fatalf("import job %d still %s", id, job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// noteSnapshot is a note without the IDs that differ between accounts.
+type noteSnapshot struct {
+	Title, Content, Color string
+	Pinned, Archived      bool
+	TrashedAt             string
+	Position              string
+	Revision              int
+	Labels                []string
+	CreatedAt, UpdatedAt  string
+	Attachments           []attachmentSnapshot
+}
+
+type attachmentSnapshot struct {
+	Filename, MimeType string
+	Size               int64
+	Data               string
+	CreatedAt          string
+}
+
+// snapshot loads all notes of a user, ordered by title.
+func snapshot(t *testing.T, db *gorm.DB, userID int64) []noteSnapshot {
+	t.Helper()
+	var notes []models.Note
+	if err := db.Preload("Labels").Preload("Attachments").Where("user_id = ?", userID).
+		Order("title").Find(&notes).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stamp := func(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
+	out := make([]noteSnapshot, len(notes))
+	for i, n := range notes {
+		s := noteSnapshot{
+			Title: n.Title, Content: n.Content, Color: n.Color,
+			Pinned: n.Pinned, Archived: n.Archived,
+			Position: n.Position, Revision: n.Revision,
+			CreatedAt: stamp(n.CreatedAt), UpdatedAt: stamp(n.UpdatedAt),
+		}
+		if n.TrashedAt != nil {
+			s.TrashedAt = stamp(*n.TrashedAt)
+		}
+		for _, l := range n.Labels {
+			s.Labels = append(s.Labels, l.Name)
+		}
+		sort.Strings(s.Labels)
+		for _, a := range n.Attachments {
+			s.Attachments = append(s.Attachments, attachmentSnapshot{
+				Filename: a.Filename, MimeType: a.MimeType, Size: a.Size,
+				Data: string(a.Data), CreatedAt: stamp(a.CreatedAt),
+			})
+		}
+		sort.Slice(s.Attachments, func(i, j int) bool { return s.Attachments[i].Filename < s.Attachments[j].Filename })
+		out[i] = s
+	}
+	return out
+}
+
+func TestJSONExportRoundTrip(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		anon := newAPI(t, db)
+		alice, aliceSession := anon.register("alice@example.com")
+		bob, bobSession := anon.register("bob@example.com")
+		aliceID := aliceSession.User.ID
+
+		day := func(d int) time.Time { return time.Date(2024, 3, d, 9, 30, 0, 0, time.UTC) }
+		trashedAt := day(20)
+		positions := rank.Spread(3)
+		notes := []models.Note{
+			{Title: "Groceries", Content: "milk\neggs", Color: "green", Pinned: true, Position: positions[0], CreatedAt: day(1), UpdatedAt: day(2)},
+			{Title: "Old trip", Content: "Lisbon", Color: "blue", Archived: true, Position: positions[1], Revision: 4, CreatedAt: day(3), UpdatedAt: day(4)},
+			{Title: "Scratch", Position: positions[2], TrashedAt: &trashedAt, CreatedAt: day(5), UpdatedAt: day(6)},
+		}
+		labels := [][]string{{"home", "shopping"}, {"travel"}, nil}
+		for i := range notes {
+			notes[i].UserID = aliceID
+			var err error
+			if notes[i].Labels, err = database.FindOrCreateLabels(db, aliceID, labels[i]); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Create(&notes[i]).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, a := range []models.Attachment{
+			{NoteID: notes[0].ID, Filename: "list.txt", MimeType: "text/plain; charset=utf-8", Data: []byte("milk"), CreatedAt: day(2)},
+			{NoteID: notes[1].ID, Filename: "photo.jpg", MimeType: "image/jpeg", Data: []byte{0xff, 0xd8, 0xff}, CreatedAt: day(4)},
+		} {
+			a.UserID, a.Size = aliceID, int64(len(a.Data))
+			if err := db.Create(&a).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		rec := alice.do(http.MethodGet, "/api/notes/export?format=json", nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("export: status %d: %s", rec.Code, rec.Body)
+		}
+
+		var job models.ImportJob
+		if rec := bob.upload("/api/import/json", rec.Body.Bytes(), &job); rec.Code != http.StatusAccepted {
+			t.Fatalf("import: status %d: %s", rec.Code, rec.Body)
+		}
+		if job = bob.waitForImport(job.ID); job.Status != models.ImportStatusCompleted || job.Imported != len(notes) {
+			t.Fatalf("import job = %+v", job)
+		}
+
+		want := snapshot(t, db, aliceID)
+		if len(want) != len(notes) {
+			t.Fatalf("alice has %d notes, want %d", len(want), len(notes))
+		}
+		if got := snapshot(t, db, bobSession.User.ID); !reflect.DeepEqual(got, want) {
+			t.Errorf("imported notes differ from the exported ones\n got: %+v\nwant: %+v", got, want)
+		}
+
+		// With a filter the listing defaults apply again
+		for query, titles := range map[string][]string{
+			"color=blue":               nil,
+			"color=blue&archived=true": {"Old trip"},
+			"sort=title":               {"Groceries", "Old trip", "Scratch"},
+		} {
+			rec := alice.do(http.MethodGet, "/api/notes/export?format=json&"+query, nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("export with %s: status %d: %s", query, rec.Code, rec.Body)
+			}
+			if got := exportedTitles(t, rec.Body.Bytes()); !reflect.DeepEqual(got, titles) {
+				t.Errorf("export with %s = %q, want %q", query, got, titles)
+			}
+		}
+	})
+}
+
+// exportedTitles returns the sorted note titles of a JSON export archive.
+func exportedTitles(t *testing.T, data []byte) []string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open(export.DocumentFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var doc export.Document
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, n := range doc.Notes {
+		titles = append(titles, n.Title)
+	}
+	sort.Strings(titles)
+	return titles
+}
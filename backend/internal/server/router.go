@@ -0,0 +1,161 @@
+// Package server assembles the HTTP API: the repositories, services and
+// handlers on a database, the middleware and the routes.
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/admin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/cors"
+	"github.com/tgogbera/google_keep_clone-backend/internal/handlers"
+	"github.com/tgogbera/google_keep_clone-backend/internal/health"
+	"github.com/tgogbera/google_keep_clone-backend/internal/importer"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/metrics"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/openapi"
+	"github.com/tgogbera/google_keep_clone-backend/internal/ratelimit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+	"github.com/tgogbera/google_keep_clone-backend/internal/service"
+	"github.com/tgogbera/google_keep_clone-backend/internal/tracing"
+	"gorm.io/gorm"
+)
+
+// LoginRule names the rate limit rule of the login endpoint, which prefixes
+// its lockout keys.
+const LoginRule = "login"
+
+// NewRouter builds the handlers and the router of the API on db. readiness
+// serves /readyz.
+func NewRouter(cfg *config.Config, db *gorm.DB, limiter *ratelimit.Limiter, readiness *health.Checker) *gin.Engine {
+	// Repositories, services and handlers
+	users := repository.NewUserRepository(db)
+	tokens := repository.NewTokenRepository(db)
+	events := repository.NewAuditRepository(db)
+	auditLog := audit.New(events)
+	notes := service.NewNoteService(repository.NewNoteRepository(db), users, auditLog)
+	authHandler := handlers.NewAuthHandler(users, tokens, auditLog)
+	accountHandler := handlers.NewAccountHandler(users, tokens, repository.NewEmailChangeRepository(db), notes, auditLog)
+	noteHandler := handlers.NewNoteHandler(notes)
+	importHandler := handlers.NewImportHandler(repository.NewImportJobRepository(db), importer.New(db, auditLog))
+	adminHandler := handlers.NewAdminHandler(admin.NewService(db), auditLog)
+	auditHandler := handlers.NewAuditHandler(events, auditLog)
+
+	// Create router without default middleware (we'll add our own)
+	router := gin.New()
+
+	// Request ID first, so every log line of the request carries it
+	router.Use(logger.RequestIDMiddleware())
+
+	// Request metrics and tracing, outside recovery so panics are seen as 500s
+	router.Use(metrics.Middleware(), tracing.Middleware())
+
+	// Errors returned by handlers and middleware become problem documents
+	router.Use(apierror.Middleware())
+	router.NoRoute(apierror.NoRoute)
+
+	// Add recovery middleware (handles panics)
+	router.Use(logger.RecoveryLogger())
+
+	// Add request logging middleware
+	router.Use(logger.RequestLogger())
+
+	// CORS, answering preflight requests before they reach the routes
+	router.Use(cors.Middleware(cors.Policy{
+		AllowedOrigins: cfg.CORSAllowedOrigins,
+		AllowedMethods: cfg.CORSAllowedMethods,
+		AllowedHeaders: cfg.CORSAllowedHeaders,
+		ExposedHeaders: []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Request-ID"},
+		MaxAge:         cfg.CORSMaxAge,
+	}))
+
+	// Health checks: /ping and /healthz only say the process is up, /readyz
+	// also checks the dependencies
+	router.GET("/ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"message": "pong",
+		})
+	})
+	router.GET("/healthz", health.Live)
+	router.GET("/readyz", readiness.Ready)
+
+	// Metrics on the API port when they have no listener of their own
+	if cfg.MetricsAddr == "" && cfg.MetricsToken != "" {
+		router.GET("/metrics", gin.WrapH(metrics.Handler(cfg.MetricsToken)))
+	}
+
+	// API description
+	router.GET("/openapi.json", openapi.Spec)
+	router.GET("/docs", openapi.Docs)
+
+	// Auth routes
+	api := router.Group("/api")
+	{
+		api.POST("/register", limiter.Middleware(ratelimit.Rule{
+			Name:  "register",
+			PerIP: ratelimit.PerMinute(cfg.RegisterRateLimitPerIP),
+		}), apierror.Handler(authHandler.Register))
+		api.POST("/login", limiter.Middleware(ratelimit.Rule{
+			Name:       LoginRule,
+			PerIP:      ratelimit.PerMinute(cfg.LoginRateLimitPerIP),
+			PerAccount: ratelimit.PerMinute(cfg.LoginRateLimitPerEmail),
+			Lockout: ratelimit.LockoutPolicy{
+				Threshold: cfg.LockoutThreshold,
+				BaseDelay: cfg.LockoutBaseDuration,
+				MaxDelay:  cfg.LockoutMaxDuration,
+				Window:    cfg.LockoutMaxDuration,
+			},
+		}), apierror.Handler(authHandler.Login))
+		api.POST("/refresh", apierror.Handler(authHandler.Refresh))
+		api.POST("/logout", apierror.Handler(authHandler.Logout))
+		api.POST("/email/confirm", apierror.Handler(accountHandler.ConfirmEmailChange))
+	}
+
+	// Protected routes example
+	protected := api.Group("/")
+	protected.Use(handlers.AuthMiddleware(users))
+	{
+
+		protected.GET("/me", apierror.Handler(accountHandler.GetMe))
+		protected.PATCH("/me", apierror.Handler(accountHandler.UpdateMe))
+		protected.POST("/me/password", apierror.Handler(authHandler.ChangePassword))
+		protected.POST("/me/email", apierror.Handler(accountHandler.RequestEmailChange))
+		protected.GET("/me/export", apierror.Handler(accountHandler.ExportAccount))
+		protected.DELETE("/me", apierror.Handler(accountHandler.DeleteAccount))
+		protected.GET("/me/activity", apierror.Handler(auditHandler.MyActivity))
+
+		// Note routes
+		protected.POST("/notes", apierror.Handler(noteHandler.CreateNote))
+		protected.GET("/notes", apierror.Handler(noteHandler.GetAllNotes))
+		protected.GET("/notes/export", apierror.Handler(noteHandler.ExportNotes))
+		protected.POST("/notes/batch", apierror.Handler(noteHandler.BatchNotes))
+		protected.GET("/notes/:id", apierror.Handler(noteHandler.GetNote))
+		protected.POST("/notes/:id/move", apierror.Handler(noteHandler.MoveNote))
+		protected.PUT("/notes/:id", apierror.Handler(noteHandler.UpdateNote))
+		protected.DELETE("/notes/:id", apierror.Handler(noteHandler.DeleteNote))
+		protected.GET("/attachments/:id", apierror.Handler(noteHandler.GetAttachment))
+
+		// Import routes
+		protected.POST("/import/keep", apierror.Handler(importHandler.ImportKeep))
+		protected.POST("/import/json", apierror.Handler(importHandler.ImportJSON))
+		protected.GET("/import/jobs", apierror.Handler(importHandler.ListImportJobs))
+		protected.GET("/import/jobs/:id", apierror.Handler(importHandler.GetImportJob))
+	}
+
+	// Admin routes (role checked against the database on every request)
+	adminRoutes := api.Group("/admin")
+	adminRoutes.Use(handlers.AuthMiddleware(users), handlers.RequireRole(users, models.RoleAdmin))
+	{
+		adminRoutes.GET("/users", apierror.Handler(adminHandler.ListUsers))
+		adminRoutes.GET("/users/:id", apierror.Handler(adminHandler.GetUser))
+		adminRoutes.POST("/users/:id/disable", apierror.Handler(adminHandler.DisableUser))
+		adminRoutes.POST("/users/:id/enable", apierror.Handler(adminHandler.EnableUser))
+		adminRoutes.POST("/users/:id/logout", apierror.Handler(adminHandler.LogoutUser))
+		adminRoutes.PUT("/users/:id/role", apierror.Handler(adminHandler.SetRole))
+		adminRoutes.GET("/audit", apierror.Handler(auditHandler.QueryEvents))
+	}
+
+	return router
+}
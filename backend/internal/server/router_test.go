@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/health"
+	"github.com/tgogbera/google_keep_clone-backend/internal/openapi"
+	"github.com/tgogbera/google_keep_clone-backend/internal/ratelimit"
+)
+
+// TestRoutesAreDocumented is "server openapi check" as a test: every route
+// is in the OpenAPI document and the document describes no other routes.
+func TestRoutesAreDocumented(t *testing.T) {
+	cfg := *config.Get()
+	cfg.MetricsAddr, cfg.MetricsToken = "", "check"
+	router := NewRouter(&cfg, nil, ratelimit.New(ratelimit.NewMemoryStore()), health.NewChecker())
+
+	missing, stale, err := openapi.Compare(router.Routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range missing {
+		t.Errorf("not documented: %s", op)
+	}
+	for _, op := range stale {
+		t.Errorf("documented but not served: %s", op)
+	}
+}
+
+// Browsers send a preflight before every PATCH, so the default methods
+// must allow it.
+func TestPreflightAllowsPatch(t *testing.T) {
+	cfg := *config.Get()
+	cfg.CORSAllowedOrigins = []string{"https://notes.example.com"}
+	router := NewRouter(&cfg, nil, ratelimit.New(ratelimit.NewMemoryStore()), health.NewChecker())
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/notes/1", nil)
+	req.Header.Set("Origin", "https://notes.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight status %d, want 204", rec.Code)
+	}
+	methods := strings.Split(rec.Header().Get("Access-Control-Allow-Methods"), ", ")
+	if !slices.Contains(methods, http.MethodPatch) {
+		t.Errorf("Allow-Methods = %q, want PATCH", methods)
+	}
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tgogbera/google_keep_clone-backend/internal/apierror"
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/handlers"
+	"github.com/tgogbera/google_keep_clone-backend/internal/health"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/ratelimit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.Init(logger.Config{Level: logger.LevelError, Output: io.Discard})
+	if err := config.Load(config.Options{Flags: map[string]string{"auth.jwt_secret": "test"}}); err != nil {
+		panic(err)
+	}
+	if err := handlers.RegisterValidators(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// api sends requests to the router as one user.
+type api struct {
+	t      *testing.T
+	router http.Handler
+	token  string
+}
+
+func newAPI(t *testing.T, db *gorm.DB) *api {
+	return &api{t: t, router: NewRouter(config.Get(), db, ratelimit.New(ratelimit.NewMemoryStore()), health.NewChecker())}
+}
+
+// do sends body encoded as JSON and decodes the response into out, if given.
+func (a *api) do(method, path string, body, out interface{}) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+// expect sends a request and fails the test unless it answers status.
+func (a *api) expect(status int, method, path string, body, out interface{}) {
+	a.t.Helper()
+	if rec := a.do(method, path, body, out); rec.Code != status {
+		a.t.Fatalf("%s %s: status %d, want %d: %s", method, path, rec.Code, status, rec.Body)
+	}
+}
+
+// register creates an account and returns an api logged in to it.
+func (a *api) register(email string) (*api, models.AuthResponse) {
+	a.t.Helper()
+	var resp models.AuthResponse
+	a.expect(http.StatusCreated, http.MethodPost, "/api/register", models.RegisterRequest{Email: email, Password: "password"}, &resp)
+	return &api{t: a.t, router: a.router, token: resp.Token}, resp
+}
+
+// code returns the problem code of an error response.
+func code(t *testing.T, rec *httptest.ResponseRecorder) apierror.Code {
+	t.Helper()
+	var problem apierror.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body.String(), err)
+	}
+	return problem.Code
+}
+
+func notePath(id int64) string {
+	return "/api/notes/" + strconv.FormatInt(id, 10)
+}
+
+func titles(notes []models.Note) []string {
+	out := make([]string, len(notes))
+	for i, n := range notes {
+		out[i] = n.Title
+	}
+	return out
+}
+
+func TestSession(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		anon := newAPI(t, db)
+		alice, session := anon.register("alice@example.com")
+
+		var me models.UserDTO
+		alice.expect(http.StatusOK, http.MethodGet, "/api/me", nil, &me)
+		if me.Email != "alice@example.com" {
+			t.Errorf("me = %+v", me)
+		}
+		if rec := anon.do(http.MethodPost, "/api/register", models.RegisterRequest{Email: "alice@example.com", Password: "password"}, nil); rec.Code != http.StatusConflict {
+			t.Errorf("register twice: status %d: %s", rec.Code, rec.Body)
+		}
+		if rec := anon.do(http.MethodPost, "/api/login", models.LoginRequest{Email: "alice@example.com", Password: "wrong"}, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("wrong password: status %d: %s", rec.Code, rec.Body)
+		}
+
+		var pair struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		anon.expect(http.StatusOK, http.MethodPost, "/api/refresh", map[string]string{"refresh_token": session.RefreshToken}, &pair)
+		if rec := anon.do(http.MethodPost, "/api/refresh", map[string]string{"refresh_token": session.RefreshToken}, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("rotated refresh token reused: status %d: %s", rec.Code, rec.Body)
+		}
+		if rec := anon.do(http.MethodGet, "/api/me", nil, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("no access token: status %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
+
+// Logins match emails exactly, and so do lockouts: failures with another
+// spelling of an email lock only that spelling.
+func TestLoginLockoutKey(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		anon := newAPI(t, db)
+		anon.register("alice@example.com")
+		threshold := config.Get().LockoutThreshold
+
+		for i := 0; i < threshold; i++ {
+			if rec := anon.do(http.MethodPost, "/api/login", models.LoginRequest{Email: "ALICE@example.com", Password: "password"}, nil); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("login %d with another spelling: status %d: %s", i+1, rec.Code, rec.Body)
+			}
+		}
+		if rec := anon.do(http.MethodPost, "/api/login", models.LoginRequest{Email: "ALICE@example.com", Password: "password"}, nil); rec.Code != http.StatusTooManyRequests || code(t, rec) != apierror.CodeAccountLocked {
+			t.Errorf("login after %d failures: status %d: %s", threshold, rec.Code, rec.Body)
+		}
+		anon.expect(http.StatusOK, http.MethodPost, "/api/login", models.LoginRequest{Email: "alice@example.com", Password: "password"}, nil)
+	})
+}
+
+func TestNotes(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		anon := newAPI(t, db)
+		alice, _ := anon.register("alice@example.com")
+		bob, _ := anon.register("bob@example.com")
+
+		var groceries, trip models.Note
+		alice.expect(http.StatusCreated, http.MethodPost, "/api/notes", models.CreateNoteRequest{
+			Title: "Groceries", Content: "milk and eggs", Labels: []string{"home"},
+		}, &groceries)
+		alice.expect(http.StatusCreated, http.MethodPost, "/api/notes", models.CreateNoteRequest{
+			Title: "Trip", Content: "book the train", Color: "blue", Labels: []string{"travel", "home"},
+		}, &trip)
+
+		var notes []models.Note
+		alice.expect(http.StatusOK, http.MethodGet, "/api/notes?sort=title", nil, &notes)
+		if got := titles(notes); len(got) != 2 || got[0] != "Groceries" || got[1] != "Trip" {
+			t.Errorf("notes by title = %v", got)
+		}
+		alice.expect(http.StatusOK, http.MethodGet, "/api/notes?label=travel", nil, &notes)
+		if got := titles(notes); len(got) != 1 || got[0] != "Trip" {
+			t.Errorf("notes labeled travel = %v", got)
+		}
+		alice.expect(http.StatusOK, http.MethodGet, "/api/notes?q=milk", nil, &notes)
+		if got := titles(notes); len(got) != 1 || got[0] != "Groceries" {
+			t.Errorf("notes matching milk = %v", got)
+		}
+		alice.expect(http.StatusOK, http.MethodGet, "/api/notes?color=blue", nil, &notes)
+		if got := titles(notes); len(got) != 1 || got[0] != "Trip" {
+			t.Errorf("blue notes = %v", got)
+		}
+		bob.expect(http.StatusOK, http.MethodGet, "/api/notes", nil, &notes)
+		if len(notes) != 0 {
+			t.Errorf("notes of another user listed: %v", titles(notes))
+		}
+
+		var note models.Note
+		alice.expect(http.StatusOK, http.MethodGet, notePath(trip.ID)+"?expand=labels", nil, &note)
+		if len(note.Labels) != 2 || note.Labels[0].Name != "home" {
+			t.Errorf("expanded labels = %v, want home and travel", note.Labels)
+		}
+		alice.expect(http.StatusOK, http.MethodPut, notePath(trip.ID), map[string]interface{}{"title": "Holiday", "archived": true}, &note)
+		if note.Title != "Holiday" || !note.Archived {
+			t.Errorf("updated note = %+v", note)
+		}
+		alice.expect(http.StatusOK, http.MethodPut, notePath(trip.ID), map[string]interface{}{"content": "- [x] train\n- [ ] hotel"}, nil)
+		alice.expect(http.StatusOK, http.MethodGet, notePath(trip.ID)+"?expand=checklist_items,revision_count", nil, &note)
+		if len(note.ChecklistItems) != 2 || !note.ChecklistItems[0].Checked || note.ChecklistItems[1].Text != "hotel" {
+			t.Errorf("expanded checklist items = %+v", note.ChecklistItems)
+		}
+		if note.RevisionCount == nil || *note.RevisionCount != 3 {
+			t.Errorf("revision count = %v after two edits, want 3", note.RevisionCount)
+		}
+		edited := note.UpdatedAt
+		alice.expect(http.StatusOK, http.MethodPut, notePath(trip.ID), map[string]interface{}{"labels": []string{"travel"}}, nil)
+		alice.expect(http.StatusOK, http.MethodGet, notePath(trip.ID)+"?expand=revision_count", nil, &note)
+		if note.RevisionCount == nil || *note.RevisionCount != 4 || !note.UpdatedAt.After(edited) {
+			t.Errorf("after changing only the labels: revision count %v, updated at %v, was %v", note.RevisionCount, note.UpdatedAt, edited)
+		}
+		alice.expect(http.StatusOK, http.MethodGet, "/api/notes", nil, &notes)
+		if got := titles(notes); len(got) != 1 || got[0] != "Groceries" {
+			t.Errorf("notes without the archived = %v", got)
+		}
+
+		for _, req := range []struct{ method, path string }{
+			{http.MethodGet, notePath(groceries.ID)},
+			{http.MethodPut, notePath(groceries.ID)},
+			{http.MethodDelete, notePath(groceries.ID)},
+		} {
+			if rec := bob.do(req.method, req.path, map[string]interface{}{"title": "Mine"}, nil); rec.Code != http.StatusNotFound || code(t, rec) != apierror.CodeNotFound {
+				t.Errorf("%s %s by another user: status %d: %s", req.method, req.path, rec.Code, rec.Body)
+			}
+		}
+		if rec := alice.do(http.MethodPut, notePath(groceries.ID), map[string]interface{}{"color": "chartreuse"}, nil); rec.Code != http.StatusBadRequest || code(t, rec) != apierror.CodeValidationFailed {
+			t.Errorf("invalid color: status %d: %s", rec.Code, rec.Body)
+		}
+
+		alice.expect(http.StatusOK, http.MethodDelete, notePath(groceries.ID), nil, nil)
+		if rec := alice.do(http.MethodGet, notePath(groceries.ID), nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("deleted note: status %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
+
+func TestMoveNotes(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		alice, _ := newAPI(t, db).register("alice@example.com")
+		notes := map[string]models.Note{}
+		for _, title := range []string{"first", "second", "third"} {
+			var note models.Note
+			alice.expect(http.StatusCreated, http.MethodPost, "/api/notes", models.CreateNoteRequest{Title: title}, &note)
+			notes[title] = note
+		}
+
+		first, third := notes["first"].ID, notes["third"].ID
+		alice.expect(http.StatusOK, http.MethodPost, notePath(first)+"/move", models.MoveNoteRequest{Before: &third}, nil)
+		alice.expect(http.StatusOK, http.MethodPost, notePath(third)+"/move", models.MoveNoteRequest{After: &first}, nil)
+
+		var list []models.Note
+		alice.expect(http.StatusOK, http.MethodGet, "/api/notes?sort=manual", nil, &list)
+		if got := titles(list); len(got) != 3 || got[0] != "first" || got[1] != "third" || got[2] != "second" {
+			t.Errorf("manual order = %v, want first, third, second", got)
+		}
+
+		// Reordering isn't an edit
+		var note models.Note
+		alice.expect(http.StatusOK, http.MethodGet, notePath(first)+"?expand=revision_count", nil, &note)
+		if note.RevisionCount == nil || *note.RevisionCount != 1 {
+			t.Errorf("revision count after a move = %v, want 1", note.RevisionCount)
+		}
+	})
+}
+
+func TestBatchNotes(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		anon := newAPI(t, db)
+		alice, _ := anon.register("alice@example.com")
+		bob, _ := anon.register("bob@example.com")
+
+		var a, b, theirs models.Note
+		alice.expect(http.StatusCreated, http.MethodPost, "/api/notes", models.CreateNoteRequest{Title: "a"}, &a)
+		alice.expect(http.StatusCreated, http.MethodPost, "/api/notes", models.CreateNoteRequest{Title: "b"}, &b)
+		bob.expect(http.StatusCreated, http.MethodPost, "/api/notes", models.CreateNoteRequest{Title: "theirs"}, &theirs)
+
+		var resp models.BatchNotesResponse
+		rec := alice.do(http.MethodPost, "/api/notes/batch", models.BatchNotesRequest{
+			IDs: []int64{a.ID, theirs.ID, b.ID}, Operation: models.BatchAddLabels, Labels: []string{"done"},
+		}, nil)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("atomic batch with another user's note: status %d: %s", rec.Code, rec.Body)
+		}
+		var note models.Note
+		alice.expect(http.StatusOK, http.MethodGet, notePath(a.ID)+"?expand=labels,revision_count", nil, &note)
+		if len(note.Labels) != 0 || note.RevisionCount == nil || *note.RevisionCount != 1 {
+			t.Errorf("after a rolled back batch: labels %v, revision count %v", note.Labels, note.RevisionCount)
+		}
+
+		alice.expect(http.StatusOK, http.MethodPost, "/api/notes/batch", models.BatchNotesRequest{
+			IDs: []int64{a.ID, theirs.ID, b.ID}, Operation: models.BatchAddLabels, Labels: []string{"done"}, Mode: models.BatchModePartial,
+		}, &resp)
+		if resp.Succeeded != 2 || resp.Failed != 1 || resp.Results[1].Status != models.BatchResultNotFound {
+			t.Errorf("partial batch = %+v", resp)
+		}
+		alice.expect(http.StatusOK, http.MethodGet, notePath(b.ID)+"?expand=labels,revision_count", nil, &note)
+		if len(note.Labels) != 1 || note.Labels[0].Name != "done" {
+			t.Errorf("labels after the batch = %v, want done", note.Labels)
+		}
+		if note.RevisionCount == nil || *note.RevisionCount != 2 {
+			t.Errorf("revision count after the batch = %v, want 2", note.RevisionCount)
+		}
+
+		alice.expect(http.StatusOK, http.MethodPost, "/api/notes/batch", models.BatchNotesRequest{
+			IDs: []int64{a.ID, b.ID}, Operation: models.BatchDelete,
+		}, &resp)
+		var list []models.Note
+		alice.expect(http.StatusOK, http.MethodGet, "/api/notes?archived=any&trashed=any", nil, &list)
+		if len(list) != 0 {
+			t.Errorf("notes after a batch delete = %v", titles(list))
+		}
+		bob.expect(http.StatusOK, http.MethodGet, notePath(theirs.ID), nil, nil)
+	})
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		anon := newAPI(t, db)
+		anon.register("root@example.com")
+		bob, bobSession := anon.register("bob@example.com")
+
+		if rec := bob.do(http.MethodGet, "/api/admin/users", nil, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("admin route as a user: status %d: %s", rec.Code, rec.Body)
+		}
+
+		if err := db.Model(&models.User{}).Where("email = ?", "root@example.com").Update("role", models.RoleAdmin).Error; err != nil {
+			t.Fatal(err)
+		}
+		var session models.AuthResponse
+		anon.expect(http.StatusOK, http.MethodPost, "/api/login", models.LoginRequest{Email: "root@example.com", Password: "password"}, &session)
+		root := &api{t: t, router: anon.router, token: session.Token}
+
+		var users []struct {
+			ID        int64  `json:"id"`
+			Email     string `json:"email"`
+			NoteCount int64  `json:"note_count"`
+		}
+		bob.expect(http.StatusCreated, http.MethodPost, "/api/notes", models.CreateNoteRequest{Title: "Bob's"}, nil)
+		root.expect(http.StatusOK, http.MethodGet, "/api/admin/users?search=bob", nil, &users)
+		if len(users) != 1 || users[0].NoteCount != 1 {
+			t.Fatalf("users matching bob = %+v", users)
+		}
+		for _, search := range []string{"%25", "_"} {
+			var matches []json.RawMessage
+			root.expect(http.StatusOK, http.MethodGet, "/api/admin/users?search="+search, nil, &matches)
+			if len(matches) != 0 {
+				t.Errorf("users matching %s literally = %s", search, matches)
+			}
+		}
+
+		root.expect(http.StatusOK, http.MethodPost, "/api/admin/users/"+strconv.FormatInt(users[0].ID, 10)+"/disable", nil, nil)
+		if rec := bob.do(http.MethodGet, "/api/notes", nil, nil); rec.Code != http.StatusForbidden || code(t, rec) != apierror.CodeAccountDisabled {
+			t.Errorf("access token of a disabled user: status %d: %s", rec.Code, rec.Body)
+		}
+		if rec := anon.do(http.MethodPost, "/api/refresh", map[string]string{"refresh_token": bobSession.RefreshToken}, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("refresh token of a disabled user: status %d: %s", rec.Code, rec.Body)
+		}
+
+		root.expect(http.StatusOK, http.MethodPost, "/api/admin/users/"+strconv.FormatInt(users[0].ID, 10)+"/enable", nil, nil)
+		bob.expect(http.StatusOK, http.MethodGet, "/api/notes", nil, nil)
+	})
+}
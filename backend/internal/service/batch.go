@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+)
+
+// Batch applies one operation to many of the user's notes in a single
+// transaction. Ownership of every ID is checked before anything is
+// changed. In atomic mode (the default) any failure rolls back the whole
+// batch; in partial mode failures are reported per ID. failures holds the
+// cause of every failed note, for the logs.
+func (s *NoteService) Batch(ctx context.Context, userID int64, req models.BatchNotesRequest) (resp *models.BatchNotesResponse, failures map[int64]error, err error) {
+	switch {
+	case req.Operation == models.BatchColor && req.Color == "":
+		return nil, nil, invalidField("color", "is required for the color operation")
+	case (req.Operation == models.BatchAddLabels || req.Operation == models.BatchRemoveLabels) && len(req.Labels) == 0:
+		return nil, nil, invalidField("labels", "are required for label operations")
+	}
+	if req.Mode == "" {
+		req.Mode = models.BatchModeAtomic
+	}
+	req.IDs = uniqueIDs(req.IDs)
+
+	result, err := s.notes.Batch(ctx, userID, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp = &models.BatchNotesResponse{
+		Operation:  req.Operation,
+		Mode:       req.Mode,
+		RolledBack: result.RolledBack,
+		Results:    result.Results,
+	}
+	for _, r := range result.Results {
+		if r.Status == models.BatchResultOK {
+			resp.Succeeded++
+			s.recordBatch(ctx, result.Notes[r.ID], req)
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp, result.Errors, nil
+}
+
+// recordBatch adds a note changed by a batch to the audit log. Deletes are
+// recorded as note.delete so they show up next to single deletes.
+func (s *NoteService) recordBatch(ctx context.Context, note models.Note, req models.BatchNotesRequest) {
+	event := models.AuditEvent{
+		UserID:     audit.ID(note.UserID),
+		Action:     audit.ActionNoteBatch,
+		TargetType: models.AuditTargetNote,
+		TargetID:   audit.ID(note.ID),
+		After:      map[string]interface{}{"operation": req.Operation},
+	}
+	switch req.Operation {
+	case models.BatchDelete:
+		event.Action = audit.ActionNoteDelete
+		event.Before = map[string]interface{}{"title": note.Title}
+	case models.BatchColor:
+		event.After["color"] = req.Color
+	case models.BatchAddLabels, models.BatchRemoveLabels:
+		event.After["labels"] = req.Labels
+	}
+	s.audit.Record(ctx, event)
+}
+
+// uniqueIDs removes duplicate IDs, keeping the first occurrence.
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"time"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+)
+
+// noteChanges turns the fields present in a raw JSON update into column
+// updates and label changes, checking the type of each value.
+func noteChanges(jsonData map[string]interface{}) (repository.NoteChanges, error) {
+	labels, setLabels, err := noteLabelNames(jsonData)
+	if err != nil {
+		return repository.NoteChanges{}, err
+	}
+	updates, err := noteUpdates(jsonData)
+	if err != nil {
+		return repository.NoteChanges{}, err
+	}
+	return repository.NoteChanges{Fields: updates, Labels: labels, SetLabels: setLabels}, nil
+}
+
+// noteUpdates turns the fields present in a raw JSON update into column
+// updates.
+func noteUpdates(jsonData map[string]interface{}) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+
+	for _, field := range []string{"title", "content"} {
+		if v, exists := jsonData[field]; exists {
+			s, ok := v.(string)
+			if !ok {
+				return nil, invalidField(field, "must be a string")
+			}
+			updates[field] = s
+		}
+	}
+	if title, exists := updates["title"]; exists && title == "" {
+		return nil, invalidField("title", "must not be empty")
+	}
+
+	if v, exists := jsonData["color"]; exists {
+		color, ok := v.(string)
+		if !ok || !models.IsNoteColor(color) {
+			return nil, invalidField("color", "must be a note color")
+		}
+		updates["color"] = color
+	}
+
+	for _, field := range []string{"pinned", "archived"} {
+		if v, exists := jsonData[field]; exists {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, invalidField(field, "must be a boolean")
+			}
+			updates[field] = b
+		}
+	}
+
+	// Moving a note to the trash records when it happened
+	if v, exists := jsonData["trashed"]; exists {
+		trashed, ok := v.(bool)
+		if !ok {
+			return nil, invalidField("trashed", "must be a boolean")
+		}
+		if trashed {
+			updates["trashed_at"] = time.Now()
+		} else {
+			updates["trashed_at"] = nil
+		}
+	}
+
+	return updates, nil
+}
+
+// noteLabelNames extracts the "labels" array of label names from a raw JSON
+// update. The boolean reports whether the field was present at all.
+func noteLabelNames(jsonData map[string]interface{}) ([]string, bool, error) {
+	v, exists := jsonData["labels"]
+	if !exists {
+		return nil, false, nil
+	}
+	if v == nil {
+		return []string{}, true, nil
+	}
+
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, false, invalidField("labels", "must be an array of names")
+	}
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok || len(name) > 100 {
+			return nil, false, invalidField("labels", "must be an array of names of at most 100 characters")
+		}
+		names = append(names, name)
+	}
+	return names, true, nil
+}
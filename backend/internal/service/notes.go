@@ -0,0 +1,274 @@
+// Package service holds the business rules of the API on top of the
+// repository interfaces, independent of HTTP and of the database.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/metrics"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+)
+
+var (
+	// ErrNoteNotFound is returned when a note doesn't exist or belongs to
+	// another user; the two cases are deliberately indistinguishable.
+	ErrNoteNotFound = errors.New("note not found")
+	// ErrAnchorNotFound is returned by Move for an unknown anchor note.
+	ErrAnchorNotFound = errors.New("anchor note not found")
+	// ErrAnchorOrder is returned by Move when "after" doesn't come before "before".
+	ErrAnchorOrder = errors.New("the after note must come before the before note")
+	// ErrAttachmentNotFound is returned by Attachment when an attachment
+	// doesn't exist or belongs to another user.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+// ValidationError reports invalid input; its message is meant for the client.
+// Field names the offending field of the request, if there is one.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field != "" {
+		return e.Field + " " + e.Message
+	}
+	return e.Message
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+func invalidField(field, format string, args ...interface{}) error {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// NoteService implements the note operations for one user at a time:
+// ownership, defaults, partial updates, expansion and manual ordering.
+// Changes are recorded in the audit log, attributed to the audit.Source of
+// the context.
+type NoteService struct {
+	notes repository.NoteRepository
+	users repository.UserRepository
+	audit *audit.Log
+}
+
+// NewNoteService returns a NoteService using the given repositories and
+// audit log.
+func NewNoteService(notes repository.NoteRepository, users repository.UserRepository, auditLog *audit.Log) *NoteService {
+	return &NoteService{notes: notes, users: users, audit: auditLog}
+}
+
+// Create stores a new note. Without a color the user's default note color
+// is used.
+func (s *NoteService) Create(ctx context.Context, userID int64, req models.CreateNoteRequest) (*models.Note, error) {
+	color := req.Color
+	if color == "" {
+		if user, err := s.users.FindByID(ctx, userID); err == nil {
+			color = user.Preferences.DefaultNoteColor
+		}
+	}
+
+	note := models.Note{
+		Title:    req.Title,
+		Content:  req.Content,
+		Color:    color,
+		Pinned:   req.Pinned,
+		Archived: req.Archived,
+		UserID:   userID,
+	}
+	if err := s.notes.Create(ctx, &note, req.Labels); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, audit.ActionNoteCreate, &note, nil, audit.NoteSummary(&note))
+	metrics.NotesCreated.Inc()
+	return &note, nil
+}
+
+// List returns the user's notes matching filter.
+func (s *NoteService) List(ctx context.Context, userID int64, filter repository.NoteFilter) ([]models.Note, error) {
+	return s.notes.List(ctx, userID, filter)
+}
+
+// Get returns one of the user's notes. expand is a comma-separated list of
+// related data to include: labels, attachments, checklist_items,
+// revision_count.
+func (s *NoteService) Get(ctx context.Context, userID, noteID int64, expand string) (*models.Note, error) {
+	var expansion repository.NoteExpansion
+	var checklist, revisions bool
+	for _, name := range strings.Split(expand, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "labels":
+			expansion.Labels = true
+		case "attachments":
+			expansion.Attachments = true
+		case "checklist_items":
+			checklist = true
+		case "revision_count":
+			revisions = true
+		default:
+			return nil, invalidField("expand", "has unknown value %q: supported values are labels, attachments, checklist_items, revision_count", name)
+		}
+	}
+
+	note, err := s.get(ctx, userID, noteID, expansion)
+	if err != nil {
+		return nil, err
+	}
+	// Both come with the note's row, so they cost no extra query
+	if checklist {
+		note.ChecklistItems = models.ParseChecklist(note.Content)
+	}
+	if revisions {
+		count := note.Revision
+		note.RevisionCount = &count
+	}
+	return note, nil
+}
+
+// Update applies a partial update given as the raw JSON object of the
+// request: only the fields present are changed.
+func (s *NoteService) Update(ctx context.Context, userID, noteID int64, fields map[string]interface{}) (*models.Note, error) {
+	note, err := s.get(ctx, userID, noteID, repository.NoteExpansion{Labels: true})
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := noteChanges(fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes.Fields) == 0 && !changes.SetLabels {
+		return nil, invalid("At least one field must be provided")
+	}
+
+	before, content := audit.NoteSummary(note), note.Content
+	if err := s.notes.Update(ctx, note, changes); err != nil {
+		return nil, err
+	}
+
+	after := audit.NoteSummary(note)
+	changed := diffSummaries(before, after)
+	// An edit keeping the length of the content is still an edit
+	if note.Content != content {
+		changed = append(changed, "content_length")
+	}
+	before, after = pick(before, changed), pick(after, changed)
+	s.record(ctx, audit.ActionNoteUpdate, note, before, after)
+	return note, nil
+}
+
+// Delete permanently removes one of the user's notes.
+func (s *NoteService) Delete(ctx context.Context, userID, noteID int64) error {
+	note, err := s.get(ctx, userID, noteID, repository.NoteExpansion{Labels: true})
+	if err != nil {
+		return err
+	}
+	if err := s.notes.Delete(ctx, note); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.ActionNoteDelete, note, audit.NoteSummary(note), nil)
+	return nil
+}
+
+// Move places one of the user's notes in the manual order.
+func (s *NoteService) Move(ctx context.Context, userID, noteID int64, req models.MoveNoteRequest) (*models.Note, error) {
+	if req.After == nil && req.Before == nil {
+		return nil, invalid("Either after or before must be given")
+	}
+	if (req.After != nil && *req.After == noteID) || (req.Before != nil && *req.Before == noteID) {
+		return nil, invalid("A note cannot be moved relative to itself")
+	}
+
+	note, err := s.get(ctx, userID, noteID, repository.NoteExpansion{})
+	if err != nil {
+		return nil, err
+	}
+
+	previous := note.Position
+	note.Position, err = s.notes.Move(ctx, userID, noteID, req.After, req.Before)
+	switch {
+	case errors.Is(err, repository.ErrAnchorNotFound):
+		return nil, ErrAnchorNotFound
+	case errors.Is(err, repository.ErrAnchorOrder):
+		return nil, ErrAnchorOrder
+	case err != nil:
+		return nil, err
+	}
+
+	s.record(ctx, audit.ActionNoteMove, note,
+		map[string]interface{}{"position": previous},
+		map[string]interface{}{"position": note.Position})
+	return note, nil
+}
+
+// Each calls fn for the user's notes matching filter, or all of them when
+// filter is nil, with labels and attachment metadata. It streams the notes
+// of exports.
+func (s *NoteService) Each(ctx context.Context, userID int64, filter *repository.NoteFilter, fn func(note models.Note) error) error {
+	return s.notes.Each(ctx, userID, filter, fn)
+}
+
+// Attachment returns one of the user's attachments with its contents.
+func (s *NoteService) Attachment(ctx context.Context, userID, attachmentID int64) (*models.Attachment, error) {
+	attachment, err := s.notes.Attachment(ctx, userID, attachmentID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, err
+}
+
+// Labels returns the user's labels by name.
+func (s *NoteService) Labels(ctx context.Context, userID int64) ([]models.Label, error) {
+	return s.notes.Labels(ctx, userID)
+}
+
+func (s *NoteService) get(ctx context.Context, userID, noteID int64, expand repository.NoteExpansion) (*models.Note, error) {
+	note, err := s.notes.Get(ctx, userID, noteID, expand)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNoteNotFound
+	}
+	return note, err
+}
+
+// record adds an event about note to the audit log.
+func (s *NoteService) record(ctx context.Context, action string, note *models.Note, before, after map[string]interface{}) {
+	s.audit.Record(ctx, models.AuditEvent{
+		UserID:     audit.ID(note.UserID),
+		Action:     action,
+		TargetType: models.AuditTargetNote,
+		TargetID:   audit.ID(note.ID),
+		Before:     before,
+		After:      after,
+	})
+}
+
+// diffSummaries returns the keys whose value differs between two summaries.
+func diffSummaries(before, after map[string]interface{}) []string {
+	var changed []string
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// pick returns the entries of summary named by keys.
+func pick(summary map[string]interface{}, keys []string) map[string]interface{} {
+	picked := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		picked[key] = summary[key]
+	}
+	return picked
+}
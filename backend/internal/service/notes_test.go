@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/audit"
+	"github.com/tgogbera/google_keep_clone-backend/internal/models"
+	"github.com/tgogbera/google_keep_clone-backend/internal/repository"
+)
+
+// fixture is a NoteService on the in-memory repositories with two users.
+type fixture struct {
+	ctx        context.Context
+	notes      *NoteService
+	users      *repository.MemoryUserRepository
+	events     *repository.MemoryAuditRepository
+	alice, bob int64
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{
+		ctx:    context.Background(),
+		users:  repository.NewMemoryUserRepository(),
+		events: repository.NewMemoryAuditRepository(),
+	}
+	f.notes = NewNoteService(repository.NewMemoryNoteRepository(), f.users, audit.New(f.events))
+
+	for _, u := range []struct {
+		email string
+		id    *int64
+	}{{"alice@example.com", &f.alice}, {"bob@example.com", &f.bob}} {
+		user := models.User{Email: u.email, PasswordHash: "x"}
+		if err := f.users.Create(f.ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+		*u.id = user.ID
+	}
+	return f
+}
+
+// create adds a note of userID titled title.
+func (f *fixture) create(t *testing.T, userID int64, title string, labels ...string) *models.Note {
+	t.Helper()
+	note, err := f.notes.Create(f.ctx, userID, models.CreateNoteRequest{Title: title, Labels: labels})
+	if err != nil {
+		t.Fatalf("create %q: %v", title, err)
+	}
+	return note
+}
+
+// actions returns the actions of the recorded events, oldest first.
+func (f *fixture) actions(t *testing.T) []string {
+	t.Helper()
+	events, err := f.events.List(f.ctx, repository.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := make([]string, len(events))
+	for i, e := range events {
+		actions[len(events)-1-i] = e.Action
+	}
+	return actions
+}
+
+func TestCreateUsesDefaultColor(t *testing.T) {
+	f := newFixture(t)
+	user, _ := f.users.FindByID(f.ctx, f.alice)
+	if err := f.users.Update(f.ctx, user, map[string]interface{}{"pref_default_note_color": "blue"}); err != nil {
+		t.Fatal(err)
+	}
+
+	note := f.create(t, f.alice, "Groceries")
+	if note.Color != "blue" {
+		t.Errorf("color = %q, want the default note color blue", note.Color)
+	}
+	if note.Position == "" {
+		t.Error("new note has no position")
+	}
+	if got := f.actions(t); len(got) != 1 || got[0] != audit.ActionNoteCreate {
+		t.Errorf("audit actions = %v, want [%s]", got, audit.ActionNoteCreate)
+	}
+}
+
+func TestNotesOfOtherUsersAreNotFound(t *testing.T) {
+	f := newFixture(t)
+	note := f.create(t, f.alice, "Private")
+
+	if _, err := f.notes.Get(f.ctx, f.bob, note.ID, ""); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Get by another user: err = %v, want ErrNoteNotFound", err)
+	}
+	if _, err := f.notes.Update(f.ctx, f.bob, note.ID, map[string]interface{}{"title": "Mine"}); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Update by another user: err = %v, want ErrNoteNotFound", err)
+	}
+	if err := f.notes.Delete(f.ctx, f.bob, note.ID); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Delete by another user: err = %v, want ErrNoteNotFound", err)
+	}
+	after := note.ID
+	other := f.create(t, f.bob, "Bob's")
+	if _, err := f.notes.Move(f.ctx, f.bob, other.ID, models.MoveNoteRequest{After: &after}); !errors.Is(err, ErrAnchorNotFound) {
+		t.Errorf("Move after another user's note: err = %v, want ErrAnchorNotFound", err)
+	}
+
+	got, err := f.notes.Get(f.ctx, f.alice, note.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Private" {
+		t.Errorf("title = %q after another user's update, want Private", got.Title)
+	}
+}
+
+func TestGetExpand(t *testing.T) {
+	f := newFixture(t)
+	note := f.create(t, f.alice, "Trip", "travel", "2026")
+
+	plain, err := f.notes.Get(f.ctx, f.alice, note.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Labels != nil || plain.Attachments != nil || plain.ChecklistItems != nil || plain.RevisionCount != nil {
+		t.Errorf("no expansion: got %+v, want no related data", plain)
+	}
+
+	expanded, err := f.notes.Get(f.ctx, f.alice, note.ID, "labels, attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expanded.Labels) != 2 || expanded.Labels[0].Name != "2026" || expanded.Labels[1].Name != "travel" {
+		t.Errorf("labels = %v, want 2026 and travel by name", expanded.Labels)
+	}
+	if expanded.Attachments == nil {
+		t.Error("attachments not expanded")
+	}
+
+	content := "Pack:\n- [x] passport\n  * [ ] charger\n- [] not an item"
+	if _, err := f.notes.Update(f.ctx, f.alice, note.ID, map[string]interface{}{"content": content}); err != nil {
+		t.Fatal(err)
+	}
+	expanded, err = f.notes.Get(f.ctx, f.alice, note.ID, "checklist_items,revision_count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.ChecklistItem{{Text: "passport", Checked: true}, {Text: "charger"}}
+	if !reflect.DeepEqual(expanded.ChecklistItems, want) {
+		t.Errorf("checklist items = %+v, want %+v", expanded.ChecklistItems, want)
+	}
+	if expanded.RevisionCount == nil || *expanded.RevisionCount != 2 {
+		t.Errorf("revision count = %v after one edit, want 2", expanded.RevisionCount)
+	}
+
+	// Changing only the labels is an edit too
+	if _, err := f.notes.Update(f.ctx, f.alice, note.ID, map[string]interface{}{"labels": []interface{}{"travel"}}); err != nil {
+		t.Fatal(err)
+	}
+	relabelled, err := f.notes.Get(f.ctx, f.alice, note.ID, "revision_count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *relabelled.RevisionCount != 3 || !relabelled.UpdatedAt.After(expanded.UpdatedAt) {
+		t.Errorf("after changing the labels: revision count %d, updated at %v, was %v",
+			*relabelled.RevisionCount, relabelled.UpdatedAt, expanded.UpdatedAt)
+	}
+
+	var validationErr *ValidationError
+	if _, err := f.notes.Get(f.ctx, f.alice, note.ID, "labels,owner"); !errors.As(err, &validationErr) || validationErr.Field != "expand" {
+		t.Errorf("unknown expansion: err = %v, want a validation error of expand", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	f := newFixture(t)
+	note := f.create(t, f.alice, "Draft", "work")
+
+	updated, err := f.notes.Update(f.ctx, f.alice, note.ID, map[string]interface{}{
+		"title":  "Final",
+		"labels": []interface{}{"work", "done"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Final" || len(updated.Labels) != 2 {
+		t.Errorf("updated note = %q with %d labels, want Final with 2", updated.Title, len(updated.Labels))
+	}
+
+	var validationErr *ValidationError
+	for _, fields := range []map[string]interface{}{
+		{},
+		{"title": ""},
+		{"color": "chartreuse"},
+		{"pinned": "yes"},
+	} {
+		if _, err := f.notes.Update(f.ctx, f.alice, note.ID, fields); !errors.As(err, &validationErr) {
+			t.Errorf("Update(%v): err = %v, want a validation error", fields, err)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	f := newFixture(t)
+	first := f.create(t, f.alice, "first")
+	second := f.create(t, f.alice, "second")
+	third := f.create(t, f.alice, "third")
+
+	// New notes go to the top: third, second, first
+	manual := func() []string {
+		notes, err := f.notes.List(f.ctx, f.alice, repository.NoteFilter{Sort: repository.SortManual})
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles := make([]string, len(notes))
+		for i, n := range notes {
+			titles[i] = n.Title
+		}
+		return titles
+	}
+	if got := manual(); got[0] != "third" || got[2] != "first" {
+		t.Fatalf("manual order = %v, want newest first", got)
+	}
+
+	if _, err := f.notes.Move(f.ctx, f.alice, first.ID, models.MoveNoteRequest{Before: &third.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.notes.Move(f.ctx, f.alice, third.ID, models.MoveNoteRequest{After: &second.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if got := manual(); got[0] != "first" || got[1] != "second" || got[2] != "third" {
+		t.Errorf("manual order = %v, want first, second, third", got)
+	}
+
+	var validationErr *ValidationError
+	if _, err := f.notes.Move(f.ctx, f.alice, first.ID, models.MoveNoteRequest{}); !errors.As(err, &validationErr) {
+		t.Errorf("Move without anchors: err = %v, want a validation error", err)
+	}
+	if _, err := f.notes.Move(f.ctx, f.alice, first.ID, models.MoveNoteRequest{After: &first.ID}); !errors.As(err, &validationErr) {
+		t.Errorf("Move relative to itself: err = %v, want a validation error", err)
+	}
+	if _, err := f.notes.Move(f.ctx, f.alice, second.ID, models.MoveNoteRequest{After: &third.ID, Before: &first.ID}); !errors.Is(err, ErrAnchorOrder) {
+		t.Errorf("Move between reversed anchors: err = %v, want ErrAnchorOrder", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	f := newFixture(t)
+	a := f.create(t, f.alice, "a")
+	b := f.create(t, f.alice, "b")
+	foreign := f.create(t, f.bob, "bob's")
+
+	// Atomic: an ID of another user rolls back the whole batch
+	resp, _, err := f.notes.Batch(f.ctx, f.alice, models.BatchNotesRequest{
+		IDs:       []int64{a.ID, foreign.ID, b.ID, a.ID},
+		Operation: models.BatchArchive,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.RolledBack || resp.Mode != models.BatchModeAtomic || len(resp.Results) != 3 {
+		t.Fatalf("atomic batch = %+v, want rolled back with 3 results", resp)
+	}
+	if resp.Results[0].Status != models.BatchResultRolledBack || resp.Results[1].Status != models.BatchResultNotFound {
+		t.Errorf("results = %+v, want rolled_back then not_found", resp.Results)
+	}
+	if note, _ := f.notes.Get(f.ctx, f.alice, a.ID, ""); note.Archived {
+		t.Error("note archived by a rolled back batch")
+	}
+	if note, _ := f.notes.Get(f.ctx, f.bob, foreign.ID, ""); note.Archived {
+		t.Error("note of another user archived")
+	}
+
+	// Partial: the notes of the user are changed
+	resp, _, err = f.notes.Batch(f.ctx, f.alice, models.BatchNotesRequest{
+		IDs:       []int64{a.ID, foreign.ID, b.ID},
+		Operation: models.BatchAddLabels,
+		Labels:    []string{"done"},
+		Mode:      models.BatchModePartial,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RolledBack || resp.Succeeded != 2 || resp.Failed != 1 {
+		t.Errorf("partial batch = %+v, want 2 succeeded and 1 failed", resp)
+	}
+	if note, _ := f.notes.Get(f.ctx, f.alice, b.ID, "labels"); len(note.Labels) != 1 || note.Labels[0].Name != "done" {
+		t.Errorf("labels after the batch = %v, want done", note.Labels)
+	}
+
+	if _, _, err := f.notes.Batch(f.ctx, f.alice, models.BatchNotesRequest{IDs: []int64{a.ID}, Operation: models.BatchDelete}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.notes.Get(f.ctx, f.alice, a.ID, ""); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Get after a batch delete: err = %v, want ErrNoteNotFound", err)
+	}
+	actions := f.actions(t)
+	if last := actions[len(actions)-1]; last != audit.ActionNoteDelete {
+		t.Errorf("last audit action = %q, want %q", last, audit.ActionNoteDelete)
+	}
+
+	var validationErr *ValidationError
+	if _, _, err := f.notes.Batch(f.ctx, f.alice, models.BatchNotesRequest{IDs: []int64{b.ID}, Operation: models.BatchColor}); !errors.As(err, &validationErr) || validationErr.Field != "color" {
+		t.Errorf("color batch without a color: err = %v, want a validation error of color", err)
+	}
+}
@@ -0,0 +1,141 @@
+// Package testdb opens migrated databases for tests, one per supported
+// driver. SQLite is always available; Postgres is used when
+// TEST_POSTGRES_DSN names, in key=value form, a database the tests may
+// create schemas in, such as
+// "host=localhost user=keep password=keep dbname=keep_test sslmode=disable".
+package testdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tgogbera/google_keep_clone-backend/internal/config"
+	"github.com/tgogbera/google_keep_clone-backend/internal/database"
+	"github.com/tgogbera/google_keep_clone-backend/internal/logger"
+	"github.com/tgogbera/google_keep_clone-backend/internal/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// PostgresDSNEnv names the variable holding the Postgres test database.
+const PostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// Run calls fn in a subtest per driver, each with an empty migrated
+// database of its own. The logger must be initialized.
+func Run(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	run(t, true, fn)
+}
+
+// RunEmpty is Run with databases that have no tables at all, for testing
+// the migrations themselves.
+func RunEmpty(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	run(t, false, fn)
+}
+
+func run(t *testing.T, migrated bool, fn func(t *testing.T, db *gorm.DB)) {
+	t.Run(database.DriverSQLite, func(t *testing.T) {
+		db := emptySQLite(t)
+		if migrated {
+			migrate(t, db)
+		}
+		fn(t, db)
+	})
+	t.Run(database.DriverPostgres, func(t *testing.T) {
+		dsn := os.Getenv(PostgresDSNEnv)
+		if dsn == "" {
+			t.Skipf("%s is not set", PostgresDSNEnv)
+		}
+		db := emptyPostgres(t, dsn)
+		if migrated {
+			migrate(t, db)
+		}
+		fn(t, db)
+	})
+}
+
+// SQLite returns a migrated database in a temporary file, removed with the
+// test.
+func SQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	db := emptySQLite(t)
+	migrate(t, db)
+	return db
+}
+
+func emptySQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(&config.Config{
+		DBDriver: database.DriverSQLite,
+		DBPath:   filepath.Join(t.TempDir(), "notes.db"),
+		LogLevel: config.LogLevelError,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(t, db) })
+	return db
+}
+
+// Postgres returns a migrated database in a new schema of the database at
+// dsn, dropped with the test.
+func Postgres(t testing.TB, dsn string) *gorm.DB {
+	t.Helper()
+	db := emptyPostgres(t, dsn)
+	migrate(t, db)
+	return db
+}
+
+func emptyPostgres(t testing.TB, dsn string) *gorm.DB {
+	t.Helper()
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(raw)
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.NewGormLoggerAdapter(logger.LevelError),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(t, admin) })
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), &gorm.Config{
+		Logger: logger.NewGormLoggerAdapter(logger.LevelError),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(t, db) })
+	return db
+}
+
+func migrate(t testing.TB, db *gorm.DB) {
+	t.Helper()
+	if _, err := migrations.Up(context.Background(), db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
+
+func closeDB(t testing.TB, db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if err != nil {
+		t.Errorf("close database: %v", err)
+	}
+}
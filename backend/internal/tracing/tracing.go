@@ -0,0 +1,119 @@
+// Package tracing sets up OpenTelemetry tracing: the exporter, W3C trace
+// context propagation, a span per HTTP request and spans for database
+// queries and other expensive steps.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// Exporters selectable with OTEL_TRACES_EXPORTER.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// serviceName is used unless OTEL_SERVICE_NAME is set.
+const serviceName = "keep-backend"
+
+var tracer = otel.Tracer("github.com/tgogbera/google_keep_clone-backend")
+
+// Init installs the tracer provider for exporter and the W3C trace context
+// propagator. The OTLP exporter sends to OTEL_EXPORTER_OTLP_ENDPOINT over
+// HTTP; sampling follows OTEL_TRACES_SAMPLER. With ExporterNone no spans are
+// recorded, but incoming trace context is still passed on and logged. The
+// returned function flushes the spans still buffered.
+func Init(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q: expected %s, %s or %s", exporter, ExporterOTLP, ExporterStdout, ExporterNone)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", exporter, err)
+	}
+
+	// Attributes from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	res, err := resource.Merge(
+		resource.NewSchemaless(semconv.ServiceName(serviceName)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// QuerySpan records a finished database query as a child of the span in
+// ctx. It is called by the GORM logger, after the query, so the span is
+// back-dated to begin. Queries without a span in ctx, such as those of
+// background jobs, are not traced. The SQL text is left out because it
+// contains the query parameters.
+func QuerySpan(ctx context.Context, begin time.Time, operation string, rows int64, err error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	_, span := tracer.Start(ctx, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(begin),
+		trace.WithAttributes(
+			semconv.DBOperationName(operation),
+			attribute.Int64("db.rows_affected", rows),
+		),
+	)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
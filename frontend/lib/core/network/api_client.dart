@@ -251,7 +251,9 @@ class ApiClient {
   /// Extract error message from various response formats
   String _extractErrorMessage(dynamic data, int? statusCode) {
     if (data is Map<String, dynamic>) {
-      return data['error'] as String? ??
+      // RFC 7807 problem details carry the message in "detail"
+      return data['detail'] as String? ??
+          data['error'] as String? ??
           data['message'] as String? ??
           data['msg'] as String? ?? // Common alternative
           'Request failed with status $statusCode';